@@ -3,48 +3,61 @@ package main
 import (
 	"fmt"
 	"os"
-	"os/signal"
-	"syscall"
 
 	"github.com/Ikhlashmulya/golang-clean-architecture-project-structure/config"
-	"github.com/Ikhlashmulya/golang-clean-architecture-project-structure/internal/delivery/http/handler"
-	"github.com/Ikhlashmulya/golang-clean-architecture-project-structure/internal/delivery/http/middleware"
-	"github.com/Ikhlashmulya/golang-clean-architecture-project-structure/internal/delivery/http/route"
 	"github.com/Ikhlashmulya/golang-clean-architecture-project-structure/internal/infrastructure"
-	"github.com/Ikhlashmulya/golang-clean-architecture-project-structure/internal/repository"
 	"github.com/Ikhlashmulya/golang-clean-architecture-project-structure/internal/usecase"
 )
 
-func main() {
-	config := config.New()
-
-	app := infrastructure.NewFiber(config)
-	port := config.Get("APP_PORT")
-
-	db := infrastructure.NewGorm(config)
-	logger := infrastructure.NewLogger(config)
-	validate := infrastructure.NewValidator(config)
-	userRepository := repository.NewUserRepository(db)
-	userUsecase := usecase.NewUserUsecase(userRepository, logger, validate, config)
-	userHandler := handler.NewUserHandler(userUsecase, logger)
-
-	authMiddleware := middleware.NewAuth(userUsecase, logger)
+const usage = `usage: web <command> [arguments]
 
-	route.RegisterRoute(app, userHandler, authMiddleware)
+commands:
+  serve                                   start the http server (default)
+  migrate up [N] | down [N] | goto V | force V | version
+  seed --fixtures file.yaml               create the users of a fixtures file
+  user create --username U --name N --email E [--password P] [--role R]...
+  user set-password --username U [--password P]
+  user lock --username U
+  user unlock --username U
 
-	go func() {
-		if err := app.Listen(fmt.Sprintf(":%v", port)); err != nil {
-			panic(fmt.Errorf("error running app : %+v", err.Error()))
-		}
-	}()
+a password that is not given as a flag is read from stdin`
 
-	ch := make(chan os.Signal, 1)                    // Create channel to signify a signal being sent
-	signal.Notify(ch, os.Interrupt, syscall.SIGTERM) // When an interrupt or termination signal is sent, notify the channel
-
-	<-ch // This blocks the main thread until an interrupt is received
+func main() {
+	config, err := config.Load(config.File())
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	logger := infrastructure.NewLogger(config)
 
-	// Your cleanup tasks go here
-	_ = app.Shutdown()
+	command, args := "serve", []string{}
+	if len(os.Args) > 1 {
+		command, args = os.Args[1], os.Args[2:]
+	}
+
+	switch command {
+	case "serve":
+		err = runServe(config, logger)
+	case "migrate":
+		err = runMigrate(config, logger, args)
+	case "seed":
+		err = runSeed(config, logger, args)
+	case "user":
+		err = runUser(config, logger, args)
+	case "help", "-h", "--help":
+		fmt.Println(usage)
+	default:
+		fmt.Fprintln(os.Stderr, usage)
+		err = fmt.Errorf("unknown command %q", command)
+	}
+
+	if err != nil {
+		logger.WithError(err).Fatalf("failed to run %v", command)
+	}
+}
 
-	fmt.Println("App was successful shutdown.")
+// cli is what the seed and user commands run against, stop Lifecycle once done to close the database.
+type cli struct {
+	UserUsecase usecase.UserUsecase
+	Lifecycle   *infrastructure.Lifecycle
 }
@@ -0,0 +1,99 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+
+	"github.com/Ikhlashmulya/golang-clean-architecture-project-structure/config"
+	"github.com/Ikhlashmulya/golang-clean-architecture-project-structure/internal/infrastructure"
+	"github.com/golang-migrate/migrate/v4"
+	"github.com/sirupsen/logrus"
+)
+
+const migrateUsage = "usage: migrate up [N] | down [N] | goto V | force V | version"
+
+// runMigrate runs a migrate subcommand. up without N applies every pending migration,
+// down without N rolls back only the latest one, and force -1 records that none is applied.
+func runMigrate(config *config.Config, logger *logrus.Logger, args []string) error {
+	if len(args) == 0 || len(args) > 2 {
+		return errors.New(migrateUsage)
+	}
+
+	var (
+		number int
+		hasArg = len(args) == 2
+		err    error
+	)
+	if hasArg {
+		// -1 is the version of an empty database, only force accepts it
+		number, err = strconv.Atoi(args[1])
+		if err != nil || number < -1 || (number == -1 && args[0] != "force") {
+			return fmt.Errorf("invalid number %q, %s", args[1], migrateUsage)
+		}
+	}
+
+	m, err := infrastructure.NewMigrate(config, logger)
+	if err != nil {
+		return err
+	}
+	defer m.Close()
+
+	switch args[0] {
+	case "up":
+		if hasArg {
+			err = m.Steps(number)
+		} else {
+			err = m.Up()
+		}
+	case "down":
+		if !hasArg {
+			number = 1
+		}
+		err = m.Steps(-number)
+	case "goto":
+		if !hasArg {
+			return errors.New(migrateUsage)
+		}
+		err = m.Migrate(uint(number))
+	case "force":
+		if !hasArg {
+			return errors.New(migrateUsage)
+		}
+		err = m.Force(number)
+	case "version":
+		if hasArg {
+			return errors.New(migrateUsage)
+		}
+		return printMigrateVersion(m)
+	default:
+		return errors.New(migrateUsage)
+	}
+
+	if errors.Is(err, migrate.ErrNoChange) {
+		fmt.Println("no change")
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	return printMigrateVersion(m)
+}
+
+func printMigrateVersion(m *migrate.Migrate) error {
+	version, dirty, err := m.Version()
+	if errors.Is(err, migrate.ErrNilVersion) {
+		fmt.Println("no migration applied")
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	if dirty {
+		fmt.Printf("%v (dirty)\n", version)
+	} else {
+		fmt.Println(version)
+	}
+	return nil
+}
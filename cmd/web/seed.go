@@ -0,0 +1,84 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/Ikhlashmulya/golang-clean-architecture-project-structure/config"
+	"github.com/Ikhlashmulya/golang-clean-architecture-project-structure/internal/exception"
+	"github.com/Ikhlashmulya/golang-clean-architecture-project-structure/internal/model"
+	"github.com/sirupsen/logrus"
+	"gopkg.in/yaml.v3"
+)
+
+type fixtures struct {
+	Users []userFixture `yaml:"users"`
+}
+
+type userFixture struct {
+	Name     string   `yaml:"name"`
+	Username string   `yaml:"username"`
+	Email    string   `yaml:"email"`
+	Password string   `yaml:"password"`
+	Roles    []string `yaml:"roles"`
+}
+
+// runSeed registers the users of a fixtures file. Users that already exist are skipped but still
+// get their roles, so seeding the same file twice is harmless.
+func runSeed(config *config.Config, logger *logrus.Logger, args []string) error {
+	flags := flag.NewFlagSet("seed", flag.ContinueOnError)
+	fixturesFile := flags.String("fixtures", "", "yaml file with the fixtures to seed")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	if *fixturesFile == "" {
+		return errors.New("missing --fixtures file")
+	}
+
+	content, err := os.ReadFile(*fixturesFile)
+	if err != nil {
+		return err
+	}
+
+	data := new(fixtures)
+	if err := yaml.Unmarshal(content, data); err != nil {
+		return fmt.Errorf("error parsing fixtures : %w", err)
+	}
+
+	ctx := context.Background()
+	cli, err := initializeCLI(config, logger)
+	if err != nil {
+		return err
+	}
+	defer cli.Lifecycle.Stop()
+	userUsecase := cli.UserUsecase
+
+	for _, user := range data.Users {
+		_, err := userUsecase.Register(ctx, &model.RegisterUserRequest{
+			Name:          user.Name,
+			Username:      user.Username,
+			Email:         user.Email,
+			Password:      user.Password,
+			EmailVerified: true,
+		})
+		if errors.Is(err, exception.ErrUserAlreadyExist) {
+			fmt.Printf("user %v already exists, skipped\n", user.Username)
+		} else if err != nil {
+			return fmt.Errorf("error seeding user %v : %w", user.Username, err)
+		} else {
+			fmt.Printf("user %v created\n", user.Username)
+		}
+
+		for _, role := range user.Roles {
+			if err := userUsecase.GrantRole(ctx, &model.UserRoleRequest{Username: user.Username, Role: role}); err != nil {
+				return fmt.Errorf("error granting role %v to %v : %w", role, user.Username, err)
+			}
+		}
+	}
+
+	return nil
+}
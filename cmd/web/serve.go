@@ -0,0 +1,94 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/Ikhlashmulya/golang-clean-architecture-project-structure/config"
+	"github.com/Ikhlashmulya/golang-clean-architecture-project-structure/internal/infrastructure"
+	"github.com/golang-migrate/migrate/v4"
+	"github.com/sirupsen/logrus"
+)
+
+func runServe(config *config.Config, logger *logrus.Logger) error {
+	if config.DB.AutoMigrate {
+		m, err := infrastructure.NewMigrate(config, logger)
+		if err != nil {
+			return err
+		}
+		err = m.Up()
+		m.Close()
+		if err != nil && !errors.Is(err, migrate.ErrNoChange) {
+			return fmt.Errorf("error migrating database : %w", err)
+		}
+	}
+
+	server, err := initializeServer(config, logger)
+	if err != nil {
+		return err
+	}
+
+	lifecycle := server.Lifecycle
+	lifecycle.Go("config watcher", func(ctx context.Context) {
+		watchConfig(ctx, config, logger)
+	})
+
+	if err := lifecycle.Start(context.Background()); err != nil {
+		return err
+	}
+	logger.WithField("port", config.App.Port).Info("server started")
+
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+
+	select {
+	case sig := <-signals:
+		logger.WithField("signal", sig.String()).Info("shutting down")
+	case err := <-lifecycle.Failed():
+		logger.WithError(err).Error("shutting down after a component failed")
+	}
+
+	// readiness fails from here, then requests drain, workers stop and the database closes
+	if err := lifecycle.Stop(); err != nil {
+		return fmt.Errorf("error shutting down : %w", err)
+	}
+	logger.Info("server stopped")
+
+	return nil
+}
+
+// watchConfig reloads the configuration when its file changes or on SIGHUP and applies the new log level.
+func watchConfig(ctx context.Context, cfg *config.Config, logger *logrus.Logger) {
+	reloader := config.NewReloader(cfg, config.File(), logger)
+
+	go func() {
+		for change := range reloader.Subscribe() {
+			logger.SetLevel(logrus.Level(change.Config.Log.Level))
+		}
+	}()
+
+	go func() {
+		hup := make(chan os.Signal, 1)
+		signal.Notify(hup, syscall.SIGHUP)
+		defer signal.Stop(hup)
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-hup:
+				if err := reloader.Reload(); err != nil {
+					logger.WithError(err).Error("failed reload configuration")
+				}
+			}
+		}
+	}()
+
+	if err := reloader.Watch(ctx); err != nil {
+		logger.WithError(err).Warn("configuration file is not watched, reload with SIGHUP")
+	}
+}
@@ -0,0 +1,38 @@
+//go:build wireinject
+
+package main
+
+import (
+	"github.com/Ikhlashmulya/golang-clean-architecture-project-structure/config"
+	"github.com/Ikhlashmulya/golang-clean-architecture-project-structure/internal/delivery"
+	"github.com/Ikhlashmulya/golang-clean-architecture-project-structure/internal/infrastructure"
+	"github.com/Ikhlashmulya/golang-clean-architecture-project-structure/internal/repository"
+	"github.com/Ikhlashmulya/golang-clean-architecture-project-structure/internal/usecase"
+	"github.com/google/wire"
+	"github.com/sirupsen/logrus"
+)
+
+// Injectors are generated into wire_gen.go with `make wire` after changing a provider set.
+
+func initializeServer(config *config.Config, logger *logrus.Logger) (*delivery.Server, error) {
+	wire.Build(
+		infrastructure.ProviderSet,
+		infrastructure.MailerSet,
+		repository.ProviderSet,
+		usecase.ProviderSet,
+		delivery.ProviderSet,
+	)
+	return nil, nil
+}
+
+// initializeCLI wires the user usecase for the cli commands the same way as for the server.
+func initializeCLI(config *config.Config, logger *logrus.Logger) (*cli, error) {
+	wire.Build(
+		infrastructure.ProviderSet,
+		infrastructure.MailerSet,
+		repository.ProviderSet,
+		usecase.ProviderSet,
+		wire.Struct(new(cli), "*"),
+	)
+	return nil, nil
+}
@@ -1,26 +1,383 @@
 package config
 
 import (
+	"errors"
 	"fmt"
+	"os"
 	"path"
 	"path/filepath"
+	"reflect"
 	"runtime"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
 
+	"github.com/go-playground/validator/v10"
 	"github.com/spf13/viper"
 )
 
-func New() *viper.Viper {
+// Config is loaded from .env and the environment, the environment wins. Every field names its
+// variable with the env tag, durations are whole seconds or a Go duration such as "15m".
+// Fields tagged reload can change while the server runs and must be read through Current.
+type Config struct {
+	App     App
+	DB      DB
+	Auth    Auth
+	Account Account
+	Mail    Mail
+	Log     Log
+	CORS    CORS
+	Metrics Metrics
+	Tracing Tracing
+
+	reloaded atomic.Pointer[Config]
+}
+
+type App struct {
+	Name    string        `env:"APP_NAME" default:"Golang Rest API" validate:"required"`
+	Port    int           `env:"APP_PORT" default:"3000" validate:"min=1,max=65535"`
+	Prefork bool          `env:"APP_PREFORK"`
+	Timeout time.Duration `env:"APP_TIMEOUT" default:"10" validate:"gt=0"`
+	// ShutdownTimeout bounds the whole shutdown, draining requests included.
+	ShutdownTimeout time.Duration `env:"APP_SHUTDOWN_TIMEOUT" default:"30" validate:"gt=0"`
+	// ShutdownReadinessDelay keeps serving requests once readiness fails on shutdown, until the load
+	// balancer noticed it. It is part of ShutdownTimeout.
+	ShutdownReadinessDelay time.Duration `env:"APP_SHUTDOWN_READINESS_DELAY" default:"5" validate:"gte=0,ltfield=ShutdownTimeout"`
+	// HealthCheckTimeout bounds every readiness check that does not set its own timeout.
+	HealthCheckTimeout time.Duration `env:"APP_HEALTH_CHECK_TIMEOUT" default:"2" validate:"gt=0"`
+}
+
+type DB struct {
+	Driver      string `env:"DB_DRIVER" default:"mysql" validate:"oneof=mysql postgres sqlite"`
+	User        string `env:"DB_USER"`
+	Password    string `env:"DB_PASSWORD" secret:"true"`
+	Host        string `env:"DB_HOST" validate:"required_unless=Driver sqlite"`
+	Port        int    `env:"DB_PORT" validate:"required_unless=Driver sqlite,max=65535"`
+	Name        string `env:"DB_NAME" validate:"required"`
+	SSLMode     string `env:"DB_SSL_MODE" default:"disable"`
+	AutoMigrate bool   `env:"DB_AUTO_MIGRATE"`
+	Pool        Pool
+}
+
+type Pool struct {
+	Idle     int           `env:"POOL_IDLE" default:"5" validate:"min=0"`
+	Max      int           `env:"POOL_MAX" default:"100" validate:"min=1"`
+	Lifetime time.Duration `env:"POOL_LIFETIME" default:"3000" validate:"gt=0"`
+}
+
+type Auth struct {
+	JWT                  JWT
+	TokenRevocationStore string        `env:"TOKEN_REVOCATION_STORE" default:"database" validate:"oneof=database memory"`
+	AllowUnverifiedLogin bool          `env:"AUTH_ALLOW_UNVERIFIED_LOGIN" reload:"true"`
+	MFATokenExpire       time.Duration `env:"MFA_TOKEN_EXPIRE" default:"300" validate:"gt=0"`
+	EmailVerification    EmailVerification
+	PasswordReset        PasswordReset
+	PasswordPolicy       PasswordPolicy
+	LoginThrottle        LoginThrottle
+}
+
+type JWT struct {
+	SigningMethod        string        `env:"JWT_SIGNING_METHOD" default:"HS256" validate:"oneof=HS256 HS384 HS512 RS256 RS384 RS512 PS256 PS384 PS512 ES256 ES384 ES512 EdDSA"`
+	SecretKey            string        `env:"JWT_SECRET_KEY" secret:"true"`
+	PreviousSecretKeys   []string      `env:"JWT_PREVIOUS_SECRET_KEYS" secret:"true"`
+	PrivateKeyFile       string        `env:"JWT_PRIVATE_KEY_FILE"`
+	VerificationKeyFiles []string      `env:"JWT_VERIFICATION_KEY_FILES"`
+	AccessTokenExpire    time.Duration `env:"JWT_ACCESS_TOKEN_EXPIRE" default:"7200" validate:"gt=0"`
+	RefreshTokenExpire   time.Duration `env:"JWT_REFRESH_TOKEN_EXPIRE" default:"1209600" validate:"gt=0"`
+}
+
+// EmailVerification and PasswordReset configure mailed links, the token is appended to URL as ?token=.
+type EmailVerification struct {
+	URL         string        `env:"EMAIL_VERIFICATION_URL" validate:"required,url"`
+	TokenExpire time.Duration `env:"EMAIL_VERIFICATION_TOKEN_EXPIRE" default:"86400" validate:"gt=0"`
+}
+
+type PasswordReset struct {
+	URL         string        `env:"PASSWORD_RESET_URL" validate:"required,url"`
+	TokenExpire time.Duration `env:"PASSWORD_RESET_TOKEN_EXPIRE" default:"1800" validate:"gt=0"`
+}
+
+// PasswordPolicy is enforced by the password validation rule. MinClasses counts the kinds of
+// characters used among lower case, upper case, digits and symbols, MinEntropy is in bits as
+// estimated with repeats, sequences and common words discounted.
+type PasswordPolicy struct {
+	MinClasses int `env:"PASSWORD_MIN_CLASSES" default:"2" validate:"min=1,max=4" reload:"true"`
+	MinEntropy int `env:"PASSWORD_MIN_ENTROPY" default:"40" validate:"min=0" reload:"true"`
+	// BreachFile is the Have I Been Pwned SHA-1 corpus, the single file ordered by hash or the directory
+	// of range files. Passwords listed in it are refused, failing to read it fails the request.
+	BreachFile string `env:"PASSWORD_BREACH_FILE" validate:"omitempty,file|dir"`
+}
+
+type LoginThrottle struct {
+	Store              string        `env:"LOGIN_ATTEMPT_STORE" default:"database" validate:"oneof=database memory"`
+	FreeAttempts       int           `env:"LOGIN_THROTTLE_FREE_ATTEMPTS" default:"3" validate:"min=0" reload:"true"`
+	BaseDelay          time.Duration `env:"LOGIN_THROTTLE_BASE_DELAY" default:"1" validate:"gt=0" reload:"true"`
+	MaxDelay           time.Duration `env:"LOGIN_THROTTLE_MAX_DELAY" default:"60" validate:"gtefield=BaseDelay" reload:"true"`
+	LockoutThreshold   int           `env:"LOGIN_LOCKOUT_THRESHOLD" default:"10" validate:"gtfield=FreeAttempts" reload:"true"`
+	IPFreeAttempts     int           `env:"LOGIN_IP_THROTTLE_FREE_ATTEMPTS" default:"20" validate:"min=0" reload:"true"`
+	IPLockoutThreshold int           `env:"LOGIN_IP_LOCKOUT_THRESHOLD" default:"100" validate:"gtfield=IPFreeAttempts" reload:"true"`
+	LockoutDuration    time.Duration `env:"LOGIN_LOCKOUT_DURATION" default:"900" validate:"gt=0" reload:"true"`
+}
+
+type Account struct {
+	RestoreWindow  time.Duration `env:"ACCOUNT_RESTORE_WINDOW" default:"604800" validate:"min=0"`
+	PurgeRetention time.Duration `env:"ACCOUNT_PURGE_RETENTION" default:"2592000" validate:"gtefield=RestoreWindow"`
+	PurgeInterval  time.Duration `env:"ACCOUNT_PURGE_INTERVAL" default:"3600" validate:"gt=0"`
+}
+
+type Mail struct {
+	Driver       string `env:"MAIL_DRIVER" default:"memory" validate:"oneof=smtp file memory"`
+	From         string `env:"MAIL_FROM" validate:"required_unless=Driver memory"`
+	FileDir      string `env:"MAIL_FILE_DIR" validate:"required_if=Driver file"`
+	SMTPHost     string `env:"MAIL_SMTP_HOST" validate:"required_if=Driver smtp"`
+	SMTPPort     int    `env:"MAIL_SMTP_PORT" default:"587" validate:"max=65535"`
+	SMTPUsername string `env:"MAIL_SMTP_USERNAME"`
+	SMTPPassword string `env:"MAIL_SMTP_PASSWORD" secret:"true"`
+	// SMTPTimeout bounds connecting to the mail server and sending one message
+	SMTPTimeout  time.Duration `env:"MAIL_SMTP_TIMEOUT" default:"10" validate:"gt=0"`
+	QueueSize    int           `env:"MAIL_QUEUE_SIZE" default:"100" validate:"gt=0"`
+	QueueWorkers int           `env:"MAIL_QUEUE_WORKERS" default:"2" validate:"gt=0"`
+}
+
+type Log struct {
+	// Level is a logrus level, from 0 (panic) to 6 (trace).
+	Level  int `env:"LOG_LEVEL" default:"4" validate:"min=0,max=6" reload:"true"`
+	Access AccessLog
+}
+
+type AccessLog struct {
+	// Fields are written on every access log line next to the request id, trace id and user id.
+	Fields []string `env:"ACCESS_LOG_FIELDS" default:"method,route,status,latency_ms,ip" validate:"dive,oneof=method route path query status latency_ms ip user_agent referer bytes" reload:"true"`
+	// SamplePercent of the requests are logged, server errors always are. 0 logs server errors only.
+	SamplePercent int `env:"ACCESS_LOG_SAMPLE_PERCENT" default:"100" validate:"min=0,max=100" reload:"true"`
+}
+
+type Metrics struct {
+	Enabled bool `env:"METRICS_ENABLED" default:"true"`
+	// Port serves /metrics apart from the API when set, otherwise it is served on APP_PORT.
+	Port int `env:"METRICS_PORT" validate:"max=65535"`
+}
+
+type Tracing struct {
+	// Exporter is none, stdout, file or otlp. stdout and file write every span as a JSON line for offline use.
+	Exporter string `env:"TRACING_EXPORTER" default:"none" validate:"oneof=none stdout file otlp"`
+	File     string `env:"TRACING_FILE" validate:"required_if=Exporter file"`
+	// OTLPEndpoint is the host:port of an OTLP/HTTP collector, the OTEL_EXPORTER_OTLP_* variables apply when empty.
+	OTLPEndpoint string `env:"TRACING_OTLP_ENDPOINT"`
+	OTLPInsecure bool   `env:"TRACING_OTLP_INSECURE"`
+}
+
+type CORS struct {
+	// AllowOrigins lists the origins allowed to call the API from a browser, "*" allows any origin.
+	AllowOrigins []string `env:"CORS_ALLOW_ORIGINS" reload:"true"`
+}
+
+// New loads the configuration from File and panics with every invalid setting at once.
+func New() *Config {
+	config, err := Load(File())
+	if err != nil {
+		panic(err)
+	}
+
+	return config
+}
+
+// File returns CONFIG_FILE when set, otherwise the .env at the project root.
+func File() string {
+	if file := os.Getenv("CONFIG_FILE"); file != "" {
+		return file
+	}
+
 	_, filename, _, _ := runtime.Caller(0)
 	currentDir := filepath.Dir(filename)
-	configFile := path.Join(currentDir, ".." ,".env")
+	return path.Join(currentDir, "..", ".env")
+}
 
+// Load reads configFile when it exists and the environment. A setting named in a secret field can
+// also be given as <NAME>_FILE, the path of a file holding the value as mounted by Docker or Kubernetes secrets.
+func Load(configFile string) (*Config, error) {
 	viper := viper.New()
-	viper.SetConfigFile(configFile)
 	viper.AutomaticEnv() //use OS environment variable
 
-	if err := viper.ReadInConfig(); err != nil {
-		panic(fmt.Errorf("error loading configuration : %+v", err))
+	if configFile != "" {
+		viper.SetConfigFile(configFile)
+		viper.SetConfigType("env")
+		if err := viper.ReadInConfig(); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return nil, fmt.Errorf("error loading configuration : %+v", err)
+		}
+	}
+
+	config := new(Config)
+	var errs []error
+	loadStruct(viper, reflect.ValueOf(config).Elem(), &errs)
+
+	if err := config.Validate(); err != nil {
+		errs = append(errs, err)
+	}
+
+	if len(errs) > 0 {
+		return nil, fmt.Errorf("invalid configuration :\n%w", errors.Join(errs...))
 	}
 
-	return viper
+	return config, nil
+}
+
+// Current returns the configuration as of the latest reload, or c itself when nothing was reloaded.
+func (c *Config) Current() *Config {
+	if current := c.reloaded.Load(); current != nil {
+		return current
+	}
+	return c
+}
+
+// Validate checks required settings and ranges, every failure is reported in the returned error.
+func (c *Config) Validate() error {
+	validate := validator.New()
+	validate.RegisterTagNameFunc(envName)
+
+	var errs []error
+	if err := validate.Struct(c); err != nil {
+		var validationErrors validator.ValidationErrors
+		if !errors.As(err, &validationErrors) {
+			return err
+		}
+
+		for _, fieldError := range validationErrors {
+			rule := fieldError.Tag()
+			if fieldError.Param() != "" {
+				rule += "=" + fieldError.Param()
+			}
+			errs = append(errs, fmt.Errorf("%s: must satisfy %s, got %q", fieldError.Field(), rule, fmt.Sprint(fieldError.Value())))
+		}
+	}
+
+	if strings.HasPrefix(c.Auth.JWT.SigningMethod, "HS") && c.Auth.JWT.SecretKey == "" {
+		errs = append(errs, errors.New("JWT_SECRET_KEY: is required for "+c.Auth.JWT.SigningMethod))
+	}
+
+	if !strings.HasPrefix(c.Auth.JWT.SigningMethod, "HS") && c.Auth.JWT.PrivateKeyFile == "" {
+		errs = append(errs, errors.New("JWT_PRIVATE_KEY_FILE: is required for "+c.Auth.JWT.SigningMethod))
+	}
+
+	return errors.Join(errs...)
+}
+
+func loadStruct(viper *viper.Viper, value reflect.Value, errs *[]error) {
+	for i := 0; i < value.NumField(); i++ {
+		field := value.Type().Field(i)
+		if !field.IsExported() {
+			continue
+		}
+
+		if field.Type.Kind() == reflect.Struct {
+			loadStruct(viper, value.Field(i), errs)
+			continue
+		}
+
+		name := field.Tag.Get("env")
+		raw, err := lookup(viper, name, field.Tag.Get("secret") == "true")
+		if err != nil {
+			*errs = append(*errs, fmt.Errorf("%s: %w", name, err))
+			continue
+		}
+
+		if raw == "" {
+			raw = field.Tag.Get("default")
+		}
+
+		if err := setField(value.Field(i), raw); err != nil {
+			*errs = append(*errs, fmt.Errorf("%s: %w", name, err))
+		}
+	}
+}
+
+// lookup returns the value of name, reading it from the file in name_FILE for secrets.
+func lookup(viper *viper.Viper, name string, secret bool) (string, error) {
+	value := strings.TrimSpace(viper.GetString(name))
+	if !secret {
+		return value, nil
+	}
+
+	file := strings.TrimSpace(viper.GetString(name + "_FILE"))
+	if file == "" {
+		return value, nil
+	}
+
+	if value != "" {
+		return "", fmt.Errorf("set either %s or %s_FILE, not both", name, name)
+	}
+
+	content, err := os.ReadFile(file)
+	if err != nil {
+		return "", err
+	}
+
+	return strings.TrimRight(string(content), "\r\n"), nil
+}
+
+func setField(field reflect.Value, raw string) error {
+	if raw == "" {
+		return nil
+	}
+
+	switch {
+	case field.Type() == reflect.TypeOf(time.Duration(0)):
+		duration, err := parseDuration(raw)
+		if err != nil {
+			return err
+		}
+		field.SetInt(int64(duration))
+	case field.Kind() == reflect.String:
+		field.SetString(raw)
+	case field.Kind() == reflect.Int:
+		number, err := strconv.Atoi(raw)
+		if err != nil {
+			return fmt.Errorf("%q is not a number", raw)
+		}
+		field.SetInt(int64(number))
+	case field.Kind() == reflect.Bool:
+		flag, err := strconv.ParseBool(raw)
+		if err != nil {
+			return fmt.Errorf("%q is not a boolean", raw)
+		}
+		field.SetBool(flag)
+	case field.Kind() == reflect.Slice && field.Type().Elem().Kind() == reflect.String:
+		var values []string
+		for _, value := range strings.Split(raw, ",") {
+			if value = strings.TrimSpace(value); value != "" {
+				values = append(values, value)
+			}
+		}
+		field.Set(reflect.ValueOf(values))
+	default:
+		return fmt.Errorf("unsupported setting type %s", field.Type())
+	}
+
+	return nil
+}
+
+// parseDuration keeps the historical whole seconds format and also accepts Go durations.
+func parseDuration(raw string) (time.Duration, error) {
+	if raw == "" {
+		return 0, nil
+	}
+
+	if seconds, err := strconv.ParseInt(raw, 10, 64); err == nil {
+		return time.Duration(seconds) * time.Second, nil
+	}
+
+	duration, err := time.ParseDuration(raw)
+	if err != nil {
+		return 0, fmt.Errorf("%q is not a duration", raw)
+	}
+
+	return duration, nil
+}
+
+func envName(field reflect.StructField) string {
+	if name := field.Tag.Get("env"); name != "" {
+		return name
+	}
+	return field.Name
 }
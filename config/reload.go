@@ -0,0 +1,160 @@
+package config
+
+import (
+	"context"
+	"path/filepath"
+	"reflect"
+	"sync"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/sirupsen/logrus"
+)
+
+// Change is sent to subscribers after a reload applied at least one setting.
+type Change struct {
+	// Keys are the env names of the settings that changed.
+	Keys   []string
+	Config *Config
+}
+
+// Reloader reloads the settings tagged reload of Config from File. A change to any other setting
+// is refused with a warning, it only takes effect on restart.
+type Reloader struct {
+	Config *Config
+	File   string
+	Logger *logrus.Logger
+
+	mu          sync.Mutex
+	subscribers []chan Change
+}
+
+func NewReloader(config *Config, file string, logger *logrus.Logger) *Reloader {
+	return &Reloader{
+		Config: config,
+		File:   file,
+		Logger: logger,
+	}
+}
+
+// Subscribe returns a channel receiving every applied change. A subscriber that falls behind
+// misses changes rather than blocking the reload, Change.Config is always complete.
+func (r *Reloader) Subscribe() <-chan Change {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	ch := make(chan Change, 8)
+	r.subscribers = append(r.subscribers, ch)
+	return ch
+}
+
+// Reload reads the configuration again and publishes it through Config.Current. An invalid
+// configuration is rejected as a whole and the current one is kept.
+func (r *Reloader) Reload() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	next, err := Load(r.File)
+	if err != nil {
+		return err
+	}
+
+	previous := r.Config.Current()
+	keys, refused := merge(reflect.ValueOf(previous).Elem(), reflect.ValueOf(next).Elem())
+	for _, key := range refused {
+		r.Logger.WithField("setting", key).Warn("setting cannot be reloaded, restart to apply it")
+	}
+
+	if len(keys) == 0 {
+		return nil
+	}
+
+	r.Config.reloaded.Store(next)
+	r.Logger.WithField("settings", keys).Info("configuration reloaded")
+
+	change := Change{Keys: keys, Config: next}
+	for _, subscriber := range r.subscribers {
+		select {
+		case subscriber <- change:
+		default:
+			r.Logger.Warn("configuration subscriber is not keeping up, change dropped")
+		}
+	}
+
+	return nil
+}
+
+// Watch reloads whenever File is written until ctx is done. The directory is watched so that
+// a file replaced by an editor is still noticed.
+func (r *Reloader) Watch(ctx context.Context) error {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return err
+	}
+	defer watcher.Close()
+
+	if err := watcher.Add(filepath.Dir(r.File)); err != nil {
+		return err
+	}
+
+	// editors write a file in several events, reload once they settle
+	debounce := time.NewTimer(time.Hour)
+	debounce.Stop()
+	defer debounce.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case event, ok := <-watcher.Events:
+			if !ok {
+				return nil
+			}
+			if filepath.Clean(event.Name) == filepath.Clean(r.File) && event.Has(fsnotify.Write|fsnotify.Create) {
+				debounce.Reset(100 * time.Millisecond)
+			}
+		case err, ok := <-watcher.Errors:
+			if !ok {
+				return nil
+			}
+			r.Logger.WithError(err).Error("failed watching configuration file")
+		case <-debounce.C:
+			if err := r.Reload(); err != nil {
+				r.Logger.WithError(err).Error("failed reload configuration")
+			}
+		}
+	}
+}
+
+// merge compares next with previous field by field. Changed settings tagged reload are returned
+// in keys, the other changed settings are restored from previous and returned in refused.
+func merge(previous, next reflect.Value) (keys []string, refused []string) {
+	for i := 0; i < next.NumField(); i++ {
+		field := next.Type().Field(i)
+		if !field.IsExported() {
+			continue
+		}
+
+		if field.Type.Kind() == reflect.Struct {
+			reloaded, kept := merge(previous.Field(i), next.Field(i))
+			keys = append(keys, reloaded...)
+			refused = append(refused, kept...)
+			continue
+		}
+
+		if reflect.DeepEqual(previous.Field(i).Interface(), next.Field(i).Interface()) {
+			continue
+		}
+
+		name := field.Tag.Get("env")
+		if field.Tag.Get("reload") == "true" {
+			keys = append(keys, name)
+			continue
+		}
+
+		next.Field(i).Set(previous.Field(i))
+		refused = append(refused, name)
+	}
+
+	return keys, refused
+}
@@ -0,0 +1,8 @@
+// Package migrations embeds the SQL migrations of every supported database driver,
+// one folder per DB_DRIVER value.
+package migrations
+
+import "embed"
+
+//go:embed mysql/*.sql postgres/*.sql sqlite/*.sql
+var FS embed.FS
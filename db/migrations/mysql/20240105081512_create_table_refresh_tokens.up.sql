@@ -0,0 +1,14 @@
+CREATE TABLE refresh_tokens(
+    id INT NOT NULL AUTO_INCREMENT,
+    user_id INT NOT NULL,
+    family_id VARCHAR(36) NOT NULL,
+    token_hash VARCHAR(64) NOT NULL,
+    expires_at BIGINT NOT NULL,
+    used_at BIGINT NOT NULL DEFAULT 0,
+    revoked_at BIGINT NOT NULL DEFAULT 0,
+    created_at BIGINT NOT NULL,
+    PRIMARY KEY(id),
+    UNIQUE KEY refresh_tokens_token_hash_unique(token_hash),
+    KEY refresh_tokens_family_id_index(family_id),
+    CONSTRAINT refresh_tokens_user_id_foreign FOREIGN KEY(user_id) REFERENCES users(id) ON DELETE CASCADE
+);
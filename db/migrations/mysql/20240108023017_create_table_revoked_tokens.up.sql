@@ -0,0 +1,8 @@
+CREATE TABLE revoked_tokens(
+    token_id VARCHAR(36) NOT NULL,
+    user_id INT NOT NULL,
+    expires_at BIGINT NOT NULL,
+    created_at BIGINT NOT NULL,
+    PRIMARY KEY(token_id),
+    KEY revoked_tokens_expires_at_index(expires_at)
+);
@@ -0,0 +1,5 @@
+CREATE TABLE user_token_revocations(
+    user_id INT NOT NULL,
+    revoked_at BIGINT NOT NULL,
+    PRIMARY KEY(user_id)
+);
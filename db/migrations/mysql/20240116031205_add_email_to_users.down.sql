@@ -0,0 +1,4 @@
+ALTER TABLE users
+    DROP INDEX users_email_unique,
+    DROP COLUMN email_verified_at,
+    DROP COLUMN email;
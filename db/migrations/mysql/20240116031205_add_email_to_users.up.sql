@@ -0,0 +1,4 @@
+ALTER TABLE users
+    ADD COLUMN email VARCHAR(255) NULL AFTER username,
+    ADD COLUMN email_verified_at BIGINT NOT NULL DEFAULT 0 AFTER email,
+    ADD UNIQUE KEY users_email_unique(email);
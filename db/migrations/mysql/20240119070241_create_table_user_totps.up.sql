@@ -0,0 +1,10 @@
+CREATE TABLE user_totps(
+    user_id INT NOT NULL,
+    secret VARCHAR(64) NOT NULL,
+    confirmed_at BIGINT NOT NULL DEFAULT 0,
+    last_used_step BIGINT NOT NULL DEFAULT 0,
+    created_at BIGINT NOT NULL,
+    updated_at BIGINT NOT NULL,
+    PRIMARY KEY(user_id),
+    CONSTRAINT user_totps_user_id_foreign FOREIGN KEY(user_id) REFERENCES users(id) ON DELETE CASCADE
+);
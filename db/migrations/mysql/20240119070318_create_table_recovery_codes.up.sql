@@ -0,0 +1,10 @@
+CREATE TABLE recovery_codes(
+    id INT NOT NULL AUTO_INCREMENT,
+    user_id INT NOT NULL,
+    code_hash VARCHAR(64) NOT NULL,
+    used_at BIGINT NOT NULL DEFAULT 0,
+    created_at BIGINT NOT NULL,
+    PRIMARY KEY(id),
+    UNIQUE KEY recovery_codes_user_id_code_hash_unique(user_id, code_hash),
+    CONSTRAINT recovery_codes_user_id_foreign FOREIGN KEY(user_id) REFERENCES users(id) ON DELETE CASCADE
+);
@@ -0,0 +1,7 @@
+CREATE TABLE login_attempts(
+    attempt_key VARCHAR(255) NOT NULL,
+    failures INT NOT NULL DEFAULT 0,
+    last_failed_at BIGINT NOT NULL,
+    PRIMARY KEY(attempt_key),
+    KEY login_attempts_last_failed_at_index(last_failed_at)
+);
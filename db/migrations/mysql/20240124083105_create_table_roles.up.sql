@@ -0,0 +1,9 @@
+CREATE TABLE roles(
+    id INT NOT NULL AUTO_INCREMENT,
+    name VARCHAR(100) NOT NULL,
+    description VARCHAR(255) NOT NULL DEFAULT '',
+    created_at BIGINT NOT NULL,
+    updated_at BIGINT NOT NULL,
+    PRIMARY KEY(id),
+    UNIQUE KEY roles_name_unique(name)
+);
@@ -0,0 +1,8 @@
+CREATE TABLE permissions(
+    id INT NOT NULL AUTO_INCREMENT,
+    name VARCHAR(100) NOT NULL,
+    description VARCHAR(255) NOT NULL DEFAULT '',
+    created_at BIGINT NOT NULL,
+    PRIMARY KEY(id),
+    UNIQUE KEY permissions_name_unique(name)
+);
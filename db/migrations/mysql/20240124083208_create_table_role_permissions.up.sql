@@ -0,0 +1,7 @@
+CREATE TABLE role_permissions(
+    role_id INT NOT NULL,
+    permission_id INT NOT NULL,
+    PRIMARY KEY(role_id, permission_id),
+    CONSTRAINT role_permissions_role_id_foreign FOREIGN KEY(role_id) REFERENCES roles(id) ON DELETE CASCADE,
+    CONSTRAINT role_permissions_permission_id_foreign FOREIGN KEY(permission_id) REFERENCES permissions(id) ON DELETE CASCADE
+);
@@ -0,0 +1,8 @@
+CREATE TABLE user_roles(
+    user_id INT NOT NULL,
+    role_id INT NOT NULL,
+    created_at BIGINT NOT NULL,
+    PRIMARY KEY(user_id, role_id),
+    CONSTRAINT user_roles_user_id_foreign FOREIGN KEY(user_id) REFERENCES users(id) ON DELETE CASCADE,
+    CONSTRAINT user_roles_role_id_foreign FOREIGN KEY(role_id) REFERENCES roles(id) ON DELETE CASCADE
+);
@@ -0,0 +1,2 @@
+INSERT INTO roles(name, description, created_at, updated_at)
+VALUES ('admin', 'Full access to user and role management', UNIX_TIMESTAMP() * 1000, UNIX_TIMESTAMP() * 1000);
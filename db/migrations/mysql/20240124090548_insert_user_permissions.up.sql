@@ -0,0 +1,4 @@
+INSERT INTO permissions(name, description, created_at)
+VALUES ('users:read', 'List and view users', UNIX_TIMESTAMP() * 1000),
+    ('users:write', 'Manage user accounts', UNIX_TIMESTAMP() * 1000),
+    ('roles:write', 'Grant and revoke user roles', UNIX_TIMESTAMP() * 1000);
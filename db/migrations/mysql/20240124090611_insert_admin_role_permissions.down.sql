@@ -0,0 +1 @@
+DELETE FROM role_permissions WHERE role_id IN (SELECT id FROM roles WHERE name = 'admin');
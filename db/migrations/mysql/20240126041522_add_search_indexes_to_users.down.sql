@@ -0,0 +1,4 @@
+ALTER TABLE users
+    DROP INDEX users_name_index,
+    DROP INDEX users_username_index,
+    DROP INDEX users_created_at_index;
@@ -0,0 +1,4 @@
+ALTER TABLE users
+    ADD KEY users_name_index(name),
+    ADD KEY users_username_index(username),
+    ADD KEY users_created_at_index(created_at);
@@ -0,0 +1,3 @@
+ALTER TABLE users
+    DROP INDEX users_deleted_at_index,
+    DROP COLUMN deleted_at;
@@ -0,0 +1,3 @@
+ALTER TABLE users
+    ADD COLUMN deleted_at DATETIME(3) NULL AFTER updated_at,
+    ADD KEY users_deleted_at_index(deleted_at);
@@ -0,0 +1,25 @@
+-- usernames are compared in lower case since they are canonicalised. Lower-casing must not merge two
+-- accounts, and LOWER only agrees with the NFKC normalisation of the application on ASCII, so the
+-- migration refuses to run while a username only differs by case from another or is not ASCII, and
+-- lists them to be renamed first. SIGNAL keeps the first 128 characters of the list.
+DROP PROCEDURE IF EXISTS check_lowercase_usernames;
+
+CREATE PROCEDURE check_lowercase_usernames()
+BEGIN
+    DECLARE conflicts TEXT;
+
+    SELECT GROUP_CONCAT(username ORDER BY LOWER(username), id SEPARATOR ', ') INTO conflicts
+    FROM users
+    WHERE LENGTH(username) <> CHAR_LENGTH(username)
+        OR LOWER(username) IN (SELECT LOWER(username) FROM users GROUP BY LOWER(username) HAVING COUNT(*) > 1);
+
+    IF conflicts IS NOT NULL THEN
+        SET conflicts = LEFT(CONCAT('rename the usernames that only differ by case or are not ASCII: ', conflicts), 128);
+        SIGNAL SQLSTATE '45000' SET MESSAGE_TEXT = conflicts;
+    END IF;
+END;
+
+CALL check_lowercase_usernames();
+DROP PROCEDURE check_lowercase_usernames;
+
+UPDATE users SET username = LOWER(username);
@@ -0,0 +1 @@
+ALTER TABLE users ADD UNIQUE KEY users_username_unique(username);
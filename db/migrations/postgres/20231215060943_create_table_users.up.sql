@@ -0,0 +1,9 @@
+CREATE TABLE users(
+    id SERIAL NOT NULL,
+    name VARCHAR(100) NOT NULL,
+    username VARCHAR(100) NOT NULL,
+    password VARCHAR(255) NOT NULL,
+    created_at BIGINT NOT NULL,
+    updated_at BIGINT NOT NULL,
+    PRIMARY KEY(id)
+);
@@ -0,0 +1,15 @@
+CREATE TABLE refresh_tokens(
+    id SERIAL NOT NULL,
+    user_id INTEGER NOT NULL,
+    family_id VARCHAR(36) NOT NULL,
+    token_hash VARCHAR(64) NOT NULL,
+    expires_at BIGINT NOT NULL,
+    used_at BIGINT NOT NULL DEFAULT 0,
+    revoked_at BIGINT NOT NULL DEFAULT 0,
+    created_at BIGINT NOT NULL,
+    PRIMARY KEY(id),
+    CONSTRAINT refresh_tokens_token_hash_unique UNIQUE(token_hash),
+    CONSTRAINT refresh_tokens_user_id_foreign FOREIGN KEY(user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+CREATE INDEX refresh_tokens_family_id_index ON refresh_tokens(family_id);
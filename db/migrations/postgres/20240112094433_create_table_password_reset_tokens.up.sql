@@ -0,0 +1,11 @@
+CREATE TABLE password_reset_tokens(
+    id SERIAL NOT NULL,
+    user_id INTEGER NOT NULL,
+    token_hash VARCHAR(64) NOT NULL,
+    expires_at BIGINT NOT NULL,
+    used_at BIGINT NOT NULL DEFAULT 0,
+    created_at BIGINT NOT NULL,
+    PRIMARY KEY(id),
+    CONSTRAINT password_reset_tokens_token_hash_unique UNIQUE(token_hash),
+    CONSTRAINT password_reset_tokens_user_id_foreign FOREIGN KEY(user_id) REFERENCES users(id) ON DELETE CASCADE
+);
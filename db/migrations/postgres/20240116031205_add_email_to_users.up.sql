@@ -0,0 +1,4 @@
+ALTER TABLE users
+    ADD COLUMN email VARCHAR(255) NULL,
+    ADD COLUMN email_verified_at BIGINT NOT NULL DEFAULT 0,
+    ADD CONSTRAINT users_email_unique UNIQUE(email);
@@ -0,0 +1,8 @@
+CREATE TABLE permissions(
+    id SERIAL NOT NULL,
+    name VARCHAR(100) NOT NULL,
+    description VARCHAR(255) NOT NULL DEFAULT '',
+    created_at BIGINT NOT NULL,
+    PRIMARY KEY(id),
+    CONSTRAINT permissions_name_unique UNIQUE(name)
+);
@@ -0,0 +1,2 @@
+INSERT INTO roles(name, description, created_at, updated_at)
+VALUES ('admin', 'Full access to user and role management', CAST(EXTRACT(EPOCH FROM NOW()) * 1000 AS BIGINT), CAST(EXTRACT(EPOCH FROM NOW()) * 1000 AS BIGINT));
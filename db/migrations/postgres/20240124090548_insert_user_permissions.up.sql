@@ -0,0 +1,4 @@
+INSERT INTO permissions(name, description, created_at)
+VALUES ('users:read', 'List and view users', CAST(EXTRACT(EPOCH FROM NOW()) * 1000 AS BIGINT)),
+    ('users:write', 'Manage user accounts', CAST(EXTRACT(EPOCH FROM NOW()) * 1000 AS BIGINT)),
+    ('roles:write', 'Grant and revoke user roles', CAST(EXTRACT(EPOCH FROM NOW()) * 1000 AS BIGINT));
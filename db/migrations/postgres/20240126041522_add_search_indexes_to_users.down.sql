@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS users_name_index;
+DROP INDEX IF EXISTS users_username_index;
+DROP INDEX IF EXISTS users_created_at_index;
@@ -0,0 +1,2 @@
+ALTER TABLE users ADD COLUMN deleted_at TIMESTAMP(3) NULL;
+CREATE INDEX users_deleted_at_index ON users(deleted_at);
@@ -0,0 +1 @@
+ALTER TABLE users ADD COLUMN locked_at BIGINT NOT NULL DEFAULT 0;
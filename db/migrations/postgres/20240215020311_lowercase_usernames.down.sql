@@ -0,0 +1,2 @@
+-- the original case of the usernames is not kept, rolling back leaves them in lower case
+SELECT 1;
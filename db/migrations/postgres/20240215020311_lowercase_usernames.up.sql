@@ -0,0 +1,19 @@
+-- usernames are compared in lower case since they are canonicalised. Lower-casing must not merge two
+-- accounts, and LOWER only agrees with the NFKC normalisation of the application on ASCII, so the
+-- migration refuses to run while a username only differs by case from another or is not ASCII, and
+-- lists them to be renamed first.
+DO $$
+DECLARE
+    conflicts TEXT;
+BEGIN
+    SELECT string_agg(username, ', ' ORDER BY LOWER(username), id) INTO conflicts
+    FROM users
+    WHERE octet_length(username) <> char_length(username)
+        OR LOWER(username) IN (SELECT LOWER(username) FROM users GROUP BY LOWER(username) HAVING COUNT(*) > 1);
+
+    IF conflicts IS NOT NULL THEN
+        RAISE EXCEPTION 'rename the usernames that only differ by case or are not ASCII before migrating: %', conflicts;
+    END IF;
+END $$;
+
+UPDATE users SET username = LOWER(username);
@@ -0,0 +1,9 @@
+CREATE TABLE revoked_tokens(
+    token_id VARCHAR(36) NOT NULL,
+    user_id INTEGER NOT NULL,
+    expires_at BIGINT NOT NULL,
+    created_at BIGINT NOT NULL,
+    PRIMARY KEY(token_id)
+);
+
+CREATE INDEX revoked_tokens_expires_at_index ON revoked_tokens(expires_at);
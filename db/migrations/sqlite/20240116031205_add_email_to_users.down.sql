@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS users_email_unique;
+ALTER TABLE users DROP COLUMN email_verified_at;
+ALTER TABLE users DROP COLUMN email;
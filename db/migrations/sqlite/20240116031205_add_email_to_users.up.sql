@@ -0,0 +1,3 @@
+ALTER TABLE users ADD COLUMN email VARCHAR(255) NULL;
+ALTER TABLE users ADD COLUMN email_verified_at BIGINT NOT NULL DEFAULT 0;
+CREATE UNIQUE INDEX users_email_unique ON users(email);
@@ -0,0 +1,9 @@
+CREATE TABLE recovery_codes(
+    id INTEGER NOT NULL PRIMARY KEY AUTOINCREMENT,
+    user_id INTEGER NOT NULL,
+    code_hash VARCHAR(64) NOT NULL,
+    used_at BIGINT NOT NULL DEFAULT 0,
+    created_at BIGINT NOT NULL,
+    CONSTRAINT recovery_codes_user_id_code_hash_unique UNIQUE(user_id, code_hash),
+    CONSTRAINT recovery_codes_user_id_foreign FOREIGN KEY(user_id) REFERENCES users(id) ON DELETE CASCADE
+);
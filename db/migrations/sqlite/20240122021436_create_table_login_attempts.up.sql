@@ -0,0 +1,8 @@
+CREATE TABLE login_attempts(
+    attempt_key VARCHAR(255) NOT NULL,
+    failures INTEGER NOT NULL DEFAULT 0,
+    last_failed_at BIGINT NOT NULL,
+    PRIMARY KEY(attempt_key)
+);
+
+CREATE INDEX login_attempts_last_failed_at_index ON login_attempts(last_failed_at);
@@ -0,0 +1,7 @@
+CREATE TABLE permissions(
+    id INTEGER NOT NULL PRIMARY KEY AUTOINCREMENT,
+    name VARCHAR(100) NOT NULL,
+    description VARCHAR(255) NOT NULL DEFAULT '',
+    created_at BIGINT NOT NULL,
+    CONSTRAINT permissions_name_unique UNIQUE(name)
+);
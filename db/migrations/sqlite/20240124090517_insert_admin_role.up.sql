@@ -0,0 +1,2 @@
+INSERT INTO roles(name, description, created_at, updated_at)
+VALUES ('admin', 'Full access to user and role management', CAST(strftime('%s', 'now') AS INTEGER) * 1000, CAST(strftime('%s', 'now') AS INTEGER) * 1000);
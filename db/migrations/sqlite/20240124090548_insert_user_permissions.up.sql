@@ -0,0 +1,4 @@
+INSERT INTO permissions(name, description, created_at)
+VALUES ('users:read', 'List and view users', CAST(strftime('%s', 'now') AS INTEGER) * 1000),
+    ('users:write', 'Manage user accounts', CAST(strftime('%s', 'now') AS INTEGER) * 1000),
+    ('roles:write', 'Grant and revoke user roles', CAST(strftime('%s', 'now') AS INTEGER) * 1000);
@@ -0,0 +1,3 @@
+CREATE INDEX users_name_index ON users(name);
+CREATE INDEX users_username_index ON users(username);
+CREATE INDEX users_created_at_index ON users(created_at);
@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS users_deleted_at_index;
+ALTER TABLE users DROP COLUMN deleted_at;
@@ -0,0 +1,19 @@
+-- usernames are compared in lower case since they are canonicalised. Lower-casing must not merge two
+-- accounts, and LOWER only agrees with the NFKC normalisation of the application on ASCII, so the
+-- migration refuses to run while a username only differs by case from another or is not ASCII.
+-- RAISE only takes a literal message in sqlite, the README has the query listing them.
+CREATE TEMP TABLE username_conflicts(username TEXT);
+
+CREATE TEMP TRIGGER username_conflicts_abort AFTER INSERT ON username_conflicts
+BEGIN
+    SELECT RAISE(ABORT, 'rename the usernames that only differ by case or are not ASCII before migrating');
+END;
+
+INSERT INTO username_conflicts
+SELECT username FROM users
+WHERE length(CAST(username AS BLOB)) <> length(username)
+    OR LOWER(username) IN (SELECT LOWER(username) FROM users GROUP BY LOWER(username) HAVING COUNT(*) > 1);
+
+DROP TABLE username_conflicts;
+
+UPDATE users SET username = LOWER(username);
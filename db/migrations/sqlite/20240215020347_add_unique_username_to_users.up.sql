@@ -0,0 +1 @@
+CREATE UNIQUE INDEX users_username_unique ON users(username);
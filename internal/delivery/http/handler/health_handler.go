@@ -0,0 +1,33 @@
+package handler
+
+import (
+	"github.com/Ikhlashmulya/golang-clean-architecture-project-structure/internal/infrastructure"
+	"github.com/Ikhlashmulya/golang-clean-architecture-project-structure/internal/model"
+	"github.com/gofiber/fiber/v2"
+)
+
+type HealthHandler struct {
+	Health *infrastructure.Health
+}
+
+func NewHealthHandler(health *infrastructure.Health) *HealthHandler {
+	return &HealthHandler{
+		Health: health,
+	}
+}
+
+// Live only tells that the process serves requests, it stays up during shutdown so the pod is not restarted.
+func (h *HealthHandler) Live(c *fiber.Ctx) error {
+	return c.JSON(&model.HealthResponse{Status: infrastructure.HealthStatusOK})
+}
+
+func (h *HealthHandler) Ready(c *fiber.Ctx) error {
+	c.Set(fiber.HeaderCacheControl, "no-store")
+
+	response := h.Health.Ready(c.UserContext())
+	if response.Status != infrastructure.HealthStatusOK {
+		c.Status(fiber.StatusServiceUnavailable)
+	}
+
+	return c.JSON(response)
+}
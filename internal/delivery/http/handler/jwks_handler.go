@@ -0,0 +1,22 @@
+package handler
+
+import (
+	"github.com/Ikhlashmulya/golang-clean-architecture-project-structure/internal/infrastructure"
+	"github.com/gofiber/fiber/v2"
+)
+
+type JWKSHandler struct {
+	TokenSigner infrastructure.TokenSigner
+}
+
+func NewJWKSHandler(tokenSigner infrastructure.TokenSigner) *JWKSHandler {
+	return &JWKSHandler{
+		TokenSigner: tokenSigner,
+	}
+}
+
+func (h *JWKSHandler) Get(c *fiber.Ctx) error {
+	c.Set(fiber.HeaderCacheControl, "public, max-age=300")
+
+	return c.JSON(h.TokenSigner.JWKS())
+}
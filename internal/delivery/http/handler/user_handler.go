@@ -1,6 +1,8 @@
 package handler
 
 import (
+	"github.com/Ikhlashmulya/golang-clean-architecture-project-structure/internal/exception"
+	"github.com/Ikhlashmulya/golang-clean-architecture-project-structure/internal/infrastructure"
 	"github.com/Ikhlashmulya/golang-clean-architecture-project-structure/internal/model"
 	"github.com/Ikhlashmulya/golang-clean-architecture-project-structure/internal/usecase"
 	"github.com/gofiber/fiber/v2"
@@ -22,13 +24,13 @@ func NewUserHandler(userUsecase usecase.UserUsecase, log *logrus.Logger) *UserHa
 func (h *UserHandler) Register(c *fiber.Ctx) error {
 	registerUserRequest := new(model.RegisterUserRequest)
 	if err := c.BodyParser(registerUserRequest); err != nil {
-		h.Logger.WithError(err).Error("error parsing request body")
+		h.log(c).WithError(err).Error("error parsing request body")
 		return err
 	}
 
-	response, err := h.UserUsecase.Register(c.Context(), registerUserRequest)
+	response, err := h.UserUsecase.Register(c.UserContext(), registerUserRequest)
 	if err != nil {
-		h.Logger.WithError(err).Error("error user register")
+		h.log(c).WithError(err).Error("error user register")
 		return err
 	}
 
@@ -42,13 +44,14 @@ func (h *UserHandler) Register(c *fiber.Ctx) error {
 func (h *UserHandler) Login(c *fiber.Ctx) error {
 	loginUserReequest := new(model.LoginUserRequest)
 	if err := c.BodyParser(loginUserReequest); err != nil {
-		h.Logger.WithError(err).Error("error parsing request body")
+		h.log(c).WithError(err).Error("error parsing request body")
 		return err
 	}
 
-	response, err := h.UserUsecase.Login(c.Context(), loginUserReequest)
+	loginUserReequest.IPAddress = c.IP()
+	response, err := h.UserUsecase.Login(c.UserContext(), loginUserReequest)
 	if err != nil {
-		h.Logger.WithError(err).Error("error user login")
+		h.log(c).WithError(err).Error("error user login")
 		return err
 	}
 
@@ -58,12 +61,107 @@ func (h *UserHandler) Login(c *fiber.Ctx) error {
 		})
 }
 
+func (h *UserHandler) LoginMFA(c *fiber.Ctx) error {
+	loginMFARequest := new(model.LoginMFARequest)
+	if err := c.BodyParser(loginMFARequest); err != nil {
+		h.log(c).WithError(err).Error("error parsing request body")
+		return err
+	}
+
+	loginMFARequest.IPAddress = c.IP()
+	response, err := h.UserUsecase.LoginMFA(c.UserContext(), loginMFARequest)
+	if err != nil {
+		h.log(c).WithError(err).Error("error user login mfa")
+		return err
+	}
+
+	return c.
+		JSON(&model.WebResponse[*model.TokenResponse]{
+			Data: response,
+		})
+}
+
+func (h *UserHandler) Refresh(c *fiber.Ctx) error {
+	refreshTokenRequest := new(model.RefreshTokenRequest)
+	if err := c.BodyParser(refreshTokenRequest); err != nil {
+		h.log(c).WithError(err).Error("error parsing request body")
+		return err
+	}
+
+	response, err := h.UserUsecase.Refresh(c.UserContext(), refreshTokenRequest)
+	if err != nil {
+		h.log(c).WithError(err).Error("error refresh token")
+		return err
+	}
+
+	return c.
+		JSON(&model.WebResponse[*model.TokenResponse]{
+			Data: response,
+		})
+}
+
+func (h *UserHandler) ForgotPassword(c *fiber.Ctx) error {
+	forgotPasswordRequest := new(model.ForgotPasswordRequest)
+	if err := c.BodyParser(forgotPasswordRequest); err != nil {
+		h.log(c).WithError(err).Error("error parsing request body")
+		return err
+	}
+
+	if err := h.UserUsecase.ForgotPassword(c.UserContext(), forgotPasswordRequest); err != nil {
+		h.log(c).WithError(err).Error("error forgot password")
+		return err
+	}
+
+	return c.
+		Status(fiber.StatusAccepted).
+		JSON(&model.WebResponse[bool]{
+			Data: true,
+		})
+}
+
+func (h *UserHandler) ResetPassword(c *fiber.Ctx) error {
+	resetPasswordRequest := new(model.ResetPasswordRequest)
+	if err := c.BodyParser(resetPasswordRequest); err != nil {
+		h.log(c).WithError(err).Error("error parsing request body")
+		return err
+	}
+
+	if err := h.UserUsecase.ResetPassword(c.UserContext(), resetPasswordRequest); err != nil {
+		h.log(c).WithError(err).Error("error reset password")
+		return err
+	}
+
+	return c.
+		JSON(&model.WebResponse[bool]{
+			Data: true,
+		})
+}
+
+func (h *UserHandler) VerifyEmail(c *fiber.Ctx) error {
+	verifyEmailRequest := new(model.VerifyEmailRequest)
+	if err := c.BodyParser(verifyEmailRequest); err != nil {
+		h.log(c).WithError(err).Error("error parsing request body")
+		return err
+	}
+
+	response, err := h.UserUsecase.VerifyEmail(c.UserContext(), verifyEmailRequest)
+	if err != nil {
+		h.log(c).WithError(err).Error("error verify email")
+		return err
+	}
+
+	return c.
+		JSON(&model.WebResponse[*model.UserResponse]{
+			Data: response,
+		})
+}
+
 func (h *UserHandler) Current(c *fiber.Ctx) error {
 	auth := c.Locals("auth").(*model.Auth)
 
-	response, err := h.UserUsecase.Current(c.Context(), &model.GetUserRequest{Username: auth.Username})
+	response, err := h.UserUsecase.Current(c.UserContext(), &model.GetUserRequest{Username: auth.Username})
 	if err != nil {
-		h.Logger.WithError(err).Error("error get current user")
+		h.log(c).WithError(err).Error("error get current user")
 		return err
 	}
 
@@ -78,14 +176,211 @@ func (h *UserHandler) Update(c *fiber.Ctx) error {
 
 	updateUserRequest := new(model.UpdateUserRequest)
 	if err := c.BodyParser(updateUserRequest); err != nil {
-		h.Logger.WithError(err).Error("error parsing request body")
+		h.log(c).WithError(err).Error("error parsing request body")
 		return err
 	}
 
 	updateUserRequest.Username = auth.Username
-	response, err := h.UserUsecase.Update(c.Context(), updateUserRequest)
+	response, err := h.UserUsecase.Update(c.UserContext(), updateUserRequest)
+	if err != nil {
+		h.log(c).WithError(err).Error("error update user")
+		return err
+	}
+
+	return c.
+		JSON(&model.WebResponse[*model.UserResponse]{
+			Data: response,
+		})
+
+}
+
+func (h *UserHandler) Logout(c *fiber.Ctx) error {
+	auth := c.Locals("auth").(*model.Auth)
+
+	if err := h.UserUsecase.Logout(c.UserContext(), toLogoutUserRequest(auth)); err != nil {
+		h.log(c).WithError(err).Error("error logout user")
+		return err
+	}
+
+	return c.
+		JSON(&model.WebResponse[bool]{
+			Data: true,
+		})
+}
+
+func (h *UserHandler) LogoutAll(c *fiber.Ctx) error {
+	auth := c.Locals("auth").(*model.Auth)
+
+	if err := h.UserUsecase.LogoutAll(c.UserContext(), toLogoutUserRequest(auth)); err != nil {
+		h.log(c).WithError(err).Error("error logout user from all sessions")
+		return err
+	}
+
+	return c.
+		JSON(&model.WebResponse[bool]{
+			Data: true,
+		})
+}
+
+func (h *UserHandler) EnrollTOTP(c *fiber.Ctx) error {
+	auth := c.Locals("auth").(*model.Auth)
+
+	response, err := h.UserUsecase.EnrollTOTP(c.UserContext(), &model.EnrollTOTPRequest{ID: auth.ID})
+	if err != nil {
+		h.log(c).WithError(err).Error("error enroll totp")
+		return err
+	}
+
+	return c.
+		Status(fiber.StatusCreated).
+		JSON(&model.WebResponse[*model.TOTPEnrollmentResponse]{
+			Data: response,
+		})
+}
+
+func (h *UserHandler) ConfirmTOTP(c *fiber.Ctx) error {
+	auth := c.Locals("auth").(*model.Auth)
+
+	confirmTOTPRequest := new(model.ConfirmTOTPRequest)
+	if err := c.BodyParser(confirmTOTPRequest); err != nil {
+		h.log(c).WithError(err).Error("error parsing request body")
+		return err
+	}
+
+	confirmTOTPRequest.ID = auth.ID
+	response, err := h.UserUsecase.ConfirmTOTP(c.UserContext(), confirmTOTPRequest)
+	if err != nil {
+		h.log(c).WithError(err).Error("error confirm totp")
+		return err
+	}
+
+	return c.
+		JSON(&model.WebResponse[*model.RecoveryCodesResponse]{
+			Data: response,
+		})
+}
+
+func (h *UserHandler) DisableTOTP(c *fiber.Ctx) error {
+	auth := c.Locals("auth").(*model.Auth)
+
+	disableTOTPRequest := new(model.DisableTOTPRequest)
+	if err := c.BodyParser(disableTOTPRequest); err != nil {
+		h.log(c).WithError(err).Error("error parsing request body")
+		return err
+	}
+
+	disableTOTPRequest.ID = auth.ID
+	if err := h.UserUsecase.DisableTOTP(c.UserContext(), disableTOTPRequest); err != nil {
+		h.log(c).WithError(err).Error("error disable totp")
+		return err
+	}
+
+	return c.
+		JSON(&model.WebResponse[bool]{
+			Data: true,
+		})
+}
+
+func toLogoutUserRequest(auth *model.Auth) *model.LogoutUserRequest {
+	return &model.LogoutUserRequest{
+		ID:        auth.ID,
+		TokenID:   auth.TokenID,
+		SessionID: auth.SessionID,
+		ExpiresAt: auth.ExpiresAt,
+	}
+}
+
+func (h *UserHandler) Unlock(c *fiber.Ctx) error {
+	unlockUserRequest := &model.UnlockUserRequest{Username: c.Params("username")}
+	if err := h.UserUsecase.UnlockUser(c.UserContext(), unlockUserRequest); err != nil {
+		h.log(c).WithError(err).Error("error unlock user")
+		return err
+	}
+
+	return c.
+		JSON(&model.WebResponse[bool]{
+			Data: true,
+		})
+}
+
+func (h *UserHandler) GrantRole(c *fiber.Ctx) error {
+	userRoleRequest := &model.UserRoleRequest{Username: c.Params("username"), Role: c.Params("role")}
+	if err := h.UserUsecase.GrantRole(c.UserContext(), userRoleRequest); err != nil {
+		h.log(c).WithError(err).Error("error grant role")
+		return err
+	}
+
+	return c.
+		JSON(&model.WebResponse[bool]{
+			Data: true,
+		})
+}
+
+func (h *UserHandler) RevokeRole(c *fiber.Ctx) error {
+	userRoleRequest := &model.UserRoleRequest{Username: c.Params("username"), Role: c.Params("role")}
+	if err := h.UserUsecase.RevokeRole(c.UserContext(), userRoleRequest); err != nil {
+		h.log(c).WithError(err).Error("error revoke role")
+		return err
+	}
+
+	return c.
+		JSON(&model.WebResponse[bool]{
+			Data: true,
+		})
+}
+
+func (h *UserHandler) List(c *fiber.Ctx) error {
+	searchUserRequest := new(model.SearchUserRequest)
+	if err := c.QueryParser(searchUserRequest); err != nil {
+		h.log(c).WithError(err).Error("error parsing query")
+		return exception.ErrRequestMalformed
+	}
+
+	responses, paging, err := h.UserUsecase.List(c.UserContext(), searchUserRequest)
+	if err != nil {
+		h.log(c).WithError(err).Error("error list users")
+		return err
+	}
+
+	return c.
+		JSON(&model.WebResponse[[]*model.UserResponse]{
+			Data:   responses,
+			Paging: paging,
+		})
+}
+
+func (h *UserHandler) Delete(c *fiber.Ctx) error {
+	auth := c.Locals("auth").(*model.Auth)
+
+	deleteUserRequest := new(model.DeleteUserRequest)
+	if err := c.BodyParser(deleteUserRequest); err != nil {
+		h.log(c).WithError(err).Error("error parsing request body")
+		return err
+	}
+
+	deleteUserRequest.ID = auth.ID
+	if err := h.UserUsecase.Delete(c.UserContext(), deleteUserRequest); err != nil {
+		h.log(c).WithError(err).Error("error delete user")
+		return err
+	}
+
+	return c.
+		JSON(&model.WebResponse[bool]{
+			Data: true,
+		})
+}
+
+func (h *UserHandler) Restore(c *fiber.Ctx) error {
+	restoreUserRequest := new(model.RestoreUserRequest)
+	if err := c.BodyParser(restoreUserRequest); err != nil {
+		h.log(c).WithError(err).Error("error parsing request body")
+		return err
+	}
+
+	restoreUserRequest.IPAddress = c.IP()
+	response, err := h.UserUsecase.Restore(c.UserContext(), restoreUserRequest)
 	if err != nil {
-		h.Logger.WithError(err).Error("error update user")
+		h.log(c).WithError(err).Error("error restore user")
 		return err
 	}
 
@@ -93,5 +388,8 @@ func (h *UserHandler) Update(c *fiber.Ctx) error {
 		JSON(&model.WebResponse[*model.UserResponse]{
 			Data: response,
 		})
+}
 
+func (h *UserHandler) log(c *fiber.Ctx) *logrus.Entry {
+	return infrastructure.LoggerFromContext(c.UserContext(), h.Logger)
 }
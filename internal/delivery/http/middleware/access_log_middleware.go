@@ -0,0 +1,66 @@
+package middleware
+
+import (
+	"math/rand"
+	"time"
+
+	"github.com/Ikhlashmulya/golang-clean-architecture-project-structure/config"
+	"github.com/Ikhlashmulya/golang-clean-architecture-project-structure/internal/infrastructure"
+	"github.com/gofiber/fiber/v2"
+	"github.com/sirupsen/logrus"
+)
+
+// NewAccessLog writes one line per request with the fields in ACCESS_LOG_FIELDS, through the request
+// logger so the line carries the request id, trace id and user id. Requests are sampled with
+// ACCESS_LOG_SAMPLE_PERCENT, server errors are always logged.
+func NewAccessLog(config *config.Config, logger *logrus.Logger) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		start := time.Now()
+		err := nextHandled(c)
+
+		settings := config.Current().Log.Access
+		status := c.Response().StatusCode()
+		serverError := status >= fiber.StatusInternalServerError
+		if !serverError && rand.Intn(100) >= settings.SamplePercent {
+			return nil
+		}
+
+		fields := logrus.Fields{}
+		for _, field := range settings.Fields {
+			switch field {
+			case "method":
+				fields[field] = c.Method()
+			case "route":
+				fields[field] = c.Route().Path
+				if isUnmatched(err) {
+					fields[field] = "unmatched"
+				}
+			case "path":
+				fields[field] = c.Path()
+			case "query":
+				fields[field] = string(c.Request().URI().QueryString())
+			case "status":
+				fields[field] = status
+			case "latency_ms":
+				fields[field] = float64(time.Since(start).Microseconds()) / 1000
+			case "ip":
+				fields[field] = c.IP()
+			case "user_agent":
+				fields[field] = c.Get(fiber.HeaderUserAgent)
+			case "referer":
+				fields[field] = c.Get(fiber.HeaderReferer)
+			case "bytes":
+				fields[field] = len(c.Response().Body())
+			}
+		}
+
+		entry := infrastructure.LoggerFromContext(c.UserContext(), logger).WithFields(fields)
+		if serverError {
+			entry.WithError(err).Error("request failed")
+		} else {
+			entry.Info("request completed")
+		}
+
+		return nil
+	}
+}
@@ -3,6 +3,8 @@ package middleware
 import (
 	"strings"
 
+	"github.com/Ikhlashmulya/golang-clean-architecture-project-structure/internal/exception"
+	"github.com/Ikhlashmulya/golang-clean-architecture-project-structure/internal/infrastructure"
 	"github.com/Ikhlashmulya/golang-clean-architecture-project-structure/internal/model"
 	"github.com/Ikhlashmulya/golang-clean-architecture-project-structure/internal/usecase"
 	"github.com/gofiber/fiber/v2"
@@ -14,18 +16,20 @@ func NewAuth(userUsecase usecase.UserUsecase, logger *logrus.Logger) fiber.Handl
 		authorization := c.Get("Authorization")
 
 		bearerToken := strings.Split(authorization, " ")
-		if bearerToken[0] != "Bearer" {
-			return fiber.ErrUnauthorized
+		if len(bearerToken) != 2 || bearerToken[0] != "Bearer" || bearerToken[1] == "" {
+			return exception.ErrUserUnauthorized
 		}
 
-		auth, err := userUsecase.Verify(c.Context(), &model.VerifyUserRequest{AccessToken: bearerToken[1]})
+		ctx := c.UserContext()
+		auth, err := userUsecase.Verify(ctx, &model.VerifyUserRequest{AccessToken: bearerToken[1]})
 		if err != nil {
-			logger.WithError(err).Warn("user not verified")
+			infrastructure.LoggerFromContext(ctx, logger).WithError(err).Warn("user not verified")
 			return err
 		}
 
 		c.Locals("auth", auth)
-		
+		c.SetUserContext(infrastructure.ContextWithLogger(ctx, infrastructure.LoggerFromContext(ctx, logger).WithField("user_id", auth.ID)))
+
 		return c.Next()
 	}
-}
\ No newline at end of file
+}
@@ -0,0 +1,36 @@
+package middleware
+
+import (
+	"github.com/Ikhlashmulya/golang-clean-architecture-project-structure/config"
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/middleware/cors"
+)
+
+// NewCORS answers browsers calling from an origin in CORS_ALLOW_ORIGINS. The list is read on every
+// request so a reload applies at once, other origins get no CORS headers and are blocked by the browser.
+func NewCORS(config *config.Config) fiber.Handler {
+	return cors.New(cors.Config{
+		Next: func(c *fiber.Ctx) bool {
+			return !allowedOrigin(config.Current().CORS.AllowOrigins, c.Get(fiber.HeaderOrigin))
+		},
+		// Next already rejected other origins, echo the caller's origin instead of "*"
+		AllowOriginsFunc: func(origin string) bool {
+			return true
+		},
+		AllowHeaders: "Origin, Content-Type, Accept, Authorization",
+	})
+}
+
+func allowedOrigin(allowOrigins []string, origin string) bool {
+	if origin == "" {
+		return false
+	}
+
+	for _, allowed := range allowOrigins {
+		if allowed == "*" || allowed == origin {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,59 @@
+package middleware
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Ikhlashmulya/golang-clean-architecture-project-structure/internal/infrastructure"
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/utils"
+)
+
+const handledErrorKey = "handledError"
+
+// NewMetrics counts and times every request by route pattern rather than path, so ids in the path
+// do not blow up the number of series. Requests that match no route are labelled "unmatched".
+func NewMetrics(metrics *infrastructure.Metrics) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		start := time.Now()
+
+		err := nextHandled(c)
+
+		// fiber reuses the request buffers, the collectors keep the label values
+		method := utils.CopyString(c.Method())
+		status := c.Response().StatusCode()
+		route := c.Route().Path
+		if isUnmatched(err) {
+			route = "unmatched"
+		}
+
+		labels := []string{method, route, strconv.Itoa(status)}
+		metrics.HTTPRequests.WithLabelValues(labels...).Inc()
+		metrics.HTTPRequestDuration.WithLabelValues(labels...).Observe(time.Since(start).Seconds())
+
+		return nil
+	}
+}
+
+// nextHandled runs the rest of the chain and, on error, the error handler right away instead of at the end
+// of the chain, so the response status is final once it returns. The error is returned for inspection
+// only, also to outer middlewares once an inner one handled it.
+func nextHandled(c *fiber.Ctx) error {
+	if err := c.Next(); err != nil {
+		c.Locals(handledErrorKey, err)
+		if err := c.App().ErrorHandler(c, err); err != nil {
+			_ = c.SendStatus(fiber.StatusInternalServerError)
+		}
+	}
+
+	err, _ := c.Locals(handledErrorKey).(error)
+	return err
+}
+
+// isUnmatched reports the error of the router for a path matching no route, a 404 "Cannot GET /path".
+func isUnmatched(err error) bool {
+	var fiberErr *fiber.Error
+	return errors.As(err, &fiberErr) && fiberErr.Code == fiber.StatusNotFound && strings.HasPrefix(fiberErr.Message, "Cannot ")
+}
@@ -0,0 +1,25 @@
+package middleware
+
+import (
+	"github.com/Ikhlashmulya/golang-clean-architecture-project-structure/internal/exception"
+	"github.com/Ikhlashmulya/golang-clean-architecture-project-structure/internal/model"
+	"github.com/gofiber/fiber/v2"
+)
+
+// RequirePermission must run after the auth middleware, it rejects users whose roles do not grant the permission.
+func RequirePermission(permission string) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		auth, ok := c.Locals("auth").(*model.Auth)
+		if !ok {
+			return exception.ErrUserUnauthorized
+		}
+
+		for _, granted := range auth.Permissions {
+			if granted == permission {
+				return c.Next()
+			}
+		}
+
+		return exception.ErrPermissionDenied
+	}
+}
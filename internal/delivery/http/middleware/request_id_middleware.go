@@ -0,0 +1,51 @@
+package middleware
+
+import (
+	"regexp"
+
+	"github.com/Ikhlashmulya/golang-clean-architecture-project-structure/internal/infrastructure"
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/utils"
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// requestIDPattern keeps ids sent by callers to plain tokens, so they cannot forge log lines.
+var requestIDPattern = regexp.MustCompile(`^[A-Za-z0-9._:-]{1,128}$`)
+
+// NewRequestID takes the X-Request-ID of the caller, or generates one, and echoes it in the response.
+// A logger with the request id and trace id is stored on the user context, handlers, usecases and
+// repositories log through it with infrastructure.LoggerFromContext.
+func NewRequestID(logger *logrus.Logger) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		requestID := c.Get(fiber.HeaderXRequestID)
+		if requestIDPattern.MatchString(requestID) {
+			requestID = utils.CopyString(requestID)
+		} else {
+			requestID = uuid.NewString()
+		}
+		c.Set(fiber.HeaderXRequestID, requestID)
+
+		ctx := c.UserContext()
+		entry := logger.WithField("request_id", requestID)
+		if spanContext := trace.SpanContextFromContext(ctx); spanContext.IsValid() {
+			entry = entry.WithField("trace_id", spanContext.TraceID().String())
+		}
+		c.SetUserContext(infrastructure.ContextWithLogger(ctx, entry))
+
+		return c.Next()
+	}
+}
+
+// NewRouteLogger adds the matched route to the request logger. Middlewares registered with app.Use run
+// before routing, so it is registered as the first handler of every route.
+func NewRouteLogger(logger *logrus.Logger) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		ctx := c.UserContext()
+		entry := infrastructure.LoggerFromContext(ctx, logger).WithField("route", c.Route().Path)
+		c.SetUserContext(infrastructure.ContextWithLogger(ctx, entry))
+
+		return c.Next()
+	}
+}
@@ -0,0 +1,53 @@
+package middleware
+
+import (
+	"strconv"
+
+	"github.com/Ikhlashmulya/golang-clean-architecture-project-structure/internal/infrastructure"
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/utils"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	semconv "go.opentelemetry.io/otel/semconv/v1.21.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// NewTracing starts a server span for every request, as a child of the W3C traceparent header when
+// the caller sent one. The span is stored in the user context, so handlers pass it on with c.UserContext().
+func NewTracing(provider trace.TracerProvider) fiber.Handler {
+	tracer := provider.Tracer(infrastructure.TracerName)
+	propagator := propagation.TraceContext{}
+
+	return func(c *fiber.Ctx) error {
+		// fiber reuses the request buffers, the exporter reads the attributes after the request ended
+		method := utils.CopyString(c.Method())
+		carrier := propagation.MapCarrier{
+			"traceparent": c.Get("traceparent"),
+			"tracestate":  c.Get("tracestate"),
+		}
+		ctx := propagator.Extract(c.UserContext(), carrier)
+
+		ctx, span := tracer.Start(ctx, method, trace.WithSpanKind(trace.SpanKindServer),
+			trace.WithAttributes(semconv.HTTPMethod(method), semconv.URLPath(utils.CopyString(c.Path()))))
+		defer span.End()
+		c.SetUserContext(ctx)
+
+		err := nextHandled(c)
+
+		status := c.Response().StatusCode()
+		span.SetAttributes(semconv.HTTPStatusCode(status))
+		if !isUnmatched(err) {
+			route := c.Route().Path
+			span.SetName(method + " " + route)
+			span.SetAttributes(semconv.HTTPRoute(route))
+		}
+		if status >= fiber.StatusInternalServerError {
+			if err != nil {
+				span.RecordError(err)
+			}
+			span.SetStatus(codes.Error, strconv.Itoa(status))
+		}
+
+		return nil
+	}
+}
@@ -2,15 +2,39 @@ package route
 
 import (
 	"github.com/Ikhlashmulya/golang-clean-architecture-project-structure/internal/delivery/http/handler"
+	"github.com/Ikhlashmulya/golang-clean-architecture-project-structure/internal/delivery/http/middleware"
 	"github.com/gofiber/fiber/v2"
 )
 
-func RegisterRoute(app *fiber.App, userHandler *handler.UserHandler, authMiddleware fiber.Handler) {
+func RegisterRoute(app *fiber.App, userHandler *handler.UserHandler, jwksHandler *handler.JWKSHandler,
+	healthHandler *handler.HealthHandler, authMiddleware, routeLogger fiber.Handler) {
+	app.Get("/healthz", routeLogger, healthHandler.Live)
+	app.Get("/readyz", routeLogger, healthHandler.Ready)
+	app.Get("/.well-known/jwks.json", routeLogger, jwksHandler.Get)
+
 	publicRouter := app.Group("/api")
-	publicRouter.Post("/users", userHandler.Register)
-	publicRouter.Post("/users/_login", userHandler.Login)
+	publicRouter.Post("/users", routeLogger, userHandler.Register)
+	publicRouter.Post("/users/_login", routeLogger, userHandler.Login)
+	publicRouter.Post("/users/_login/mfa", routeLogger, userHandler.LoginMFA)
+	publicRouter.Post("/users/_refresh", routeLogger, userHandler.Refresh)
+	publicRouter.Post("/users/_forgot-password", routeLogger, userHandler.ForgotPassword)
+	publicRouter.Post("/users/_reset-password", routeLogger, userHandler.ResetPassword)
+	publicRouter.Post("/users/_verify-email", routeLogger, userHandler.VerifyEmail)
+	publicRouter.Post("/users/_restore", routeLogger, userHandler.Restore)
 
 	protectedRouter := app.Group("/api", authMiddleware)
-	protectedRouter.Get("/users/_current", userHandler.Current)
-	protectedRouter.Patch("/users/_current", userHandler.Update)
+	protectedRouter.Get("/users/_current", routeLogger, userHandler.Current)
+	protectedRouter.Patch("/users/_current", routeLogger, userHandler.Update)
+	protectedRouter.Delete("/users/_current", routeLogger, userHandler.Delete)
+	protectedRouter.Delete("/users/_current/sessions/_current", routeLogger, userHandler.Logout)
+	protectedRouter.Delete("/users/_current/sessions", routeLogger, userHandler.LogoutAll)
+	protectedRouter.Post("/users/_current/mfa/totp", routeLogger, userHandler.EnrollTOTP)
+	protectedRouter.Post("/users/_current/mfa/totp/_confirm", routeLogger, userHandler.ConfirmTOTP)
+	protectedRouter.Delete("/users/_current/mfa/totp", routeLogger, userHandler.DisableTOTP)
+
+	adminRouter := app.Group("/api/admin", authMiddleware)
+	adminRouter.Get("/users", routeLogger, middleware.RequirePermission("users:read"), userHandler.List)
+	adminRouter.Post("/users/:username/_unlock", routeLogger, middleware.RequirePermission("users:write"), userHandler.Unlock)
+	adminRouter.Put("/users/:username/roles/:role", routeLogger, middleware.RequirePermission("roles:write"), userHandler.GrantRole)
+	adminRouter.Delete("/users/:username/roles/:role", routeLogger, middleware.RequirePermission("roles:write"), userHandler.RevokeRole)
 }
@@ -0,0 +1,112 @@
+package delivery
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/Ikhlashmulya/golang-clean-architecture-project-structure/config"
+	"github.com/Ikhlashmulya/golang-clean-architecture-project-structure/internal/delivery/http/handler"
+	"github.com/Ikhlashmulya/golang-clean-architecture-project-structure/internal/delivery/http/middleware"
+	"github.com/Ikhlashmulya/golang-clean-architecture-project-structure/internal/delivery/http/route"
+	"github.com/Ikhlashmulya/golang-clean-architecture-project-structure/internal/delivery/worker"
+	"github.com/Ikhlashmulya/golang-clean-architecture-project-structure/internal/infrastructure"
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/middleware/adaptor"
+	"github.com/google/wire"
+	"github.com/sirupsen/logrus"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// ProviderSet provides the http handlers, the middlewares and the background workers.
+var ProviderSet = wire.NewSet(
+	handler.NewUserHandler,
+	handler.NewJWKSHandler,
+	handler.NewHealthHandler,
+	middleware.NewAuth,
+	worker.NewUserPurger,
+	NewServer,
+)
+
+// Server is everything the serve command runs: the fiber app with every route registered and the
+// workers, both registered with Lifecycle.
+type Server struct {
+	App       *fiber.App
+	Lifecycle *infrastructure.Lifecycle
+}
+
+func NewServer(app *fiber.App, config *config.Config, lifecycle *infrastructure.Lifecycle, userHandler *handler.UserHandler,
+	jwksHandler *handler.JWKSHandler, healthHandler *handler.HealthHandler, authMiddleware fiber.Handler,
+	userPurger *worker.UserPurger, metrics *infrastructure.Metrics, tracerProvider trace.TracerProvider,
+	logger *logrus.Logger) *Server {
+	app.Use(middleware.NewTracing(tracerProvider))
+	app.Use(middleware.NewRequestID(logger))
+	if config.Metrics.Enabled {
+		app.Use(middleware.NewMetrics(metrics))
+	}
+	app.Use(middleware.NewAccessLog(config, logger))
+	app.Use(middleware.NewCORS(config))
+	route.RegisterRoute(app, userHandler, jwksHandler, healthHandler, authMiddleware, middleware.NewRouteLogger(logger))
+
+	// workers are registered before the http server so they keep running while requests drain
+	lifecycle.Go("user purger", userPurger.Run)
+
+	switch {
+	case !config.Metrics.Enabled:
+	case config.Metrics.Port == 0:
+		app.Get("/metrics", adaptor.HTTPHandler(metrics.Handler()))
+	default:
+		registerMetricsServer(config, lifecycle, metrics)
+	}
+
+	lifecycle.Append(infrastructure.Hook{
+		Name: "http server",
+		OnStart: func(ctx context.Context) error {
+			go func() {
+				if err := app.Listen(fmt.Sprintf(":%v", config.App.Port)); err != nil && !lifecycle.ShuttingDown() {
+					lifecycle.Fail(fmt.Errorf("error running app : %w", err))
+				}
+			}()
+			return nil
+		},
+		OnStop: func(ctx context.Context) error {
+			// readiness fails from now on, keep serving until the load balancer stopped routing here
+			select {
+			case <-time.After(config.App.ShutdownReadinessDelay):
+			case <-ctx.Done():
+			}
+			return app.ShutdownWithContext(ctx)
+		},
+	})
+
+	return &Server{
+		App:       app,
+		Lifecycle: lifecycle,
+	}
+}
+
+// registerMetricsServer serves /metrics on METRICS_PORT, it stops after the api so the drain can still be scraped.
+func registerMetricsServer(config *config.Config, lifecycle *infrastructure.Lifecycle, metrics *infrastructure.Metrics) {
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", metrics.Handler())
+	server := &http.Server{
+		Addr:              fmt.Sprintf(":%v", config.Metrics.Port),
+		Handler:           mux,
+		ReadHeaderTimeout: config.App.Timeout,
+	}
+
+	lifecycle.Append(infrastructure.Hook{
+		Name: "metrics server",
+		OnStart: func(ctx context.Context) error {
+			go func() {
+				if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+					lifecycle.Fail(fmt.Errorf("error running metrics server : %w", err))
+				}
+			}()
+			return nil
+		},
+		OnStop: server.Shutdown,
+	})
+}
@@ -0,0 +1,46 @@
+package worker
+
+import (
+	"context"
+	"time"
+
+	"github.com/Ikhlashmulya/golang-clean-architecture-project-structure/config"
+	"github.com/Ikhlashmulya/golang-clean-architecture-project-structure/internal/usecase"
+	"github.com/sirupsen/logrus"
+)
+
+// UserPurger periodically hard deletes accounts whose retention period after deletion has passed.
+type UserPurger struct {
+	UserUsecase usecase.UserUsecase
+	Logger      *logrus.Logger
+	Interval    time.Duration
+}
+
+func NewUserPurger(userUsecase usecase.UserUsecase, logger *logrus.Logger, config *config.Config) *UserPurger {
+	return &UserPurger{
+		UserUsecase: userUsecase,
+		Logger:      logger,
+		Interval:    config.Account.PurgeInterval,
+	}
+}
+
+// Run purges once right away and then on every tick until ctx is cancelled.
+func (p *UserPurger) Run(ctx context.Context) {
+	ticker := time.NewTicker(p.Interval)
+	defer ticker.Stop()
+
+	for {
+		purged, err := p.UserUsecase.PurgeDeleted(ctx)
+		if err != nil {
+			p.Logger.WithError(err).Error("error purge deleted users")
+		} else if purged > 0 {
+			p.Logger.WithField("purged", purged).Info("purged deleted users")
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
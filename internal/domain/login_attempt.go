@@ -0,0 +1,8 @@
+package domain
+
+// LoginAttempt counts recent failed logins for a throttle key, such as a username or a client IP.
+type LoginAttempt struct {
+	Key          string `gorm:"primaryKey;column:attempt_key"`
+	Failures     int    `gorm:"column:failures"`
+	LastFailedAt int64  `gorm:"column:last_failed_at"`
+}
@@ -0,0 +1,10 @@
+package domain
+
+type PasswordResetToken struct {
+	ID        uint   `gorm:"primaryKey;autoIncrement;column:id"`
+	UserID    uint   `gorm:"column:user_id"`
+	TokenHash string `gorm:"column:token_hash"`
+	ExpiresAt int64  `gorm:"column:expires_at"`
+	UsedAt    int64  `gorm:"column:used_at"`
+	CreatedAt int64  `gorm:"column:created_at;autoCreateTime:milli"`
+}
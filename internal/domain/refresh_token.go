@@ -0,0 +1,12 @@
+package domain
+
+type RefreshToken struct {
+	ID        uint   `gorm:"primaryKey;autoIncrement;column:id"`
+	UserID    uint   `gorm:"column:user_id"`
+	FamilyID  string `gorm:"column:family_id"`
+	TokenHash string `gorm:"column:token_hash"`
+	ExpiresAt int64  `gorm:"column:expires_at"`
+	UsedAt    int64  `gorm:"column:used_at"`
+	RevokedAt int64  `gorm:"column:revoked_at"`
+	CreatedAt int64  `gorm:"column:created_at;autoCreateTime:milli"`
+}
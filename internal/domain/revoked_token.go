@@ -0,0 +1,13 @@
+package domain
+
+type RevokedToken struct {
+	TokenID   string `gorm:"primaryKey;column:token_id"`
+	UserID    uint   `gorm:"column:user_id"`
+	ExpiresAt int64  `gorm:"column:expires_at"`
+	CreatedAt int64  `gorm:"column:created_at;autoCreateTime:milli"`
+}
+
+type UserTokenRevocation struct {
+	UserID    uint  `gorm:"primaryKey;column:user_id"`
+	RevokedAt int64 `gorm:"column:revoked_at"`
+}
@@ -0,0 +1,24 @@
+package domain
+
+type Role struct {
+	ID          uint         `gorm:"primaryKey;autoIncrement;column:id"`
+	Name        string       `gorm:"column:name"`
+	Description string       `gorm:"column:description"`
+	Permissions []Permission `gorm:"many2many:role_permissions"`
+	CreatedAt   int64        `gorm:"column:created_at;autoCreateTime:milli"`
+	UpdatedAt   int64        `gorm:"column:updated_at;autoCreateTime:milli;autoUpdateTime:milli"`
+}
+
+// Permission names follow the "resource:action" form, e.g. users:read.
+type Permission struct {
+	ID          uint   `gorm:"primaryKey;autoIncrement;column:id"`
+	Name        string `gorm:"column:name"`
+	Description string `gorm:"column:description"`
+	CreatedAt   int64  `gorm:"column:created_at;autoCreateTime:milli"`
+}
+
+type UserRole struct {
+	UserID    uint  `gorm:"primaryKey;column:user_id"`
+	RoleID    uint  `gorm:"primaryKey;column:role_id"`
+	CreatedAt int64 `gorm:"column:created_at;autoCreateTime:milli"`
+}
@@ -0,0 +1,18 @@
+package domain
+
+type UserTOTP struct {
+	UserID       uint   `gorm:"primaryKey;column:user_id"`
+	Secret       string `gorm:"column:secret"`
+	ConfirmedAt  int64  `gorm:"column:confirmed_at"`
+	LastUsedStep int64  `gorm:"column:last_used_step"`
+	CreatedAt    int64  `gorm:"column:created_at;autoCreateTime:milli"`
+	UpdatedAt    int64  `gorm:"column:updated_at;autoCreateTime:milli;autoUpdateTime:milli"`
+}
+
+type RecoveryCode struct {
+	ID        uint   `gorm:"primaryKey;autoIncrement;column:id"`
+	UserID    uint   `gorm:"column:user_id"`
+	CodeHash  string `gorm:"column:code_hash"`
+	UsedAt    int64  `gorm:"column:used_at"`
+	CreatedAt int64  `gorm:"column:created_at;autoCreateTime:milli"`
+}
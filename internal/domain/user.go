@@ -1,10 +1,16 @@
 package domain
 
+import "gorm.io/gorm"
+
 type User struct {
-	ID        uint   `gorm:"primaryKey;autoIncrement;column:id"`
-	Name      string `gorm:"column:name"`
-	Username  string `gorm:"column:username"`
-	Password  string `gorm:"column:password"`
-	CreatedAt int64  `gorm:"column:created_at;autoCreateTime:milli"`
-	UpdatedAt int64  `gorm:"column:updated_at;autoCreateTime:milli;autoUpdateTime:milli"`
+	ID              uint           `gorm:"primaryKey;autoIncrement;column:id"`
+	Name            string         `gorm:"column:name"`
+	Username        string         `gorm:"column:username"`
+	Email           *string        `gorm:"column:email"`
+	EmailVerifiedAt int64          `gorm:"column:email_verified_at"`
+	LockedAt        int64          `gorm:"column:locked_at"`
+	Password        string         `gorm:"column:password"`
+	CreatedAt       int64          `gorm:"column:created_at;autoCreateTime:milli"`
+	UpdatedAt       int64          `gorm:"column:updated_at;autoCreateTime:milli;autoUpdateTime:milli"`
+	DeletedAt       gorm.DeletedAt `gorm:"column:deleted_at"`
 }
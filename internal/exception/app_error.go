@@ -0,0 +1,40 @@
+package exception
+
+// AppError is an error the client can act on. Code is stable and machine readable, Detail is meant
+// for humans and may change. Errors match with errors.Is by code, so a copy carrying violations or
+// another detail still matches its declared error.
+type AppError struct {
+	Code       string
+	Status     int
+	Detail     string
+	Violations []Violation
+}
+
+// Violation is a field failing a validation rule, Param is the argument of the rule such as the
+// minimum length, empty for rules without one. Message describes it in the language of the client.
+type Violation struct {
+	Field   string
+	Rule    string
+	Param   string
+	Message string
+}
+
+func NewAppError(status int, code string, detail string) *AppError {
+	return &AppError{Code: code, Status: status, Detail: detail}
+}
+
+func (e *AppError) Error() string {
+	return e.Detail
+}
+
+func (e *AppError) Is(target error) bool {
+	appError, ok := target.(*AppError)
+	return ok && appError.Code == e.Code
+}
+
+// WithViolations returns a copy of e listing the given violations.
+func (e *AppError) WithViolations(violations []Violation) *AppError {
+	appError := *e
+	appError.Violations = violations
+	return &appError
+}
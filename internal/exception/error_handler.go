@@ -1,51 +1,151 @@
 package exception
 
 import (
-	"fmt"
+	"context"
+	"encoding/json"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
 
+	"github.com/Ikhlashmulya/golang-clean-architecture-project-structure/internal/model"
+	ut "github.com/go-playground/universal-translator"
 	"github.com/go-playground/validator/v10"
 	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/utils"
+	"github.com/sirupsen/logrus"
 )
 
-// define error here
+// define error here, the code is part of the api and must not change once released
 var (
 	// error user domain
-	ErrUserNotFound         = fiber.NewError(fiber.StatusNotFound, "User is not found")
-	ErrUserAlreadyExist     = fiber.NewError(fiber.StatusBadRequest, "username already exist")
-	ErrUserPasswordNotMatch = fiber.NewError(fiber.StatusBadRequest, "password not match")
-	ErrUserUnauthorized     = fiber.NewError(fiber.StatusUnauthorized, "User unauthorized")
+	ErrUserNotFound         = NewAppError(fiber.StatusNotFound, "USER_NOT_FOUND", "User is not found")
+	ErrUserAlreadyExist     = NewAppError(fiber.StatusBadRequest, "USERNAME_TAKEN", "username already exist")
+	ErrUserPasswordNotMatch = NewAppError(fiber.StatusBadRequest, "PASSWORD_MISMATCH", "password not match")
+	ErrUserUnauthorized     = NewAppError(fiber.StatusUnauthorized, "UNAUTHORIZED", "User unauthorized")
+	ErrUserLocked           = NewAppError(fiber.StatusTooManyRequests, "LOGIN_THROTTLED", "too many failed login attempts, try again later")
+	ErrAccountLocked        = NewAppError(fiber.StatusForbidden, "ACCOUNT_LOCKED", "account is locked")
+	ErrPermissionDenied     = NewAppError(fiber.StatusForbidden, "PERMISSION_DENIED", "permission denied")
+	ErrRoleNotFound         = NewAppError(fiber.StatusNotFound, "ROLE_NOT_FOUND", "role is not found")
+	ErrCursorInvalid        = NewAppError(fiber.StatusBadRequest, "CURSOR_INVALID", "cursor is invalid")
+	ErrEmailAlreadyExist    = NewAppError(fiber.StatusBadRequest, "EMAIL_TAKEN", "email already exist")
+	ErrEmailNotVerified     = NewAppError(fiber.StatusForbidden, "EMAIL_NOT_VERIFIED", "email is not verified")
+	ErrEmailTokenInvalid    = NewAppError(fiber.StatusBadRequest, "EMAIL_TOKEN_INVALID", "email verification token is invalid or expired")
+	ErrMFAAlreadyEnabled    = NewAppError(fiber.StatusBadRequest, "MFA_ALREADY_ENABLED", "two-factor authentication is already enabled")
+	ErrMFANotEnrolled       = NewAppError(fiber.StatusBadRequest, "MFA_NOT_ENROLLED", "two-factor authentication is not enrolled")
+	ErrMFACodeInvalid       = NewAppError(fiber.StatusUnauthorized, "MFA_CODE_INVALID", "two-factor code is invalid")
+	ErrMFATokenInvalid      = NewAppError(fiber.StatusUnauthorized, "MFA_TOKEN_INVALID", "mfa token is invalid or expired")
+	ErrRefreshTokenInvalid  = NewAppError(fiber.StatusUnauthorized, "REFRESH_TOKEN_INVALID", "refresh token is invalid")
+	ErrResetTokenInvalid    = NewAppError(fiber.StatusBadRequest, "RESET_TOKEN_INVALID", "password reset token is invalid or expired")
+	ErrPasswordBreached     = NewAppError(fiber.StatusBadRequest, "PASSWORD_BREACHED", "password appeared in a data breach, choose another one")
+
+	// error request
+	ErrValidationFailed = NewAppError(fiber.StatusBadRequest, "VALIDATION_FAILED", "request has invalid fields")
+	ErrRequestMalformed = NewAppError(fiber.StatusBadRequest, "MALFORMED_REQUEST", "request is malformed")
 
 	//error
-	ErrInternalServerError = fiber.ErrInternalServerError
+	ErrInternalServerError = NewAppError(fiber.StatusInternalServerError, "INTERNAL_ERROR", "an unexpected error occurred")
 )
 
-func NewErrorHandler() fiber.ErrorHandler {
+// LockedError is returned while logins are throttled, it matches ErrUserLocked with errors.Is
+// and tells the error handler what to send in the Retry-After header.
+type LockedError struct {
+	RetryAfter time.Duration
+}
+
+func (e *LockedError) Error() string {
+	return ErrUserLocked.Error()
+}
+
+func (e *LockedError) Unwrap() error {
+	return ErrUserLocked
+}
+
+const problemContentType = "application/problem+json"
+
+// NewErrorHandler renders every error as an RFC 7807 problem. Errors that are no AppError, validation
+// or client error of fiber are logged with the request logger and answered with a generic
+// INTERNAL_ERROR, so internals never reach the client. The detail and the violation messages are
+// translated to the best of Languages for the Accept-Language of the request.
+func NewErrorHandler(logger func(ctx context.Context) *logrus.Entry, translator *ut.UniversalTranslator) fiber.ErrorHandler {
 	return func(c *fiber.Ctx, err error) error {
-		statusCode := fiber.StatusInternalServerError
-		var message any
+		trans, _ := translator.GetTranslator(c.AcceptsLanguages(Languages...))
+		c.Vary(fiber.HeaderAcceptLanguage)
+		c.Set(fiber.HeaderContentLanguage, trans.Locale())
+
+		appError := toAppError(err, trans)
+		if appError == nil {
+			logger(c.UserContext()).WithError(err).Error("unhandled error")
+			appError = ErrInternalServerError
+		}
 
-		if e, ok := err.(*fiber.Error); ok {
-			statusCode = e.Code
-			message = e.Error()
+		var lockedError *LockedError
+		if errors.As(err, &lockedError) {
+			// round up so clients never retry while still locked
+			retryAfter := (lockedError.RetryAfter + time.Second - 1) / time.Second
+			c.Set(fiber.HeaderRetryAfter, strconv.FormatInt(int64(retryAfter), 10))
 		}
 
-		if validationErrors, ok := err.(validator.ValidationErrors); ok {
-			errorMessages := make([]string, 0)
-			for _, value := range validationErrors {
-				errorMessages = append(errorMessages, fmt.Sprintf(
-					"[%s]: '%v' | needs to implements '%s'",
-					value.Field(),
-					value.Value(),
-					value.ActualTag(),
-				))
-			}
-
-			statusCode = fiber.StatusBadRequest
-			message = errorMessages
+		detail := appError.Detail
+		if translated, err := trans.T(appError.Code); err == nil {
+			detail = translated
 		}
 
-		return c.Status(statusCode).JSON(fiber.Map{
-			"errors": message,
-		})
+		problem := &model.ProblemResponse{
+			Type:     "urn:problem-type:" + strings.ToLower(strings.ReplaceAll(appError.Code, "_", "-")),
+			Title:    utils.StatusMessage(appError.Status),
+			Status:   appError.Status,
+			Detail:   detail,
+			Instance: c.Path(),
+			Code:     appError.Code,
+		}
+		for _, violation := range appError.Violations {
+			problem.Errors = append(problem.Errors, model.ProblemViolation{
+				Field:   violation.Field,
+				Rule:    violation.Rule,
+				Param:   violation.Param,
+				Message: violation.Message,
+			})
+		}
+
+		if err := c.Status(appError.Status).JSON(problem); err != nil {
+			return err
+		}
+		c.Set(fiber.HeaderContentType, problemContentType)
+
+		return nil
+	}
+}
+
+// toAppError returns nil for errors whose message must not be shown to the client.
+func toAppError(err error, trans ut.Translator) *AppError {
+	var appError *AppError
+	var validationErrors validator.ValidationErrors
+	var syntaxError *json.SyntaxError
+	var typeError *json.UnmarshalTypeError
+	var fiberError *fiber.Error
+
+	switch {
+	case errors.As(err, &appError):
+		return appError
+	case errors.As(err, &validationErrors):
+		violations := make([]Violation, 0, len(validationErrors))
+		for _, value := range validationErrors {
+			violations = append(violations, Violation{
+				Field:   value.Field(),
+				Rule:    value.Tag(),
+				Param:   value.Param(),
+				Message: value.Translate(trans),
+			})
+		}
+		return ErrValidationFailed.WithViolations(violations)
+	case errors.As(err, &syntaxError), errors.As(err, &typeError):
+		return ErrRequestMalformed
+	case errors.As(err, &fiberError) && fiberError.Code < fiber.StatusInternalServerError:
+		// raised by fiber itself, such as for an unmatched route or an unsupported content type
+		code := strings.ToUpper(strings.ReplaceAll(utils.StatusMessage(fiberError.Code), " ", "_"))
+		return NewAppError(fiberError.Code, code, fiberError.Message)
+	default:
+		return nil
 	}
 }
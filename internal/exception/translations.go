@@ -0,0 +1,53 @@
+package exception
+
+import ut "github.com/go-playground/universal-translator"
+
+// Languages are the languages a problem can be rendered in, the first one is used when the client
+// accepts none of them.
+var Languages = []string{"en", "id"}
+
+// translations holds the detail of the declared errors by language and code. The errors are
+// declared in English, so English is not listed and a missing translation falls back to it.
+var translations = map[string]map[string]string{
+	"id": {
+		"USER_NOT_FOUND":        "Pengguna tidak ditemukan",
+		"USERNAME_TAKEN":        "username sudah digunakan",
+		"PASSWORD_MISMATCH":     "password tidak cocok",
+		"UNAUTHORIZED":          "Pengguna tidak terautentikasi",
+		"LOGIN_THROTTLED":       "terlalu banyak percobaan login yang gagal, coba lagi nanti",
+		"ACCOUNT_LOCKED":        "akun dikunci",
+		"PERMISSION_DENIED":     "akses ditolak",
+		"ROLE_NOT_FOUND":        "role tidak ditemukan",
+		"CURSOR_INVALID":        "cursor tidak valid",
+		"EMAIL_TAKEN":           "email sudah digunakan",
+		"EMAIL_NOT_VERIFIED":    "email belum diverifikasi",
+		"EMAIL_TOKEN_INVALID":   "token verifikasi email tidak valid atau sudah kedaluwarsa",
+		"MFA_ALREADY_ENABLED":   "autentikasi dua faktor sudah aktif",
+		"MFA_NOT_ENROLLED":      "autentikasi dua faktor belum didaftarkan",
+		"MFA_CODE_INVALID":      "kode dua faktor tidak valid",
+		"MFA_TOKEN_INVALID":     "token mfa tidak valid atau sudah kedaluwarsa",
+		"REFRESH_TOKEN_INVALID": "refresh token tidak valid",
+		"RESET_TOKEN_INVALID":   "token reset password tidak valid atau sudah kedaluwarsa",
+		"PASSWORD_BREACHED":     "password pernah bocor dalam pelanggaran data, pilih password lain",
+		"VALIDATION_FAILED":     "request memiliki field yang tidak valid",
+		"MALFORMED_REQUEST":     "format request tidak valid",
+		"INTERNAL_ERROR":        "terjadi kesalahan yang tidak terduga",
+	},
+}
+
+// RegisterTranslations adds the catalogue to the translators of translator, keyed by error code.
+func RegisterTranslations(translator *ut.UniversalTranslator) error {
+	for language, details := range translations {
+		trans, found := translator.GetTranslator(language)
+		if !found {
+			continue
+		}
+		for code, detail := range details {
+			if err := trans.Add(code, detail, false); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
@@ -1,21 +1,25 @@
 package infrastructure
 
 import (
-	"time"
+	"context"
 
+	"github.com/Ikhlashmulya/golang-clean-architecture-project-structure/config"
 	"github.com/Ikhlashmulya/golang-clean-architecture-project-structure/internal/exception"
+	ut "github.com/go-playground/universal-translator"
 	"github.com/gofiber/fiber/v2"
 	"github.com/gofiber/fiber/v2/middleware/recover"
-	"github.com/spf13/viper"
+	"github.com/sirupsen/logrus"
 )
 
-func NewFiber(config *viper.Viper) *fiber.App {
+func NewFiber(config *config.Config, translator *ut.UniversalTranslator, logger *logrus.Logger) *fiber.App {
 	app := fiber.New(fiber.Config{
-		AppName:      config.GetString("APP_NAME"),
-		ErrorHandler: exception.NewErrorHandler(),
-		Prefork:      config.GetBool("APP_PREFORK"),
-		WriteTimeout: config.GetDuration("APP_TIMEOUT") * time.Second,
-		ReadTimeout:  config.GetDuration("APP_TIMEOUT") * time.Second,
+		AppName: config.App.Name,
+		ErrorHandler: exception.NewErrorHandler(func(ctx context.Context) *logrus.Entry {
+			return LoggerFromContext(ctx, logger)
+		}, translator),
+		Prefork:      config.App.Prefork,
+		WriteTimeout: config.App.Timeout,
+		ReadTimeout:  config.App.Timeout,
 	})
 	app.Use(recover.New())
 
@@ -1,48 +1,104 @@
 package infrastructure
 
 import (
+	"context"
 	"fmt"
-	"time"
 
-	"github.com/spf13/viper"
+	"github.com/Ikhlashmulya/golang-clean-architecture-project-structure/config"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/sirupsen/logrus"
+	"go.opentelemetry.io/otel/trace"
 	"gorm.io/driver/mysql"
+	"gorm.io/driver/postgres"
+	"gorm.io/driver/sqlite"
 	"gorm.io/gorm"
 )
 
-func NewGorm(config *viper.Viper) *gorm.DB {
-	user := config.GetString("DB_USER")
-	password := config.GetString("DB_PASSWORD")
-	host := config.GetString("DB_HOST")
-	dbname := config.GetString("DB_NAME")
-	port := config.GetInt("DB_PORT")
-	idleConns := config.GetInt("POOL_IDLE")
-	maxConns := config.GetInt("POOL_MAX")
-	lifetime := config.GetDuration("POOL_LIFETIME") * time.Second
-
-	dsn := fmt.Sprintf(
-		"%v:%v@tcp(%v:%v)/%v?charset=utf8mb4&parseTime=True&loc=Local",
-		user,
-		password,
-		host,
-		port,
-		dbname,
-	)
-
-	db, err := gorm.Open(mysql.Open(dsn), &gorm.Config{
+// NewGorm opens the connection pool, the pool is pinged by readiness, exports its stats as metrics
+// and is closed when the lifecycle stops. Every statement is traced and logged with the span and logger of its context.
+func NewGorm(config *config.Config, lifecycle *Lifecycle, health *Health, metrics *Metrics, tracer trace.Tracer,
+	logger *logrus.Logger) (*gorm.DB, error) {
+	idleConns := config.DB.Pool.Idle
+	maxConns := config.DB.Pool.Max
+	lifetime := config.DB.Pool.Lifetime
+
+	dialector, err := newDialector(config)
+	if err != nil {
+		return nil, err
+	}
+
+	db, err := gorm.Open(dialector, &gorm.Config{
 		SkipDefaultTransaction: true,
+		Logger:                 newGormLogger(logger),
 	})
 	if err != nil {
-		panic(fmt.Errorf("error connecting database : %+v", err.Error()))
+		return nil, fmt.Errorf("error connecting database : %w", err)
+	}
+
+	if err := registerGormTracing(db, tracer, config.DB.Driver); err != nil {
+		return nil, fmt.Errorf("error registering database tracing : %w", err)
 	}
 
 	connection, err := db.DB()
 	if err != nil {
-		panic(err)
+		return nil, err
 	}
 
 	connection.SetMaxIdleConns(idleConns)
 	connection.SetMaxOpenConns(maxConns)
 	connection.SetConnMaxLifetime(lifetime)
 
-	return db
+	metrics.Registry.MustRegister(collectors.NewDBStatsCollector(connection, config.DB.Name))
+	health.Register(HealthCheck{
+		Name:  "database",
+		Check: connection.PingContext,
+	})
+	lifecycle.Append(Hook{
+		Name: "database",
+		OnStop: func(ctx context.Context) error {
+			return connection.Close()
+		},
+	})
+
+	return db, nil
+}
+
+// newDialector picks the gorm driver from DB_DRIVER. For sqlite, DB_NAME is the path of the database file.
+func newDialector(config *config.Config) (gorm.Dialector, error) {
+	user := config.DB.User
+	password := config.DB.Password
+	host := config.DB.Host
+	dbname := config.DB.Name
+	port := config.DB.Port
+
+	switch driver := config.DB.Driver; driver {
+	case "mysql":
+		dsn := fmt.Sprintf(
+			"%v:%v@tcp(%v:%v)/%v?charset=utf8mb4&parseTime=True&loc=Local",
+			user,
+			password,
+			host,
+			port,
+			dbname,
+		)
+		return mysql.Open(dsn), nil
+	case "postgres":
+		sslMode := config.DB.SSLMode
+		dsn := fmt.Sprintf(
+			"host=%v user=%v password=%v dbname=%v port=%v sslmode=%v",
+			host,
+			user,
+			password,
+			dbname,
+			port,
+			sslMode,
+		)
+		return postgres.Open(dsn), nil
+	case "sqlite":
+		// sqlite leaves foreign keys off per connection, the schema relies on ON DELETE CASCADE
+		dsn := fmt.Sprintf("file:%v?_foreign_keys=on&_busy_timeout=5000", dbname)
+		return sqlite.Open(dsn), nil
+	default:
+		return nil, fmt.Errorf("unsupported database driver : %q", driver)
+	}
 }
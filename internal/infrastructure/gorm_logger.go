@@ -0,0 +1,75 @@
+package infrastructure
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+	gormlogger "gorm.io/gorm/logger"
+)
+
+const slowQueryThreshold = 200 * time.Millisecond
+
+// gormLogger writes the logs of gorm through the request scoped logger on the statement context, so
+// a failed or slow query can be tied to its request. Every statement is logged at debug level, with
+// placeholders instead of the values since those include password hashes and tokens.
+type gormLogger struct {
+	Logger *logrus.Logger
+	level  gormlogger.LogLevel
+}
+
+func newGormLogger(logger *logrus.Logger) gormlogger.Interface {
+	return &gormLogger{Logger: logger, level: gormlogger.Warn}
+}
+
+func (l *gormLogger) LogMode(level gormlogger.LogLevel) gormlogger.Interface {
+	return &gormLogger{Logger: l.Logger, level: level}
+}
+
+func (l *gormLogger) Info(ctx context.Context, msg string, args ...interface{}) {
+	if l.level >= gormlogger.Info {
+		LoggerFromContext(ctx, l.Logger).Infof(msg, args...)
+	}
+}
+
+func (l *gormLogger) Warn(ctx context.Context, msg string, args ...interface{}) {
+	if l.level >= gormlogger.Warn {
+		LoggerFromContext(ctx, l.Logger).Warnf(msg, args...)
+	}
+}
+
+func (l *gormLogger) Error(ctx context.Context, msg string, args ...interface{}) {
+	if l.level >= gormlogger.Error {
+		LoggerFromContext(ctx, l.Logger).Errorf(msg, args...)
+	}
+}
+
+func (l *gormLogger) Trace(ctx context.Context, begin time.Time, fc func() (string, int64), err error) {
+	if l.level <= gormlogger.Silent {
+		return
+	}
+
+	elapsed := time.Since(begin)
+	logger := LoggerFromContext(ctx, l.Logger)
+	fields := func() logrus.Fields {
+		sql, rows := fc()
+		return logrus.Fields{"sql": sql, "rows": rows, "latency_ms": float64(elapsed.Microseconds()) / 1000}
+	}
+
+	switch {
+	case err != nil && !errors.Is(err, gorm.ErrRecordNotFound) && l.level >= gormlogger.Error:
+		logger.WithFields(fields()).WithError(err).Error("failed database query")
+	case elapsed > slowQueryThreshold && l.level >= gormlogger.Warn:
+		logger.WithFields(fields()).Warn("slow database query")
+	case l.level >= gormlogger.Info:
+		logger.WithFields(fields()).Info("database query")
+	case logger.Logger.IsLevelEnabled(logrus.DebugLevel):
+		logger.WithFields(fields()).Debug("database query")
+	}
+}
+
+func (l *gormLogger) ParamsFilter(ctx context.Context, sql string, params ...interface{}) (string, []interface{}) {
+	return sql, nil
+}
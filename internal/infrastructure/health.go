@@ -0,0 +1,114 @@
+package infrastructure
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/Ikhlashmulya/golang-clean-architecture-project-structure/config"
+	"github.com/Ikhlashmulya/golang-clean-architecture-project-structure/internal/model"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	HealthStatusOK           = "ok"
+	HealthStatusFail         = "fail"
+	HealthStatusShuttingDown = "shutting down"
+)
+
+// HealthCheck reports whether a dependency can serve requests. Timeout falls back to APP_HEALTH_CHECK_TIMEOUT.
+type HealthCheck struct {
+	Name    string
+	Timeout time.Duration
+	Check   func(ctx context.Context) error
+}
+
+// Health runs the readiness checks registered by the components, readiness fails as soon as the lifecycle stops.
+type Health struct {
+	Lifecycle *Lifecycle
+	Logger    *logrus.Logger
+	Timeout   time.Duration
+
+	mu     sync.Mutex
+	checks []HealthCheck
+}
+
+func NewHealth(config *config.Config, lifecycle *Lifecycle, logger *logrus.Logger) *Health {
+	return &Health{
+		Lifecycle: lifecycle,
+		Logger:    logger,
+		Timeout:   config.App.HealthCheckTimeout,
+	}
+}
+
+func (h *Health) Register(check HealthCheck) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.checks = append(h.checks, check)
+}
+
+// Ready runs every check concurrently, each within its own timeout.
+func (h *Health) Ready(ctx context.Context) *model.HealthResponse {
+	if h.Lifecycle.ShuttingDown() {
+		return &model.HealthResponse{Status: HealthStatusShuttingDown}
+	}
+
+	h.mu.Lock()
+	checks := h.checks
+	h.mu.Unlock()
+
+	response := &model.HealthResponse{
+		Status: HealthStatusOK,
+		Checks: make(map[string]model.HealthCheckResponse, len(checks)),
+	}
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for _, check := range checks {
+		wg.Add(1)
+		go func(check HealthCheck) {
+			defer wg.Done()
+			result := h.run(ctx, check)
+
+			mu.Lock()
+			defer mu.Unlock()
+			response.Checks[check.Name] = result
+			if result.Status != HealthStatusOK {
+				response.Status = HealthStatusFail
+			}
+		}(check)
+	}
+	wg.Wait()
+
+	return response
+}
+
+func (h *Health) run(ctx context.Context, check HealthCheck) model.HealthCheckResponse {
+	timeout := check.Timeout
+	if timeout <= 0 {
+		timeout = h.Timeout
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	start := time.Now()
+	err := check.Check(ctx)
+	result := model.HealthCheckResponse{
+		Status:    HealthStatusOK,
+		LatencyMs: float64(time.Since(start).Microseconds()) / 1000,
+	}
+
+	if err != nil {
+		h.Logger.WithError(err).WithField("check", check.Name).Warn("health check failed")
+		result.Status = HealthStatusFail
+		result.Error = "unavailable"
+		if errors.Is(err, context.DeadlineExceeded) {
+			result.Error = "timeout"
+		}
+	}
+
+	return result
+}
@@ -0,0 +1,347 @@
+package infrastructure
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+
+	"github.com/Ikhlashmulya/golang-clean-architecture-project-structure/config"
+	"github.com/Ikhlashmulya/golang-clean-architecture-project-structure/internal/model"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// hmacKeyID is the kid used for tokens signed with the shared JWT_SECRET_KEY.
+const hmacKeyID = "hmac"
+
+type TokenSigner interface {
+	Sign(claims jwt.Claims) (string, error)
+	Parse(tokenString string) (jwt.MapClaims, error)
+	// JWKS returns the public verification keys, symmetric keys are never published.
+	JWKS() *model.JSONWebKeySet
+}
+
+type verificationKey struct {
+	methods []jwt.SigningMethod
+	key     any
+	jwk     *model.JSONWebKey
+}
+
+// keyMethods are the algorithms accepted for a key of their type, a key file does not say which one
+// it signed with so the token names it.
+var keyMethods = []jwt.SigningMethod{
+	jwt.SigningMethodHS256, jwt.SigningMethodHS384, jwt.SigningMethodHS512,
+	jwt.SigningMethodRS256, jwt.SigningMethodRS384, jwt.SigningMethodRS512,
+	jwt.SigningMethodPS256, jwt.SigningMethodPS384, jwt.SigningMethodPS512,
+	jwt.SigningMethodES256, jwt.SigningMethodES384, jwt.SigningMethodES512,
+	jwt.SigningMethodEdDSA,
+}
+
+type JWTSigner struct {
+	Method     jwt.SigningMethod
+	KeyID      string
+	SigningKey any
+	keys       map[string]*verificationKey
+	keyIDs     []string
+}
+
+// NewTokenSigner signs with JWT_SIGNING_METHOD (HS256 by default). Asymmetric methods sign with the PEM
+// private key in JWT_PRIVATE_KEY_FILE; keys being rotated out are listed as comma separated PEM files in
+// JWT_VERIFICATION_KEY_FILES and keep verifying tokens until they are removed. Secrets rotated out are
+// listed in JWT_PREVIOUS_SECRET_KEYS the same way.
+func NewTokenSigner(config *config.Config) (TokenSigner, error) {
+	signer, err := newJWTSigner(
+		config.Auth.JWT.SigningMethod,
+		config.Auth.JWT.SecretKey,
+		config.Auth.JWT.PrivateKeyFile,
+		config.Auth.JWT.PreviousSecretKeys,
+		config.Auth.JWT.VerificationKeyFiles,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("error loading jwt keys : %w", err)
+	}
+
+	return signer, nil
+}
+
+func newJWTSigner(alg, secret, privateKeyFile string, previousSecrets, verificationKeyFiles []string) (*JWTSigner, error) {
+	if alg == "" {
+		alg = jwt.SigningMethodHS256.Alg()
+	}
+
+	method := jwt.GetSigningMethod(alg)
+	if method == nil || method == jwt.SigningMethodNone {
+		return nil, fmt.Errorf("unsupported signing method %q", alg)
+	}
+
+	signer := &JWTSigner{Method: method, keys: make(map[string]*verificationKey)}
+
+	if _, ok := method.(*jwt.SigningMethodHMAC); ok {
+		// tokens only name the hmac key, each secret is tried in turn
+		secrets := jwt.VerificationKeySet{Keys: []jwt.VerificationKey{[]byte(secret)}}
+		for _, previous := range previousSecrets {
+			secrets.Keys = append(secrets.Keys, []byte(previous))
+		}
+
+		signer.KeyID = hmacKeyID
+		signer.SigningKey = []byte(secret)
+		signer.addKey(hmacKeyID, &verificationKey{methods: methodsForKey([]byte(secret)), key: secrets})
+	} else {
+		data, err := os.ReadFile(privateKeyFile)
+		if err != nil {
+			return nil, err
+		}
+
+		privateKey, err := parsePrivateKeyPEM(data)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", privateKeyFile, err)
+		}
+
+		if !methodAcceptsKey(method, privateKey.Public()) {
+			return nil, fmt.Errorf("%s: key type does not match signing method %s", privateKeyFile, alg)
+		}
+
+		key, err := newVerificationKey(privateKey.Public())
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", privateKeyFile, err)
+		}
+		key.jwk.Algorithm = method.Alg()
+
+		signer.KeyID = key.jwk.KeyID
+		signer.SigningKey = privateKey
+		signer.addKey(key.jwk.KeyID, key)
+	}
+
+	for _, file := range verificationKeyFiles {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return nil, err
+		}
+
+		publicKey, err := parsePublicKeyPEM(data)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", file, err)
+		}
+
+		key, err := newVerificationKey(publicKey)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", file, err)
+		}
+
+		signer.addKey(key.jwk.KeyID, key)
+	}
+
+	return signer, nil
+}
+
+func (s *JWTSigner) addKey(keyID string, key *verificationKey) {
+	if _, ok := s.keys[keyID]; ok {
+		return
+	}
+	s.keys[keyID] = key
+	s.keyIDs = append(s.keyIDs, keyID)
+}
+
+func (s *JWTSigner) Sign(claims jwt.Claims) (string, error) {
+	token := jwt.NewWithClaims(s.Method, claims)
+	token.Header["kid"] = s.KeyID
+	return token.SignedString(s.SigningKey)
+}
+
+func (s *JWTSigner) Parse(tokenString string) (jwt.MapClaims, error) {
+	token, err := jwt.Parse(tokenString, s.keyFunc)
+	if err != nil {
+		return nil, err
+	}
+
+	claims, ok := token.Claims.(jwt.MapClaims)
+	if !ok {
+		return nil, jwt.ErrTokenInvalidClaims
+	}
+
+	return claims, nil
+}
+
+func (s *JWTSigner) keyFunc(token *jwt.Token) (interface{}, error) {
+	keyID, _ := token.Header["kid"].(string)
+	if keyID == "" {
+		keyID = s.KeyID
+	}
+
+	key, ok := s.keys[keyID]
+	if !ok {
+		return nil, fmt.Errorf("unknown key id %q", keyID)
+	}
+
+	// the algorithms are restricted to the key type so a public key can never be used as an HMAC secret
+	for _, method := range key.methods {
+		if token.Method.Alg() == method.Alg() {
+			return key.key, nil
+		}
+	}
+
+	return nil, fmt.Errorf("unexpected signing method %s for key %q", token.Method.Alg(), keyID)
+}
+
+func (s *JWTSigner) JWKS() *model.JSONWebKeySet {
+	keySet := &model.JSONWebKeySet{Keys: make([]model.JSONWebKey, 0, len(s.keyIDs))}
+	for _, keyID := range s.keyIDs {
+		if jwk := s.keys[keyID].jwk; jwk != nil {
+			keySet.Keys = append(keySet.Keys, *jwk)
+		}
+	}
+	return keySet
+}
+
+// newVerificationKey accepts every algorithm of the key type, the published key only names one when
+// it is the signing key.
+func newVerificationKey(publicKey crypto.PublicKey) (*verificationKey, error) {
+	jwk, err := toJSONWebKey(publicKey)
+	if err != nil {
+		return nil, err
+	}
+
+	methods := methodsForKey(publicKey)
+	if len(methods) == 0 {
+		return nil, fmt.Errorf("unsupported public key type %T", publicKey)
+	}
+
+	jwk.KeyID = jwkThumbprint(jwk)
+	jwk.Use = "sig"
+
+	return &verificationKey{methods: methods, key: publicKey, jwk: jwk}, nil
+}
+
+func parsePrivateKeyPEM(data []byte) (crypto.Signer, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("invalid PEM data")
+	}
+
+	switch block.Type {
+	case "RSA PRIVATE KEY":
+		key, err := x509.ParsePKCS1PrivateKey(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+		return key, nil
+	case "EC PRIVATE KEY":
+		key, err := x509.ParseECPrivateKey(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+		return key, nil
+	case "PRIVATE KEY":
+		key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+		signer, ok := key.(crypto.Signer)
+		if !ok {
+			return nil, fmt.Errorf("unsupported private key type %T", key)
+		}
+		return signer, nil
+	default:
+		return nil, fmt.Errorf("unsupported PEM block %q", block.Type)
+	}
+}
+
+func parsePublicKeyPEM(data []byte) (crypto.PublicKey, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("invalid PEM data")
+	}
+
+	switch block.Type {
+	case "PUBLIC KEY":
+		return x509.ParsePKIXPublicKey(block.Bytes)
+	case "RSA PUBLIC KEY":
+		return x509.ParsePKCS1PublicKey(block.Bytes)
+	default:
+		privateKey, err := parsePrivateKeyPEM(data)
+		if err != nil {
+			return nil, err
+		}
+		return privateKey.Public(), nil
+	}
+}
+
+func methodsForKey(key any) []jwt.SigningMethod {
+	var methods []jwt.SigningMethod
+	for _, method := range keyMethods {
+		if methodAcceptsKey(method, key) {
+			methods = append(methods, method)
+		}
+	}
+	return methods
+}
+
+func methodAcceptsKey(method jwt.SigningMethod, key any) bool {
+	switch m := method.(type) {
+	case *jwt.SigningMethodHMAC:
+		_, ok := key.([]byte)
+		return ok
+	case *jwt.SigningMethodRSA, *jwt.SigningMethodRSAPSS:
+		_, ok := key.(*rsa.PublicKey)
+		return ok
+	case *jwt.SigningMethodECDSA:
+		ecKey, ok := key.(*ecdsa.PublicKey)
+		return ok && ecKey.Curve.Params().BitSize == m.CurveBits
+	case *jwt.SigningMethodEd25519:
+		_, ok := key.(ed25519.PublicKey)
+		return ok
+	}
+	return false
+}
+
+func toJSONWebKey(publicKey crypto.PublicKey) (*model.JSONWebKey, error) {
+	encode := base64.RawURLEncoding.EncodeToString
+
+	switch key := publicKey.(type) {
+	case *rsa.PublicKey:
+		return &model.JSONWebKey{
+			KeyType: "RSA",
+			N:       encode(key.N.Bytes()),
+			E:       encode(big.NewInt(int64(key.E)).Bytes()),
+		}, nil
+	case *ecdsa.PublicKey:
+		size := (key.Curve.Params().BitSize + 7) / 8
+		return &model.JSONWebKey{
+			KeyType: "EC",
+			Curve:   key.Curve.Params().Name,
+			X:       encode(key.X.FillBytes(make([]byte, size))),
+			Y:       encode(key.Y.FillBytes(make([]byte, size))),
+		}, nil
+	case ed25519.PublicKey:
+		return &model.JSONWebKey{
+			KeyType: "OKP",
+			Curve:   "Ed25519",
+			X:       encode(key),
+		}, nil
+	}
+	return nil, fmt.Errorf("unsupported public key type %T", publicKey)
+}
+
+// jwkThumbprint computes the RFC 7638 thumbprint, used as a stable kid that needs no configuration.
+func jwkThumbprint(jwk *model.JSONWebKey) string {
+	var members string
+	switch jwk.KeyType {
+	case "RSA":
+		members = fmt.Sprintf(`{"e":%q,"kty":"RSA","n":%q}`, jwk.E, jwk.N)
+	case "EC":
+		members = fmt.Sprintf(`{"crv":%q,"kty":"EC","x":%q,"y":%q}`, jwk.Curve, jwk.X, jwk.Y)
+	case "OKP":
+		members = fmt.Sprintf(`{"crv":%q,"kty":"OKP","x":%q}`, jwk.Curve, jwk.X)
+	}
+
+	sum := sha256.Sum256([]byte(members))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
@@ -0,0 +1,164 @@
+package infrastructure
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/Ikhlashmulya/golang-clean-architecture-project-structure/config"
+	"github.com/sirupsen/logrus"
+)
+
+// Hook is a component registered with the Lifecycle. OnStart must not block, long running work
+// belongs in a goroutine, see Lifecycle.Go. Either function may be nil.
+type Hook struct {
+	Name    string
+	OnStart func(ctx context.Context) error
+	OnStop  func(ctx context.Context) error
+}
+
+// Lifecycle starts the registered components in order and stops them in reverse order, so a
+// component is stopped before the resources it was built on are closed.
+type Lifecycle struct {
+	Logger  *logrus.Logger
+	Timeout time.Duration
+
+	mu           sync.Mutex
+	hooks        []Hook
+	started      int
+	shuttingDown atomic.Bool
+	stopOnce     sync.Once
+	stopErr      error
+	failed       chan error
+}
+
+func NewLifecycle(config *config.Config, logger *logrus.Logger) *Lifecycle {
+	lifecycle := &Lifecycle{
+		Logger:  logger,
+		Timeout: config.App.ShutdownTimeout,
+		failed:  make(chan error, 1),
+	}
+
+	// registered first so it runs last, once every component logged its shutdown
+	lifecycle.Append(Hook{
+		Name: "logger",
+		OnStop: func(ctx context.Context) error {
+			if out, ok := logger.Out.(interface{ Sync() error }); ok {
+				_ = out.Sync()
+			}
+			return nil
+		},
+	})
+
+	return lifecycle
+}
+
+func (l *Lifecycle) Append(hook Hook) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.hooks = append(l.hooks, hook)
+}
+
+// Go registers a worker that runs until Stop cancels its context, Stop then waits for it to return.
+func (l *Lifecycle) Go(name string, run func(ctx context.Context)) {
+	var cancel context.CancelFunc
+	done := make(chan struct{})
+
+	l.Append(Hook{
+		Name: name,
+		OnStart: func(context.Context) error {
+			var ctx context.Context
+			ctx, cancel = context.WithCancel(context.Background())
+			go func() {
+				defer close(done)
+				run(ctx)
+			}()
+			return nil
+		},
+		OnStop: func(ctx context.Context) error {
+			cancel()
+			select {
+			case <-done:
+				return nil
+			case <-ctx.Done():
+				return fmt.Errorf("did not stop in time : %w", ctx.Err())
+			}
+		},
+	})
+}
+
+// Start runs every OnStart in registration order. When one fails, the components already started are stopped.
+func (l *Lifecycle) Start(ctx context.Context) error {
+	l.mu.Lock()
+	hooks := l.hooks
+	l.mu.Unlock()
+
+	for i, hook := range hooks {
+		if hook.OnStart != nil {
+			if err := hook.OnStart(ctx); err != nil {
+				_ = l.Stop()
+				return fmt.Errorf("error starting %s : %w", hook.Name, err)
+			}
+		}
+
+		l.mu.Lock()
+		l.started = i + 1
+		l.mu.Unlock()
+	}
+
+	return nil
+}
+
+// Fail reports a component that stopped on its own, the first failure is delivered on Failed.
+func (l *Lifecycle) Fail(err error) {
+	select {
+	case l.failed <- err:
+	default:
+	}
+}
+
+func (l *Lifecycle) Failed() <-chan error {
+	return l.failed
+}
+
+// ShuttingDown is true from the moment Stop is called, readiness reports failing from then on.
+func (l *Lifecycle) ShuttingDown() bool {
+	return l.shuttingDown.Load()
+}
+
+// Stop runs every OnStop in reverse order within APP_SHUTDOWN_TIMEOUT. Hooks with an OnStart are
+// only stopped when they were started. Stop runs once, later calls return the same error.
+func (l *Lifecycle) Stop() error {
+	l.stopOnce.Do(func() {
+		l.shuttingDown.Store(true)
+
+		ctx, cancel := context.WithTimeout(context.Background(), l.Timeout)
+		defer cancel()
+
+		l.mu.Lock()
+		hooks, started := l.hooks, l.started
+		l.mu.Unlock()
+
+		var errs []error
+		for i := len(hooks) - 1; i >= 0; i-- {
+			hook := hooks[i]
+			if hook.OnStop == nil || (hook.OnStart != nil && i >= started) {
+				continue
+			}
+
+			l.Logger.WithField("component", hook.Name).Debug("stopping component")
+			if err := hook.OnStop(ctx); err != nil {
+				l.Logger.WithError(err).WithField("component", hook.Name).Error("failed stop component")
+				errs = append(errs, fmt.Errorf("%s: %w", hook.Name, err))
+			}
+		}
+
+		l.stopErr = errors.Join(errs...)
+	})
+
+	return l.stopErr
+}
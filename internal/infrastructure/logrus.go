@@ -1,15 +1,33 @@
 package infrastructure
 
 import (
+	"context"
+
+	"github.com/Ikhlashmulya/golang-clean-architecture-project-structure/config"
 	"github.com/sirupsen/logrus"
-	"github.com/spf13/viper"
 )
 
-func NewLogger(config *viper.Viper) *logrus.Logger {
+func NewLogger(config *config.Config) *logrus.Logger {
 	logger := logrus.New()
 
-	logger.SetLevel(logrus.Level(config.GetInt("LOG_LEVEL")))
+	logger.SetLevel(logrus.Level(config.Log.Level))
 	logger.SetFormatter(&logrus.JSONFormatter{})
 
 	return logger
 }
+
+type loggerKey struct{}
+
+// ContextWithLogger stores a request scoped logger on ctx, carrying fields such as the request id.
+func ContextWithLogger(ctx context.Context, logger *logrus.Entry) context.Context {
+	return context.WithValue(ctx, loggerKey{}, logger)
+}
+
+// LoggerFromContext returns the logger stored on ctx, or logger itself outside of a request such as
+// in workers and cli commands.
+func LoggerFromContext(ctx context.Context, logger *logrus.Logger) *logrus.Entry {
+	if entry, ok := ctx.Value(loggerKey{}).(*logrus.Entry); ok {
+		return entry
+	}
+	return logrus.NewEntry(logger)
+}
@@ -0,0 +1,139 @@
+package infrastructure
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/Ikhlashmulya/golang-clean-architecture-project-structure/config"
+	"github.com/sirupsen/logrus"
+)
+
+var (
+	ErrMailQueueFull   = errors.New("mail queue is full")
+	ErrMailQueueClosed = errors.New("mail queue is closed")
+)
+
+// MailQueue runs mail jobs in the background so a request neither waits for the mail server nor
+// fails with it. At most MAIL_QUEUE_SIZE jobs wait for one of the MAIL_QUEUE_WORKERS workers, a job
+// queued beyond that is refused instead of piling up goroutines while the mail server is slow.
+type MailQueue struct {
+	Logger *logrus.Logger
+
+	mu      sync.RWMutex
+	closed  bool
+	jobs    chan mailJob
+	workers sync.WaitGroup
+	ctx     context.Context
+	cancel  context.CancelFunc
+}
+
+// NewMailQueue starts the workers at once, so the cli commands can send mails without starting the
+// Lifecycle. Stopping the Lifecycle drains the queue, it must be created after the database that
+// the jobs still use meanwhile.
+func NewMailQueue(config *config.Config, lifecycle *Lifecycle, logger *logrus.Logger) *MailQueue {
+	queue := &MailQueue{
+		Logger: logger,
+		jobs:   make(chan mailJob, config.Mail.QueueSize),
+	}
+	queue.ctx, queue.cancel = context.WithCancel(context.Background())
+
+	for i := 0; i < config.Mail.QueueWorkers; i++ {
+		queue.workers.Add(1)
+		go queue.work()
+	}
+
+	lifecycle.Append(Hook{
+		Name:   "mail queue",
+		OnStop: queue.Close,
+	})
+
+	return queue
+}
+
+type mailJob struct {
+	values context.Context
+	run    func(ctx context.Context)
+}
+
+// Enqueue hands job to a worker, it never blocks. The job gets the values of ctx, such as the
+// request logger and trace span, but is not cancelled with it since the request ends first.
+func (q *MailQueue) Enqueue(ctx context.Context, job func(ctx context.Context)) error {
+	q.mu.RLock()
+	defer q.mu.RUnlock()
+
+	if q.closed {
+		return ErrMailQueueClosed
+	}
+
+	select {
+	case q.jobs <- mailJob{values: ctx, run: job}:
+		return nil
+	default:
+		return ErrMailQueueFull
+	}
+}
+
+func (q *MailQueue) work() {
+	defer q.workers.Done()
+
+	for job := range q.jobs {
+		// once Close stopped waiting the jobs still queued are dropped
+		if q.ctx.Err() != nil {
+			continue
+		}
+		q.run(job)
+	}
+}
+
+// run keeps a panicking job from taking the worker down with it.
+func (q *MailQueue) run(job mailJob) {
+	defer func() {
+		if r := recover(); r != nil {
+			q.Logger.WithField("panic", r).Error("mail job panicked")
+		}
+	}()
+
+	job.run(detachedContext{Context: q.ctx, values: job.values})
+}
+
+// Close refuses new jobs and waits for the queued ones until ctx is done. It then cancels the
+// running jobs, drops the queued ones and still waits for the workers to return, so no job is
+// left running against resources closed after the queue.
+func (q *MailQueue) Close(ctx context.Context) error {
+	q.mu.Lock()
+	if !q.closed {
+		q.closed = true
+		close(q.jobs)
+	}
+	q.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		q.workers.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		q.cancel()
+		return nil
+	case <-ctx.Done():
+	}
+
+	unsent := len(q.jobs)
+	q.cancel()
+	<-done
+	return fmt.Errorf("%d mails left unsent : %w", unsent, ctx.Err())
+}
+
+// detachedContext is cancelled with the queue but looks values up in the context of the request.
+type detachedContext struct {
+	context.Context
+	values context.Context
+}
+
+func (c detachedContext) Value(key any) any {
+	return c.values.Value(key)
+}
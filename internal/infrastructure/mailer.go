@@ -0,0 +1,194 @@
+package infrastructure
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"net/smtp"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Ikhlashmulya/golang-clean-architecture-project-structure/config"
+	"github.com/Ikhlashmulya/golang-clean-architecture-project-structure/internal/model"
+)
+
+type Mailer interface {
+	Send(ctx context.Context, message *model.MailMessage) error
+}
+
+// NewMailer selects the implementation with MAIL_DRIVER: smtp, file or memory.
+func NewMailer(config *config.Config) (Mailer, error) {
+	switch driver := config.Mail.Driver; driver {
+	case "smtp":
+		return &SMTPMailer{
+			Host:     config.Mail.SMTPHost,
+			Port:     config.Mail.SMTPPort,
+			Username: config.Mail.SMTPUsername,
+			Password: config.Mail.SMTPPassword,
+			From:     config.Mail.From,
+			Timeout:  config.Mail.SMTPTimeout,
+		}, nil
+	case "file":
+		return &FileMailer{Dir: config.Mail.FileDir, From: config.Mail.From}, nil
+	case "memory", "":
+		return NewInMemoryMailer(), nil
+	default:
+		return nil, fmt.Errorf("error creating mailer : unsupported driver %q", driver)
+	}
+}
+
+type SMTPMailer struct {
+	Host     string
+	Port     int
+	Username string
+	Password string
+	From     string
+	// Timeout bounds the whole exchange with the server, within the deadline of the context
+	Timeout time.Duration
+}
+
+// Send does what smtp.SendMail does, STARTTLS when offered and AUTH when a username is set, but
+// gives up when ctx is done or Timeout passed instead of waiting on a stuck server.
+func (m *SMTPMailer) Send(ctx context.Context, message *model.MailMessage) error {
+	if m.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, m.Timeout)
+		defer cancel()
+	}
+
+	addr := net.JoinHostPort(m.Host, strconv.Itoa(m.Port))
+	conn, err := new(net.Dialer).DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	if deadline, ok := ctx.Deadline(); ok {
+		if err := conn.SetDeadline(deadline); err != nil {
+			return err
+		}
+	}
+
+	// a cancelled ctx without deadline still interrupts a blocked read or write
+	stop := make(chan struct{})
+	defer close(stop)
+	go func() {
+		select {
+		case <-ctx.Done():
+			_ = conn.SetDeadline(time.Now())
+		case <-stop:
+		}
+	}()
+
+	client, err := smtp.NewClient(conn, m.Host)
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+
+	if ok, _ := client.Extension("STARTTLS"); ok {
+		if err := client.StartTLS(&tls.Config{ServerName: m.Host}); err != nil {
+			return err
+		}
+	}
+
+	if m.Username != "" {
+		if ok, _ := client.Extension("AUTH"); !ok {
+			return errors.New("smtp: server doesn't support AUTH")
+		}
+		if err := client.Auth(smtp.PlainAuth("", m.Username, m.Password, m.Host)); err != nil {
+			return err
+		}
+	}
+
+	if err := client.Mail(m.From); err != nil {
+		return err
+	}
+	if err := client.Rcpt(message.To); err != nil {
+		return err
+	}
+
+	writer, err := client.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := writer.Write(encodeMessage(m.From, message)); err != nil {
+		return err
+	}
+	if err := writer.Close(); err != nil {
+		return err
+	}
+
+	// the message is accepted once the data is closed, a failed QUIT loses nothing
+	_ = client.Quit()
+	return nil
+}
+
+// FileMailer writes every message as an .eml file, useful for local development.
+type FileMailer struct {
+	Dir  string
+	From string
+}
+
+func (m *FileMailer) Send(ctx context.Context, message *model.MailMessage) error {
+	if err := os.MkdirAll(m.Dir, 0o755); err != nil {
+		return err
+	}
+
+	name := fmt.Sprintf("%d.eml", time.Now().UnixNano())
+	return os.WriteFile(filepath.Join(m.Dir, name), encodeMessage(m.From, message), 0o600)
+}
+
+// InMemoryMailer keeps sent messages in memory so tests can inspect them.
+type InMemoryMailer struct {
+	mu       sync.Mutex
+	messages []model.MailMessage
+}
+
+func NewInMemoryMailer() *InMemoryMailer {
+	return &InMemoryMailer{}
+}
+
+func (m *InMemoryMailer) Send(ctx context.Context, message *model.MailMessage) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.messages = append(m.messages, *message)
+	return nil
+}
+
+func (m *InMemoryMailer) Messages() []model.MailMessage {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return append([]model.MailMessage(nil), m.messages...)
+}
+
+func (m *InMemoryMailer) Reset() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.messages = nil
+}
+
+// headerSanitizer drops line breaks so user supplied values cannot inject extra headers.
+var headerSanitizer = strings.NewReplacer("\r", "", "\n", "")
+
+func encodeMessage(from string, message *model.MailMessage) []byte {
+	buf := new(bytes.Buffer)
+	fmt.Fprintf(buf, "From: %s\r\n", headerSanitizer.Replace(from))
+	fmt.Fprintf(buf, "To: %s\r\n", headerSanitizer.Replace(message.To))
+	fmt.Fprintf(buf, "Subject: %s\r\n", headerSanitizer.Replace(message.Subject))
+	fmt.Fprintf(buf, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	buf.WriteString("MIME-Version: 1.0\r\n")
+	buf.WriteString("Content-Type: text/plain; charset=UTF-8\r\n\r\n")
+	buf.WriteString(message.Body)
+	return buf.Bytes()
+}
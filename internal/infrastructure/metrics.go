@@ -0,0 +1,81 @@
+package infrastructure
+
+import (
+	"net/http"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// Metrics owns its own registry instead of the prometheus default one, so every wired graph and
+// every test gets fresh collectors.
+type Metrics struct {
+	Registry *prometheus.Registry
+
+	HTTPRequests               *prometheus.CounterVec
+	HTTPRequestDuration        *prometheus.HistogramVec
+	Logins                     *prometheus.CounterVec
+	Registrations              prometheus.Counter
+	TokenVerificationsRejected *prometheus.CounterVec
+}
+
+func NewMetrics() *Metrics {
+	metrics := &Metrics{
+		Registry: prometheus.NewRegistry(),
+		HTTPRequests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "http_requests_total",
+			Help: "HTTP requests by method, route pattern and status code.",
+		}, []string{"method", "route", "status"}),
+		HTTPRequestDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "http_request_duration_seconds",
+			Help:    "HTTP request latency by method, route pattern and status code.",
+			Buckets: prometheus.DefBuckets,
+		}, []string{"method", "route", "status"}),
+		Logins: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "user_logins_total",
+			Help: "Logins by result, succeeded or failed on wrong credentials or second factor.",
+		}, []string{"result"}),
+		Registrations: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "user_registrations_total",
+			Help: "Users registered.",
+		}),
+		TokenVerificationsRejected: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "token_verifications_rejected_total",
+			Help: "Access tokens rejected by reason: invalid, revoked or user.",
+		}, []string{"reason"}),
+	}
+
+	metrics.Registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		metrics.HTTPRequests,
+		metrics.HTTPRequestDuration,
+		metrics.Logins,
+		metrics.Registrations,
+		metrics.TokenVerificationsRejected,
+	)
+
+	return metrics
+}
+
+func (m *Metrics) LoginSucceeded() {
+	m.Logins.WithLabelValues("succeeded").Inc()
+}
+
+func (m *Metrics) LoginFailed() {
+	m.Logins.WithLabelValues("failed").Inc()
+}
+
+func (m *Metrics) UserRegistered() {
+	m.Registrations.Inc()
+}
+
+func (m *Metrics) TokenRejected(reason string) {
+	m.TokenVerificationsRejected.WithLabelValues(reason).Inc()
+}
+
+// Handler serves the registry in the Prometheus text format.
+func (m *Metrics) Handler() http.Handler {
+	return promhttp.HandlerFor(m.Registry, promhttp.HandlerOpts{Registry: m.Registry})
+}
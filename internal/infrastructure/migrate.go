@@ -0,0 +1,87 @@
+package infrastructure
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/Ikhlashmulya/golang-clean-architecture-project-structure/config"
+	"github.com/Ikhlashmulya/golang-clean-architecture-project-structure/db/migrations"
+	"github.com/golang-migrate/migrate/v4"
+	"github.com/golang-migrate/migrate/v4/database"
+	"github.com/golang-migrate/migrate/v4/database/mysql"
+	pgx "github.com/golang-migrate/migrate/v4/database/pgx/v5"
+	"github.com/golang-migrate/migrate/v4/database/sqlite3"
+	"github.com/golang-migrate/migrate/v4/source/iofs"
+	"github.com/sirupsen/logrus"
+	gormmysql "gorm.io/driver/mysql"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// NewMigrate applies the embedded migrations of DB_DRIVER. It opens its own connection
+// because closing the returned migrate also closes the database it was given.
+func NewMigrate(config *config.Config, log *logrus.Logger) (*migrate.Migrate, error) {
+	driver := config.DB.Driver
+
+	source, err := iofs.New(migrations.FS, driver)
+	if err != nil {
+		return nil, fmt.Errorf("error loading migrations : %w", err)
+	}
+
+	dialector, err := newDialector(config)
+	if err != nil {
+		return nil, err
+	}
+	// migrate sends a migration file as one query, mysql only runs the several statements of a
+	// file with multiStatements, which the connection of the server leaves off
+	if mysqlDialector, ok := dialector.(*gormmysql.Dialector); ok {
+		mysqlDialector.DSN += "&multiStatements=true"
+	}
+
+	db, err := gorm.Open(dialector, &gorm.Config{Logger: logger.Discard})
+	if err != nil {
+		return nil, fmt.Errorf("error connecting database : %w", err)
+	}
+
+	connection, err := db.DB()
+	if err != nil {
+		return nil, err
+	}
+
+	var instance database.Driver
+	switch driver {
+	case "mysql":
+		instance, err = mysql.WithInstance(connection, &mysql.Config{})
+	case "postgres":
+		instance, err = pgx.WithInstance(connection, &pgx.Config{})
+	case "sqlite":
+		instance, err = sqlite3.WithInstance(connection, &sqlite3.Config{})
+	}
+	if err != nil {
+		connection.Close()
+		return nil, fmt.Errorf("error preparing migration : %w", err)
+	}
+
+	m, err := migrate.NewWithInstance("iofs", source, driver, instance)
+	if err != nil {
+		connection.Close()
+		return nil, fmt.Errorf("error preparing migration : %w", err)
+	}
+	m.Log = &migrateLogger{Logger: log}
+
+	return m, nil
+}
+
+// migrateLogger adapts logrus to migrate.Logger, every applied migration is logged at info level and
+// the verbose messages of migrate only at debug level.
+type migrateLogger struct {
+	Logger *logrus.Logger
+}
+
+func (l *migrateLogger) Printf(format string, v ...interface{}) {
+	l.Logger.Infof(strings.TrimSuffix(format, "\n"), v...)
+}
+
+func (l *migrateLogger) Verbose() bool {
+	return l.Logger.IsLevelEnabled(logrus.DebugLevel)
+}
@@ -0,0 +1,104 @@
+package infrastructure
+
+import (
+	"math"
+	"strings"
+	"unicode"
+)
+
+// commonPasswordWords are guessed first by any cracker, a password built on one of them is
+// barely stronger than the rest of it.
+var commonPasswordWords = []string{
+	"password", "passw0rd", "qwerty", "azerty", "asdf", "letmein", "welcome", "admin", "login",
+	"iloveyou", "monkey", "dragon", "master", "secret", "sunshine", "princess", "football",
+	"baseball", "shadow", "trustno1", "rahasia", "sayang",
+}
+
+// PasswordClasses counts the kinds of characters in password among lower case, upper case,
+// digits and symbols, anything else counts as a symbol.
+func PasswordClasses(password string) int {
+	var lower, upper, digit, symbol int
+	for _, r := range password {
+		switch {
+		case unicode.IsLower(r):
+			lower = 1
+		case unicode.IsUpper(r):
+			upper = 1
+		case unicode.IsDigit(r):
+			digit = 1
+		default:
+			symbol = 1
+		}
+	}
+
+	return lower + upper + digit + symbol
+}
+
+// PasswordEntropy estimates the bits needed to guess password in the spirit of zxcvbn. Every
+// character is worth the size of the alphabets it draws from, except that a repeat or the next
+// step of a sequence such as "abc" or "321" is worth one bit, and a common word only as much as
+// picking it from commonPasswordWords.
+func PasswordEntropy(password string) float64 {
+	runes := []rune(password)
+	lowered := make([]rune, len(runes))
+	for i, r := range runes {
+		lowered[i] = unicode.ToLower(r)
+	}
+
+	charBits := math.Log2(float64(passwordPoolSize(runes)))
+	wordBits := math.Log2(float64(len(commonPasswordWords)))
+
+	var bits float64
+	for i := 0; i < len(runes); {
+		if word := commonPasswordWordAt(lowered, i); word > 0 {
+			bits += wordBits
+			i += word
+			continue
+		}
+
+		if i > 0 && math.Abs(float64(lowered[i]-lowered[i-1])) <= 1 {
+			bits++
+		} else {
+			bits += charBits
+		}
+		i++
+	}
+
+	return bits
+}
+
+func passwordPoolSize(runes []rune) int {
+	var lower, upper, digit, symbol, other int
+	for _, r := range runes {
+		switch {
+		case r > unicode.MaxASCII:
+			other = 100
+		case unicode.IsLower(r):
+			lower = 26
+		case unicode.IsUpper(r):
+			upper = 26
+		case unicode.IsDigit(r):
+			digit = 10
+		default:
+			symbol = 33
+		}
+	}
+
+	if size := lower + upper + digit + symbol + other; size > 0 {
+		return size
+	}
+	return 1
+}
+
+// commonPasswordWordAt returns the length in runes of the longest common word starting at i.
+func commonPasswordWordAt(lowered []rune, i int) int {
+	rest := string(lowered[i:])
+	var longest int
+	for _, word := range commonPasswordWords {
+		if length := len(word); length > longest && strings.HasPrefix(rest, word) {
+			longest = length
+		}
+	}
+
+	return longest
+}
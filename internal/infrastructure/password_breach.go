@@ -0,0 +1,336 @@
+package infrastructure
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/Ikhlashmulya/golang-clean-architecture-project-structure/config"
+)
+
+// PasswordBreachChecker tells whether a password is known from a data breach, such passwords are
+// the first ones tried by credential stuffing whatever their strength.
+type PasswordBreachChecker interface {
+	IsBreached(ctx context.Context, password string) (bool, error)
+}
+
+// NewPasswordBreachChecker looks passwords up in PASSWORD_BREACH_FILE, either the single file of
+// full hashes or the directory of range files, no password is refused when it is not set.
+func NewPasswordBreachChecker(config *config.Config, lifecycle *Lifecycle) (PasswordBreachChecker, error) {
+	path := config.Auth.PasswordPolicy.BreachFile
+	if path == "" {
+		return NoopPasswordBreachChecker{}, nil
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, fmt.Errorf("error opening password breach file : %w", err)
+	}
+	if info.IsDir() {
+		checker, err := NewHIBPRangeChecker(path)
+		if err != nil {
+			return nil, fmt.Errorf("error opening password breach directory : %w", err)
+		}
+		return checker, nil
+	}
+
+	checker, err := NewHIBPFileChecker(path)
+	if err != nil {
+		return nil, fmt.Errorf("error opening password breach file : %w", err)
+	}
+
+	lifecycle.Append(Hook{
+		Name: "password breach file",
+		OnStop: func(ctx context.Context) error {
+			return checker.Close()
+		},
+	})
+
+	return checker, nil
+}
+
+type NoopPasswordBreachChecker struct{}
+
+func (NoopPasswordBreachChecker) IsBreached(ctx context.Context, password string) (bool, error) {
+	return false, nil
+}
+
+const (
+	// hibpLineMax is longer than any line, 40 hex digits, a colon, the count and a line break.
+	hibpLineMax = 64
+	// hibpPrefixLen is the number of hex digits naming a range file, its lines hold the rest.
+	hibpPrefixLen = 5
+	// hibpBlockSize is scanned line by line once the binary search narrowed the range to it.
+	hibpBlockSize = 4096
+)
+
+// HIBPFileChecker looks passwords up in the Have I Been Pwned corpus of SHA-1 hashes ordered by
+// hash, one "HASH:COUNT" line per password, as written by the PwnedPasswordsDownloader. A lookup
+// is a binary search with ReadAt, a few dozen small reads for a billion lines, so the file is
+// never loaded in memory. The file can be refreshed offline and swapped while the server runs,
+// it is reopened by the next lookup.
+type HIBPFileChecker struct {
+	Path string
+
+	mu   sync.Mutex
+	file *os.File
+	info os.FileInfo
+}
+
+func NewHIBPFileChecker(path string) (*HIBPFileChecker, error) {
+	checker := &HIBPFileChecker{Path: path}
+	if err := checker.reopen(); err != nil {
+		return nil, err
+	}
+
+	return checker, nil
+}
+
+func (c *HIBPFileChecker) IsBreached(ctx context.Context, password string) (bool, error) {
+	sum := sha1.Sum([]byte(password))
+	hash := strings.ToUpper(hex.EncodeToString(sum[:]))
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if err := c.reopen(); err != nil {
+		return false, err
+	}
+
+	count, err := searchHIBPFile(c.file, c.info.Size(), hash)
+	if err != nil {
+		return false, fmt.Errorf("error searching %s : %w", c.Path, err)
+	}
+
+	return count > 0, nil
+}
+
+func (c *HIBPFileChecker) Close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.file == nil {
+		return nil
+	}
+	err := c.file.Close()
+	c.file = nil
+	return err
+}
+
+// reopen opens Path when it was replaced or changed since it was last opened.
+func (c *HIBPFileChecker) reopen() error {
+	info, err := os.Stat(c.Path)
+	if err != nil {
+		return err
+	}
+	if c.file != nil && os.SameFile(info, c.info) && info.ModTime().Equal(c.info.ModTime()) && info.Size() == c.info.Size() {
+		return nil
+	}
+
+	file, err := os.Open(c.Path)
+	if err != nil {
+		return err
+	}
+	if info, err = file.Stat(); err == nil {
+		err = checkHIBPFile(file)
+	}
+	if err != nil {
+		file.Close()
+		return fmt.Errorf("error reading %s : %w", c.Path, err)
+	}
+
+	if c.file != nil {
+		c.file.Close()
+	}
+	c.file, c.info = file, info
+
+	return nil
+}
+
+// HIBPRangeChecker looks passwords up in the Have I Been Pwned corpus split in range files, as
+// written by the PwnedPasswordsDownloader without the single file option. Each file is named after
+// the first 5 hex digits of the hashes it lists, such as 5BAA6.txt, and holds "SUFFIX:COUNT" lines
+// for the other 35 digits, the format of the range API. A lookup reads the one file of its prefix,
+// a few dozen KB, so files refreshed offline are picked up by the next lookup.
+type HIBPRangeChecker struct {
+	Dir string
+}
+
+func NewHIBPRangeChecker(dir string) (*HIBPRangeChecker, error) {
+	checker := &HIBPRangeChecker{Dir: dir}
+
+	// the first range always exists in the corpus, refusing a directory of something else
+	if _, err := checker.count(strings.Repeat("0", sha1.Size*2)); err != nil {
+		return nil, err
+	}
+
+	return checker, nil
+}
+
+func (c *HIBPRangeChecker) IsBreached(ctx context.Context, password string) (bool, error) {
+	sum := sha1.Sum([]byte(password))
+
+	count, err := c.count(strings.ToUpper(hex.EncodeToString(sum[:])))
+	if err != nil {
+		return false, err
+	}
+
+	return count > 0, nil
+}
+
+// count returns the count of hash in the file of its range, 0 when it is not listed. A missing
+// file is an error rather than a miss, the download was not complete.
+func (c *HIBPRangeChecker) count(hash string) (int64, error) {
+	path := filepath.Join(c.Dir, hash[:hibpPrefixLen]+".txt")
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return 0, err
+	}
+
+	suffix := []byte(hash[hibpPrefixLen:])
+	for _, line := range bytes.Split(content, []byte("\n")) {
+		if len(bytes.TrimSpace(line)) == 0 {
+			continue
+		}
+		entry, err := parseHIBPLine(line, sha1.Size*2-hibpPrefixLen)
+		if err != nil {
+			return 0, fmt.Errorf("error reading %s : %w", path, err)
+		}
+		if bytes.Equal(entry.hash, suffix) {
+			return entry.count, nil
+		}
+	}
+
+	return 0, nil
+}
+
+// checkHIBPFile refuses a file whose first line is no SHA-1 line, such as the NTLM corpus.
+func checkHIBPFile(file io.ReaderAt) error {
+	buf := make([]byte, hibpLineMax)
+	n, err := file.ReadAt(buf, 0)
+	if err != nil && !errors.Is(err, io.EOF) {
+		return err
+	}
+
+	line, _, _ := bytes.Cut(buf[:n], []byte("\n"))
+	_, err = parseHIBPLine(line, sha1.Size*2)
+	return err
+}
+
+// searchHIBPFile returns the count of hash in the file, 0 when it is not listed.
+func searchHIBPFile(file io.ReaderAt, size int64, hash string) (int64, error) {
+	target := []byte(hash)
+
+	// lo is always the start of a line and no line before it holds hash
+	lo, hi := int64(0), size
+	for hi-lo > hibpBlockSize {
+		mid := lo + (hi-lo)/2
+		line, start, err := hibpLineAfter(file, mid)
+		if err != nil {
+			return 0, err
+		}
+		if line == nil {
+			hi = mid
+			continue
+		}
+
+		entry, err := parseHIBPLine(line, sha1.Size*2)
+		if err != nil {
+			return 0, err
+		}
+		switch bytes.Compare(entry.hash, target) {
+		case 0:
+			return entry.count, nil
+		case -1:
+			lo = start
+		default:
+			// no line starts between mid and this one, so the line of hash starts before mid
+			hi = mid
+		}
+	}
+
+	buf := make([]byte, hi-lo+hibpLineMax)
+	n, err := file.ReadAt(buf, lo)
+	if err != nil && !errors.Is(err, io.EOF) {
+		return 0, err
+	}
+
+	// lines starting after hi may be cut short, they are not needed
+	for offset := 0; offset < n && lo+int64(offset) <= hi; {
+		line := buf[offset:n]
+		if end := bytes.IndexByte(line, '\n'); end >= 0 {
+			line = line[:end]
+		}
+		offset += len(line) + 1
+
+		if len(bytes.TrimSpace(line)) == 0 {
+			continue
+		}
+		entry, err := parseHIBPLine(line, sha1.Size*2)
+		if err != nil {
+			return 0, err
+		}
+		if cmp := bytes.Compare(entry.hash, target); cmp == 0 {
+			return entry.count, nil
+		} else if cmp > 0 {
+			break
+		}
+	}
+
+	return 0, nil
+}
+
+// hibpLineAfter returns the first line starting after offset and where it starts, a nil line when
+// none does.
+func hibpLineAfter(file io.ReaderAt, offset int64) ([]byte, int64, error) {
+	buf := make([]byte, 2*hibpLineMax)
+	n, err := file.ReadAt(buf, offset)
+	if err != nil && !errors.Is(err, io.EOF) {
+		return nil, 0, err
+	}
+
+	newline := bytes.IndexByte(buf[:n], '\n')
+	if newline < 0 {
+		return nil, 0, nil
+	}
+
+	line := buf[newline+1 : n]
+	if end := bytes.IndexByte(line, '\n'); end >= 0 {
+		line = line[:end]
+	}
+	if len(bytes.TrimSpace(line)) == 0 {
+		return nil, 0, nil
+	}
+
+	return line, offset + int64(newline) + 1, nil
+}
+
+type hibpEntry struct {
+	hash  []byte
+	count int64
+}
+
+// parseHIBPLine parses a line holding the last digits hex digits of an SHA-1 hash and its count.
+func parseHIBPLine(line []byte, digits int) (hibpEntry, error) {
+	line = bytes.TrimRight(line, "\r")
+	hash, count, found := bytes.Cut(line, []byte(":"))
+	if !found || len(hash) != digits {
+		return hibpEntry{}, fmt.Errorf("malformed line %q, want %d digits of an SHA-1 hash and a count", line, digits)
+	}
+
+	parsedCount, err := strconv.ParseInt(string(count), 10, 64)
+	if err != nil {
+		return hibpEntry{}, fmt.Errorf("malformed line %q : %w", line, err)
+	}
+
+	return hibpEntry{hash: bytes.ToUpper(hash), count: parsedCount}, nil
+}
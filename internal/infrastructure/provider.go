@@ -0,0 +1,29 @@
+package infrastructure
+
+import "github.com/google/wire"
+
+// ProviderSet provides the drivers shared by the server and the cli commands, the logger is
+// passed to the injectors since it is needed before anything is wired.
+var ProviderSet = wire.NewSet(
+	NewLifecycle,
+	NewHealth,
+	NewMetrics,
+	NewTracerProvider,
+	NewTracer,
+	NewGorm,
+	NewFiber,
+	NewTranslator,
+	NewValidator,
+	NewTokenSigner,
+	NewPasswordBreachChecker,
+	NewMailQueue,
+	NewMigrate,
+)
+
+var MailerSet = wire.NewSet(NewMailer)
+
+// InMemoryMailerSet replaces MailerSet where the sent messages have to be inspected.
+var InMemoryMailerSet = wire.NewSet(
+	NewInMemoryMailer,
+	wire.Bind(new(Mailer), new(*InMemoryMailer)),
+)
@@ -0,0 +1,160 @@
+package infrastructure
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/Ikhlashmulya/golang-clean-architecture-project-structure/config"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.21.0"
+	"go.opentelemetry.io/otel/trace"
+	"gorm.io/gorm"
+)
+
+// TracerName is the instrumentation scope of every span created by the application.
+const TracerName = "github.com/Ikhlashmulya/golang-clean-architecture-project-structure"
+
+// NewTracerProvider selects the span exporter with TRACING_EXPORTER, "none" disables tracing. The
+// provider is flushed when the lifecycle stops, and it is installed globally together with the W3C
+// trace context propagator.
+func NewTracerProvider(config *config.Config, lifecycle *Lifecycle) (trace.TracerProvider, error) {
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+
+	exporter, closer, err := newSpanExporter(config)
+	if err != nil {
+		return nil, fmt.Errorf("error creating span exporter : %w", err)
+	}
+	if exporter == nil {
+		provider := trace.NewNoopTracerProvider()
+		otel.SetTracerProvider(provider)
+		return provider, nil
+	}
+
+	res, err := resource.Merge(resource.Default(), resource.NewWithAttributes(
+		semconv.SchemaURL,
+		semconv.ServiceName(config.App.Name),
+	))
+	if err != nil {
+		return nil, fmt.Errorf("error creating trace resource : %w", err)
+	}
+
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(res),
+	)
+	otel.SetTracerProvider(provider)
+
+	lifecycle.Append(Hook{
+		Name: "tracer provider",
+		OnStop: func(ctx context.Context) error {
+			err := provider.Shutdown(ctx)
+			if closer != nil {
+				err = errors.Join(err, closer.Close())
+			}
+			return err
+		},
+	})
+
+	return provider, nil
+}
+
+// newSpanExporter returns a nil exporter when tracing is disabled, and the file to close after the
+// exporter flushed for the file exporter.
+func newSpanExporter(config *config.Config) (sdktrace.SpanExporter, io.Closer, error) {
+	switch exporter := config.Tracing.Exporter; exporter {
+	case "otlp":
+		var options []otlptracehttp.Option
+		if config.Tracing.OTLPEndpoint != "" {
+			options = append(options, otlptracehttp.WithEndpoint(config.Tracing.OTLPEndpoint))
+		}
+		if config.Tracing.OTLPInsecure {
+			options = append(options, otlptracehttp.WithInsecure())
+		}
+		spanExporter, err := otlptracehttp.New(context.Background(), options...)
+		return spanExporter, nil, err
+	case "stdout":
+		spanExporter, err := stdouttrace.New(stdouttrace.WithWriter(os.Stdout))
+		return spanExporter, nil, err
+	case "file":
+		if err := os.MkdirAll(filepath.Dir(config.Tracing.File), 0o755); err != nil {
+			return nil, nil, err
+		}
+		file, err := os.OpenFile(config.Tracing.File, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o600)
+		if err != nil {
+			return nil, nil, err
+		}
+		spanExporter, err := stdouttrace.New(stdouttrace.WithWriter(file))
+		return spanExporter, file, err
+	case "none", "":
+		return nil, nil, nil
+	default:
+		return nil, nil, fmt.Errorf("unsupported exporter %q", exporter)
+	}
+}
+
+func NewTracer(provider trace.TracerProvider) trace.Tracer {
+	return provider.Tracer(TracerName)
+}
+
+const gormSpanKey = "otel:span"
+
+// registerGormTracing wraps every statement in a client span named after the operation and table.
+// Only the statement with placeholders is recorded, never the bound values.
+func registerGormTracing(db *gorm.DB, tracer trace.Tracer, driver string) error {
+	before := func(operation string) func(*gorm.DB) {
+		return func(tx *gorm.DB) {
+			name := operation
+			if tx.Statement.Table != "" {
+				name += " " + tx.Statement.Table
+			}
+			_, span := tracer.Start(tx.Statement.Context, name,
+				trace.WithSpanKind(trace.SpanKindClient),
+				trace.WithAttributes(semconv.DBSystemKey.String(driver), semconv.DBOperation(operation)))
+			tx.InstanceSet(gormSpanKey, span)
+		}
+	}
+
+	after := func(tx *gorm.DB) {
+		value, ok := tx.InstanceGet(gormSpanKey)
+		if !ok {
+			return
+		}
+		span := value.(trace.Span)
+		defer span.End()
+
+		span.SetAttributes(
+			semconv.DBSQLTable(tx.Statement.Table),
+			semconv.DBStatement(tx.Statement.SQL.String()),
+		)
+		if tx.Error != nil && !errors.Is(tx.Error, gorm.ErrRecordNotFound) {
+			span.RecordError(tx.Error)
+			span.SetStatus(codes.Error, tx.Error.Error())
+		}
+	}
+
+	callback := db.Callback()
+	return errors.Join(
+		callback.Create().Before("gorm:create").Register("otel:before_create", before("INSERT")),
+		callback.Create().After("gorm:create").Register("otel:after_create", after),
+		callback.Query().Before("gorm:query").Register("otel:before_query", before("SELECT")),
+		callback.Query().After("gorm:query").Register("otel:after_query", after),
+		callback.Update().Before("gorm:update").Register("otel:before_update", before("UPDATE")),
+		callback.Update().After("gorm:update").Register("otel:after_update", after),
+		callback.Delete().Before("gorm:delete").Register("otel:before_delete", before("DELETE")),
+		callback.Delete().After("gorm:delete").Register("otel:after_delete", after),
+		callback.Row().Before("gorm:row").Register("otel:before_row", before("ROW")),
+		callback.Row().After("gorm:row").Register("otel:after_row", after),
+		callback.Raw().Before("gorm:raw").Register("otel:before_raw", before("RAW")),
+		callback.Raw().After("gorm:raw").Register("otel:after_raw", after),
+	)
+}
@@ -1,10 +1,131 @@
 package infrastructure
 
 import (
+	"reflect"
+	"regexp"
+	"strings"
+
+	"github.com/Ikhlashmulya/golang-clean-architecture-project-structure/config"
+	"github.com/Ikhlashmulya/golang-clean-architecture-project-structure/internal/exception"
+	"github.com/go-playground/locales/en"
+	"github.com/go-playground/locales/id"
+	ut "github.com/go-playground/universal-translator"
 	"github.com/go-playground/validator/v10"
-	"github.com/spf13/viper"
+	enTranslations "github.com/go-playground/validator/v10/translations/en"
+	idTranslations "github.com/go-playground/validator/v10/translations/id"
+	"golang.org/x/text/unicode/norm"
 )
 
-func NewValidator(config *viper.Viper) *validator.Validate {
-	return validator.New()
-}
\ No newline at end of file
+// usernamePattern allows 3 to 32 lower case letters, digits, dots, dashes and underscores that
+// start and end with a letter or digit, so a username is safe in a path and a mail greeting.
+var usernamePattern = regexp.MustCompile(`^[a-z0-9][a-z0-9._-]{1,30}[a-z0-9]$`)
+
+// reservedUsernames could be mistaken for the service itself or clash with a route.
+var reservedUsernames = map[string]bool{
+	"admin": true, "administrator": true, "root": true, "system": true, "support": true,
+	"api": true, "me": true, "_current": true, "_restore": true, "null": true, "undefined": true,
+}
+
+// ruleTranslations are the messages of the rules registered by NewValidator.
+var ruleTranslations = map[string]map[string]string{
+	"en": {
+		"username": "{0} must be 3 to 32 lower case letters, digits, dots, dashes or underscores and not a reserved name",
+		"password": "{0} is too weak, use a longer mix of letters, digits and symbols that does not contain the username",
+	},
+	"id": {
+		"username": "{0} harus terdiri dari 3 sampai 32 huruf kecil, angka, titik, tanda hubung atau garis bawah dan bukan nama yang dicadangkan",
+		"password": "{0} terlalu lemah, gunakan gabungan huruf, angka dan simbol yang lebih panjang dan tidak mengandung username",
+	},
+}
+
+// NewTranslator returns the translators for exception.Languages, English is the fallback for a
+// language without translator. The details of the domain errors are registered on it, the messages
+// of the validation rules are registered by NewValidator.
+func NewTranslator() (*ut.UniversalTranslator, error) {
+	translator := ut.New(en.New(), en.New(), id.New())
+	if err := exception.RegisterTranslations(translator); err != nil {
+		return nil, err
+	}
+
+	return translator, nil
+}
+
+// CanonicalUsername maps the ways a username can be typed to one form, lower case and NFKC
+// normalised, so "JohnDoe" and the full width "ｊｏｈｎｄｏｅ" are the same username.
+func CanonicalUsername(username string) string {
+	return strings.ToLower(norm.NFKC.String(strings.TrimSpace(username)))
+}
+
+// NewValidator names the fields of a violation by their json or query tag, the name the client sent
+// them by, and registers the messages of the built-in rules for every language of translator.
+//
+// It adds the username rule, for a canonical username, and the password rule enforcing the
+// password policy of config. The password rule takes the struct field holding the username as
+// parameter, such as password=Username, and then also refuses a password containing it.
+func NewValidator(config *config.Config, translator *ut.UniversalTranslator) (*validator.Validate, error) {
+	validate := validator.New()
+	validate.RegisterTagNameFunc(func(field reflect.StructField) string {
+		for _, key := range []string{"json", "query"} {
+			name, _, _ := strings.Cut(field.Tag.Get(key), ",")
+			if name != "" && name != "-" {
+				return name
+			}
+		}
+		return ""
+	})
+
+	if err := validate.RegisterValidation("username", func(fl validator.FieldLevel) bool {
+		username := fl.Field().String()
+		return usernamePattern.MatchString(username) && !reservedUsernames[username]
+	}); err != nil {
+		return nil, err
+	}
+
+	if err := validate.RegisterValidation("password", func(fl validator.FieldLevel) bool {
+		password := fl.Field().String()
+		policy := config.Current().Auth.PasswordPolicy
+		if PasswordClasses(password) < policy.MinClasses || PasswordEntropy(password) < float64(policy.MinEntropy) {
+			return false
+		}
+
+		if fl.Param() == "" {
+			return true
+		}
+		field, _, _, ok := fl.GetStructFieldOKAdvanced2(fl.Parent(), fl.Param())
+		if !ok {
+			return true
+		}
+		username := CanonicalUsername(field.String())
+		return username == "" || !strings.Contains(CanonicalUsername(password), username)
+	}); err != nil {
+		return nil, err
+	}
+
+	registers := map[string]func(*validator.Validate, ut.Translator) error{
+		"en": enTranslations.RegisterDefaultTranslations,
+		"id": idTranslations.RegisterDefaultTranslations,
+	}
+	for language, register := range registers {
+		trans, _ := translator.GetTranslator(language)
+		if err := register(validate, trans); err != nil {
+			return nil, err
+		}
+
+		for tag, text := range ruleTranslations[language] {
+			if err := registerRuleTranslation(validate, trans, tag, text); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	return validate, nil
+}
+
+func registerRuleTranslation(validate *validator.Validate, trans ut.Translator, tag string, text string) error {
+	return validate.RegisterTranslation(tag, trans, func(trans ut.Translator) error {
+		return trans.Add(tag, text, false)
+	}, func(trans ut.Translator, fieldError validator.FieldError) string {
+		message, _ := trans.T(tag, fieldError.Field())
+		return message
+	})
+}
@@ -1,6 +1,12 @@
 package model
 
 type Auth struct {
-	ID       uint
-	Username string
+	ID        uint
+	Username  string
+	TokenID   string
+	SessionID string
+	ExpiresAt int64
+	// Roles come from the token, Permissions are resolved from the roles on every request.
+	Roles       []string
+	Permissions []string
 }
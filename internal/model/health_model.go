@@ -0,0 +1,13 @@
+package model
+
+type HealthResponse struct {
+	Status string                         `json:"status"`
+	Checks map[string]HealthCheckResponse `json:"checks,omitempty"`
+}
+
+// HealthCheckResponse keeps the failure reason generic, the underlying error is only logged.
+type HealthCheckResponse struct {
+	Status    string  `json:"status"`
+	LatencyMs float64 `json:"latency_ms"`
+	Error     string  `json:"error,omitempty"`
+}
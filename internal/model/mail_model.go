@@ -0,0 +1,7 @@
+package model
+
+type MailMessage struct {
+	To      string
+	Subject string
+	Body    string
+}
@@ -6,11 +6,18 @@ import (
 )
 
 func ToUserResponse(user *domain.User) *model.UserResponse {
-	return &model.UserResponse{
-		ID: user.ID,
-		Name: user.Name,
-		Username: user.Username,
-		CreatedAt: user.CreatedAt,
-		UpdatedAt: user.UpdatedAt,
+	response := &model.UserResponse{
+		ID:              user.ID,
+		Name:            user.Name,
+		Username:        user.Username,
+		EmailVerifiedAt: user.EmailVerifiedAt,
+		CreatedAt:       user.CreatedAt,
+		UpdatedAt:       user.UpdatedAt,
 	}
-}
\ No newline at end of file
+
+	if user.Email != nil {
+		response.Email = *user.Email
+	}
+
+	return response
+}
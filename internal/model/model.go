@@ -1,5 +1,33 @@
 package model
 
 type WebResponse[T any] struct {
-	Data T
+	Data   T
+	Paging *PageMetadata `json:",omitempty"`
+}
+
+// PageMetadata describes an offset page with Page and the totals, or a cursor page with NextCursor.
+type PageMetadata struct {
+	Page       int    `json:"page,omitempty"`
+	Size       int    `json:"size"`
+	TotalItem  int64  `json:"total_item,omitempty"`
+	TotalPage  int64  `json:"total_page,omitempty"`
+	NextCursor string `json:"next_cursor,omitempty"`
+}
+
+// ProblemResponse is an RFC 7807 problem details document, served as application/problem+json.
+type ProblemResponse struct {
+	Type     string             `json:"type"`
+	Title    string             `json:"title"`
+	Status   int                `json:"status"`
+	Detail   string             `json:"detail,omitempty"`
+	Instance string             `json:"instance,omitempty"`
+	Code     string             `json:"code"`
+	Errors   []ProblemViolation `json:"errors,omitempty"`
+}
+
+type ProblemViolation struct {
+	Field   string `json:"field"`
+	Rule    string `json:"rule"`
+	Param   string `json:"param,omitempty"`
+	Message string `json:"message,omitempty"`
 }
@@ -0,0 +1,31 @@
+package model
+
+type EnrollTOTPRequest struct {
+	ID uint `validate:"required"`
+}
+
+type ConfirmTOTPRequest struct {
+	ID   uint   `validate:"required"`
+	Code string `json:"code" validate:"required,len=6,numeric"`
+}
+
+type DisableTOTPRequest struct {
+	ID       uint   `validate:"required"`
+	Password string `json:"password" validate:"required,max=100"`
+}
+
+type LoginMFARequest struct {
+	MFAToken string `json:"mfa_token" validate:"required"`
+	// Code is either a TOTP code or one of the recovery codes
+	Code      string `json:"code" validate:"required,max=32"`
+	IPAddress string `json:"-"`
+}
+
+type TOTPEnrollmentResponse struct {
+	Secret string `json:"secret"`
+	URI    string `json:"otpauth_uri"`
+}
+
+type RecoveryCodesResponse struct {
+	RecoveryCodes []string `json:"recovery_codes"`
+}
@@ -2,19 +2,66 @@ package model
 
 type RegisterUserRequest struct {
 	Name     string `json:"name" validate:"required,max=100"`
-	Username string `json:"username" validate:"required,max=100"`
-	Password string `json:"password" validate:"required,max=100,min=8"`
+	Username string `json:"username" validate:"required,username"`
+	Email    string `json:"email" validate:"required,email,max=255"`
+	Password string `json:"password" validate:"required,max=100,min=8,password=Username"`
+	// EmailVerified is set by operator commands, the email is trusted and no verification mail is sent
+	EmailVerified bool `json:"-"`
 }
 
 type LoginUserRequest struct {
+	Username  string `json:"username" validate:"required,max=100"`
+	Password  string `json:"password" validate:"required,max=100"`
+	IPAddress string `json:"-"`
+}
+
+type UnlockUserRequest struct {
 	Username string `json:"username" validate:"required,max=100"`
+}
+
+// SearchUserRequest uses offset pagination when Page is set and cursor pagination otherwise.
+// Sort is a column name, prefixed with "-" for descending order.
+type SearchUserRequest struct {
+	Name          string `query:"name" validate:"max=100"`
+	Username      string `query:"username" validate:"max=100"`
+	CreatedAfter  int64  `query:"created_after" validate:"min=0"`
+	CreatedBefore int64  `query:"created_before" validate:"min=0"`
+	Sort          string `query:"sort" validate:"omitempty,oneof=id -id name -name username -username created_at -created_at"`
+	Page          int    `query:"page" validate:"min=0,max=100000,excluded_with=Cursor"`
+	Size          int    `query:"size" validate:"min=0,max=100"`
+	Cursor        string `query:"cursor" validate:"max=512"`
+}
+
+type DeleteUserRequest struct {
+	ID       uint   `json:"-" validate:"required"`
 	Password string `json:"password" validate:"required,max=100"`
 }
 
+type RestoreUserRequest struct {
+	Username  string `json:"username" validate:"required,max=100"`
+	Password  string `json:"password" validate:"required,max=100"`
+	IPAddress string `json:"-"`
+}
+
+type LockUserRequest struct {
+	Username string `json:"username" validate:"required,max=100"`
+}
+
+type SetPasswordRequest struct {
+	Username string `json:"username" validate:"required,max=100"`
+	Password string `json:"password" validate:"required,max=100,min=8,password=Username"`
+}
+
+type UserRoleRequest struct {
+	Username string `json:"username" validate:"required,max=100"`
+	Role     string `json:"role" validate:"required,max=100"`
+}
+
 type UpdateUserRequest struct {
 	Name     string `json:"name,omitempty" validate:"max=100"`
 	Username string `validate:"max=100"`
-	Password string `json:"password,omitempty" validate:"max=100"`
+	Email    string `json:"email,omitempty" validate:"omitempty,email,max=255"`
+	Password string `json:"password,omitempty" validate:"omitempty,max=100,min=8,password=Username"`
 }
 
 type GetUserRequest struct {
@@ -25,15 +72,45 @@ type VerifyUserRequest struct {
 	AccessToken string `json:"access_token,omitempty"`
 }
 
+type RefreshTokenRequest struct {
+	RefreshToken string `json:"refresh_token" validate:"required"`
+}
+
+type LogoutUserRequest struct {
+	ID        uint   `validate:"required"`
+	TokenID   string `validate:"required"`
+	SessionID string
+	ExpiresAt int64
+}
+
+type ForgotPasswordRequest struct {
+	Username string `json:"username" validate:"required,max=100"`
+}
+
+type ResetPasswordRequest struct {
+	Token    string `json:"token" validate:"required"`
+	Password string `json:"password" validate:"required,max=100,min=8,password"`
+}
+
+type VerifyEmailRequest struct {
+	Token string `json:"token" validate:"required"`
+}
+
 type UserResponse struct {
-	ID        uint    `json:"id,omitempty"`
-	Name      string `json:"name,omitempty"`
-	Username  string `json:"username,omitempty"`
-	CreatedAt int64  `json:"created_at,omitempty"`
-	UpdatedAt int64  `json:"updated_at,omitempty"`
+	ID              uint   `json:"id,omitempty"`
+	Name            string `json:"name,omitempty"`
+	Username        string `json:"username,omitempty"`
+	Email           string `json:"email,omitempty"`
+	EmailVerifiedAt int64  `json:"email_verified_at,omitempty"`
+	CreatedAt       int64  `json:"created_at,omitempty"`
+	UpdatedAt       int64  `json:"updated_at,omitempty"`
 }
 
 type TokenResponse struct {
-	AccessToken string `json:"access_token,omitempty"`
-	TokenType   string `json:"token_type,omitempty"`
+	AccessToken  string `json:"access_token,omitempty"`
+	TokenType    string `json:"token_type,omitempty"`
+	ExpiresIn    int64  `json:"expires_in,omitempty"`
+	RefreshToken string `json:"refresh_token,omitempty"`
+	MFARequired  bool   `json:"mfa_required,omitempty"`
+	MFAToken     string `json:"mfa_token,omitempty"`
 }
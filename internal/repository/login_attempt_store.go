@@ -0,0 +1,129 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"sync"
+
+	"github.com/Ikhlashmulya/golang-clean-architecture-project-structure/config"
+	"github.com/Ikhlashmulya/golang-clean-architecture-project-structure/internal/domain"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// LoginAttemptStore counts failed logins per throttle key. Failures older than resetBefore are forgotten,
+// so a key starts over once it stayed quiet for the whole window.
+type LoginAttemptStore interface {
+	// Find returns an attempt without failures when nothing failed for the key since resetBefore.
+	Find(ctx context.Context, key string, resetBefore int64) (*domain.LoginAttempt, error)
+	AddFailure(ctx context.Context, key string, failedAt int64, resetBefore int64) (*domain.LoginAttempt, error)
+	Reset(ctx context.Context, key string) error
+}
+
+// NewLoginAttemptStore selects the implementation with LOGIN_ATTEMPT_STORE: database or memory.
+func NewLoginAttemptStore(config *config.Config, db *gorm.DB) LoginAttemptStore {
+	if config.Auth.LoginThrottle.Store == "memory" {
+		return NewInMemoryLoginAttemptStore()
+	}
+	return NewGormLoginAttemptStore(db)
+}
+
+type GormLoginAttemptStore struct {
+	DB *gorm.DB
+}
+
+func NewGormLoginAttemptStore(db *gorm.DB) LoginAttemptStore {
+	return &GormLoginAttemptStore{DB: db}
+}
+
+func (s *GormLoginAttemptStore) Find(ctx context.Context, key string, resetBefore int64) (*domain.LoginAttempt, error) {
+	attempt := new(domain.LoginAttempt)
+	err := s.DB.WithContext(ctx).Where("attempt_key = ? AND last_failed_at >= ?", key, resetBefore).Take(attempt).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return &domain.LoginAttempt{Key: key}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return attempt, nil
+}
+
+func (s *GormLoginAttemptStore) AddFailure(ctx context.Context, key string, failedAt int64, resetBefore int64) (*domain.LoginAttempt, error) {
+	attempt := new(domain.LoginAttempt)
+	err := s.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("last_failed_at < ?", resetBefore).Delete(&domain.LoginAttempt{}).Error; err != nil {
+			return err
+		}
+
+		// the counter is incremented by the database so concurrent failures are never lost
+		err := tx.Clauses(clause.OnConflict{
+			Columns: []clause.Column{{Name: "attempt_key"}},
+			DoUpdates: clause.Assignments(map[string]any{
+				"failures":       gorm.Expr("login_attempts.failures + 1"),
+				"last_failed_at": failedAt,
+			}),
+		}).Create(&domain.LoginAttempt{Key: key, Failures: 1, LastFailedAt: failedAt}).Error
+		if err != nil {
+			return err
+		}
+
+		return tx.Where("attempt_key = ?", key).Take(attempt).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return attempt, nil
+}
+
+func (s *GormLoginAttemptStore) Reset(ctx context.Context, key string) error {
+	return s.DB.WithContext(ctx).Where("attempt_key = ?", key).Delete(&domain.LoginAttempt{}).Error
+}
+
+// InMemoryLoginAttemptStore is only suitable for single node deployments,
+// counters are lost on restart and are not shared between instances.
+type InMemoryLoginAttemptStore struct {
+	mu       sync.Mutex
+	attempts map[string]domain.LoginAttempt
+}
+
+func NewInMemoryLoginAttemptStore() LoginAttemptStore {
+	return &InMemoryLoginAttemptStore{attempts: make(map[string]domain.LoginAttempt)}
+}
+
+func (s *InMemoryLoginAttemptStore) Find(ctx context.Context, key string, resetBefore int64) (*domain.LoginAttempt, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	attempt, ok := s.attempts[key]
+	if !ok || attempt.LastFailedAt < resetBefore {
+		return &domain.LoginAttempt{Key: key}, nil
+	}
+	return &attempt, nil
+}
+
+func (s *InMemoryLoginAttemptStore) AddFailure(ctx context.Context, key string, failedAt int64, resetBefore int64) (*domain.LoginAttempt, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for attemptKey, attempt := range s.attempts {
+		if attempt.LastFailedAt < resetBefore {
+			delete(s.attempts, attemptKey)
+		}
+	}
+
+	attempt := s.attempts[key]
+	attempt.Key = key
+	attempt.Failures++
+	attempt.LastFailedAt = failedAt
+	s.attempts[key] = attempt
+
+	return &attempt, nil
+}
+
+func (s *InMemoryLoginAttemptStore) Reset(ctx context.Context, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.attempts, key)
+	return nil
+}
@@ -0,0 +1,55 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/Ikhlashmulya/golang-clean-architecture-project-structure/internal/domain"
+	"gorm.io/gorm"
+)
+
+type PasswordResetTokenRepository interface {
+	Create(ctx context.Context, passwordResetToken *domain.PasswordResetToken) error
+	FindByTokenHash(ctx context.Context, tokenHash string) (*domain.PasswordResetToken, error)
+	// MarkUsed consumes an unused token and reports whether this call was the one that consumed it.
+	MarkUsed(ctx context.Context, id uint, usedAt int64) (bool, error)
+	// MarkUsedByUserID invalidates every outstanding token of the user.
+	MarkUsedByUserID(ctx context.Context, userID uint, usedAt int64) error
+}
+
+type PasswordResetTokenRepositoryImpl struct {
+	DB *gorm.DB
+}
+
+func NewPasswordResetTokenRepository(db *gorm.DB) PasswordResetTokenRepository {
+	return &PasswordResetTokenRepositoryImpl{DB: db}
+}
+
+func (r *PasswordResetTokenRepositoryImpl) Create(ctx context.Context, passwordResetToken *domain.PasswordResetToken) error {
+	return r.DB.WithContext(ctx).Create(passwordResetToken).Error
+}
+
+func (r *PasswordResetTokenRepositoryImpl) FindByTokenHash(ctx context.Context, tokenHash string) (*domain.PasswordResetToken, error) {
+	passwordResetToken := new(domain.PasswordResetToken)
+	if err := r.DB.WithContext(ctx).Where("token_hash = ?", tokenHash).Take(passwordResetToken).Error; err != nil {
+		return nil, err
+	}
+	return passwordResetToken, nil
+}
+
+func (r *PasswordResetTokenRepositoryImpl) MarkUsed(ctx context.Context, id uint, usedAt int64) (bool, error) {
+	result := r.DB.WithContext(ctx).
+		Model(&domain.PasswordResetToken{}).
+		Where("id = ? AND used_at = 0", id).
+		Update("used_at", usedAt)
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected == 1, nil
+}
+
+func (r *PasswordResetTokenRepositoryImpl) MarkUsedByUserID(ctx context.Context, userID uint, usedAt int64) error {
+	return r.DB.WithContext(ctx).
+		Model(&domain.PasswordResetToken{}).
+		Where("user_id = ? AND used_at = 0", userID).
+		Update("used_at", usedAt).Error
+}
@@ -0,0 +1,25 @@
+package repository
+
+import "github.com/google/wire"
+
+// ProviderSet provides every repository backed by gorm and the stores selected by configuration.
+var ProviderSet = wire.NewSet(RepositorySet, StoreSet)
+
+var RepositorySet = wire.NewSet(
+	NewUserRepository,
+	NewRefreshTokenRepository,
+	NewPasswordResetTokenRepository,
+	NewTwoFactorRepository,
+	NewRoleRepository,
+)
+
+var StoreSet = wire.NewSet(
+	NewTokenRevocationStore,
+	NewLoginAttemptStore,
+)
+
+// InMemoryStoreSet replaces StoreSet to keep revoked tokens and login attempts in memory whatever the configuration.
+var InMemoryStoreSet = wire.NewSet(
+	NewInMemoryTokenRevocationStore,
+	NewInMemoryLoginAttemptStore,
+)
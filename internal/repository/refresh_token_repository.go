@@ -0,0 +1,62 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/Ikhlashmulya/golang-clean-architecture-project-structure/internal/domain"
+	"gorm.io/gorm"
+)
+
+type RefreshTokenRepository interface {
+	Create(ctx context.Context, refreshToken *domain.RefreshToken) error
+	FindByTokenHash(ctx context.Context, tokenHash string) (*domain.RefreshToken, error)
+	// MarkUsed consumes an unused, unrevoked token and reports whether this call was the one that consumed it.
+	MarkUsed(ctx context.Context, id uint, usedAt int64) (bool, error)
+	RevokeFamily(ctx context.Context, familyID string, revokedAt int64) error
+	RevokeByUserID(ctx context.Context, userID uint, revokedAt int64) error
+}
+
+type RefreshTokenRepositoryImpl struct {
+	DB *gorm.DB
+}
+
+func NewRefreshTokenRepository(db *gorm.DB) RefreshTokenRepository {
+	return &RefreshTokenRepositoryImpl{DB: db}
+}
+
+func (r *RefreshTokenRepositoryImpl) Create(ctx context.Context, refreshToken *domain.RefreshToken) error {
+	return r.DB.WithContext(ctx).Create(refreshToken).Error
+}
+
+func (r *RefreshTokenRepositoryImpl) FindByTokenHash(ctx context.Context, tokenHash string) (*domain.RefreshToken, error) {
+	refreshToken := new(domain.RefreshToken)
+	if err := r.DB.WithContext(ctx).Where("token_hash = ?", tokenHash).Take(refreshToken).Error; err != nil {
+		return nil, err
+	}
+	return refreshToken, nil
+}
+
+func (r *RefreshTokenRepositoryImpl) MarkUsed(ctx context.Context, id uint, usedAt int64) (bool, error) {
+	result := r.DB.WithContext(ctx).
+		Model(&domain.RefreshToken{}).
+		Where("id = ? AND used_at = 0 AND revoked_at = 0", id).
+		Update("used_at", usedAt)
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected == 1, nil
+}
+
+func (r *RefreshTokenRepositoryImpl) RevokeFamily(ctx context.Context, familyID string, revokedAt int64) error {
+	return r.DB.WithContext(ctx).
+		Model(&domain.RefreshToken{}).
+		Where("family_id = ? AND revoked_at = 0", familyID).
+		Update("revoked_at", revokedAt).Error
+}
+
+func (r *RefreshTokenRepositoryImpl) RevokeByUserID(ctx context.Context, userID uint, revokedAt int64) error {
+	return r.DB.WithContext(ctx).
+		Model(&domain.RefreshToken{}).
+		Where("user_id = ? AND revoked_at = 0", userID).
+		Update("revoked_at", revokedAt).Error
+}
@@ -0,0 +1,75 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/Ikhlashmulya/golang-clean-architecture-project-structure/internal/domain"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type RoleRepository interface {
+	FindByName(ctx context.Context, name string) (*domain.Role, error)
+	FindNamesByUserID(ctx context.Context, userID uint) ([]string, error)
+	FindPermissionNamesByRoleNames(ctx context.Context, roleNames []string) ([]string, error)
+	AddUserRole(ctx context.Context, userRole *domain.UserRole) error
+	RemoveUserRole(ctx context.Context, userID uint, roleID uint) (bool, error)
+}
+
+type RoleRepositoryImpl struct {
+	DB *gorm.DB
+}
+
+func NewRoleRepository(db *gorm.DB) RoleRepository {
+	return &RoleRepositoryImpl{DB: db}
+}
+
+func (r *RoleRepositoryImpl) FindByName(ctx context.Context, name string) (*domain.Role, error) {
+	role := new(domain.Role)
+	if err := r.DB.WithContext(ctx).Where("name = ?", name).Take(role).Error; err != nil {
+		return nil, err
+	}
+	return role, nil
+}
+
+func (r *RoleRepositoryImpl) FindNamesByUserID(ctx context.Context, userID uint) ([]string, error) {
+	var names []string
+	err := r.DB.WithContext(ctx).
+		Model(&domain.Role{}).
+		Joins("JOIN user_roles ON user_roles.role_id = roles.id").
+		Where("user_roles.user_id = ?", userID).
+		Order("roles.name").
+		Pluck("roles.name", &names).Error
+	if err != nil {
+		return nil, err
+	}
+	return names, nil
+}
+
+func (r *RoleRepositoryImpl) FindPermissionNamesByRoleNames(ctx context.Context, roleNames []string) ([]string, error) {
+	var names []string
+	err := r.DB.WithContext(ctx).
+		Model(&domain.Permission{}).
+		Distinct().
+		Joins("JOIN role_permissions ON role_permissions.permission_id = permissions.id").
+		Joins("JOIN roles ON roles.id = role_permissions.role_id").
+		Where("roles.name IN ?", roleNames).
+		Order("permissions.name").
+		Pluck("permissions.name", &names).Error
+	if err != nil {
+		return nil, err
+	}
+	return names, nil
+}
+
+func (r *RoleRepositoryImpl) AddUserRole(ctx context.Context, userRole *domain.UserRole) error {
+	return r.DB.WithContext(ctx).Clauses(clause.OnConflict{DoNothing: true}).Create(userRole).Error
+}
+
+func (r *RoleRepositoryImpl) RemoveUserRole(ctx context.Context, userID uint, roleID uint) (bool, error) {
+	result := r.DB.WithContext(ctx).Where("user_id = ? AND role_id = ?", userID, roleID).Delete(&domain.UserRole{})
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected == 1, nil
+}
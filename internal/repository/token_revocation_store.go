@@ -0,0 +1,127 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/Ikhlashmulya/golang-clean-architecture-project-structure/config"
+	"github.com/Ikhlashmulya/golang-clean-architecture-project-structure/internal/domain"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// TokenRevocationStore keeps track of access tokens that were invalidated before they expired.
+// Single tokens are keyed on their jti claim, while a per-user cutoff revokes every token issued before it.
+type TokenRevocationStore interface {
+	Revoke(ctx context.Context, revokedToken *domain.RevokedToken) error
+	IsRevoked(ctx context.Context, tokenID string) (bool, error)
+	RevokeAllByUserID(ctx context.Context, userID uint, revokedAt int64) error
+	// FindRevokedAtByUserID returns 0 when the user never revoked all of their tokens.
+	FindRevokedAtByUserID(ctx context.Context, userID uint) (int64, error)
+}
+
+// NewTokenRevocationStore selects the implementation with TOKEN_REVOCATION_STORE: database or memory.
+func NewTokenRevocationStore(config *config.Config, db *gorm.DB) TokenRevocationStore {
+	if config.Auth.TokenRevocationStore == "memory" {
+		return NewInMemoryTokenRevocationStore()
+	}
+	return NewGormTokenRevocationStore(db)
+}
+
+type GormTokenRevocationStore struct {
+	DB *gorm.DB
+}
+
+func NewGormTokenRevocationStore(db *gorm.DB) TokenRevocationStore {
+	return &GormTokenRevocationStore{DB: db}
+}
+
+func (s *GormTokenRevocationStore) Revoke(ctx context.Context, revokedToken *domain.RevokedToken) error {
+	return s.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("expires_at < ?", time.Now().UnixMilli()).Delete(&domain.RevokedToken{}).Error; err != nil {
+			return err
+		}
+
+		return tx.Clauses(clause.OnConflict{DoNothing: true}).Create(revokedToken).Error
+	})
+}
+
+func (s *GormTokenRevocationStore) IsRevoked(ctx context.Context, tokenID string) (bool, error) {
+	var countToken int64
+	if err := s.DB.WithContext(ctx).Model(&domain.RevokedToken{}).Where("token_id = ?", tokenID).Count(&countToken).Error; err != nil {
+		return false, err
+	}
+	return countToken > 0, nil
+}
+
+func (s *GormTokenRevocationStore) RevokeAllByUserID(ctx context.Context, userID uint, revokedAt int64) error {
+	return s.DB.WithContext(ctx).
+		Clauses(clause.OnConflict{UpdateAll: true}).
+		Create(&domain.UserTokenRevocation{UserID: userID, RevokedAt: revokedAt}).Error
+}
+
+func (s *GormTokenRevocationStore) FindRevokedAtByUserID(ctx context.Context, userID uint) (int64, error) {
+	revocation := new(domain.UserTokenRevocation)
+	if err := s.DB.WithContext(ctx).Where("user_id = ?", userID).Take(revocation).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return 0, nil
+		}
+		return 0, err
+	}
+	return revocation.RevokedAt, nil
+}
+
+// InMemoryTokenRevocationStore is only suitable for single node deployments,
+// revocations are lost on restart and are not shared between instances.
+type InMemoryTokenRevocationStore struct {
+	mu           sync.RWMutex
+	tokens       map[string]int64
+	usersRevoked map[uint]int64
+}
+
+func NewInMemoryTokenRevocationStore() TokenRevocationStore {
+	return &InMemoryTokenRevocationStore{
+		tokens:       make(map[string]int64),
+		usersRevoked: make(map[uint]int64),
+	}
+}
+
+func (s *InMemoryTokenRevocationStore) Revoke(ctx context.Context, revokedToken *domain.RevokedToken) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now().UnixMilli()
+	for tokenID, expiresAt := range s.tokens {
+		if expiresAt < now {
+			delete(s.tokens, tokenID)
+		}
+	}
+
+	s.tokens[revokedToken.TokenID] = revokedToken.ExpiresAt
+	return nil
+}
+
+func (s *InMemoryTokenRevocationStore) IsRevoked(ctx context.Context, tokenID string) (bool, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	_, ok := s.tokens[tokenID]
+	return ok, nil
+}
+
+func (s *InMemoryTokenRevocationStore) RevokeAllByUserID(ctx context.Context, userID uint, revokedAt int64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.usersRevoked[userID] = revokedAt
+	return nil
+}
+
+func (s *InMemoryTokenRevocationStore) FindRevokedAtByUserID(ctx context.Context, userID uint) (int64, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.usersRevoked[userID], nil
+}
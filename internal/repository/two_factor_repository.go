@@ -0,0 +1,86 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/Ikhlashmulya/golang-clean-architecture-project-structure/internal/domain"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type TwoFactorRepository interface {
+	FindTOTPByUserID(ctx context.Context, userID uint) (*domain.UserTOTP, error)
+	SaveTOTP(ctx context.Context, userTOTP *domain.UserTOTP) error
+	// ConfirmTOTP enables the pending secret and replaces any previous recovery codes.
+	ConfirmTOTP(ctx context.Context, userTOTP *domain.UserTOTP, recoveryCodes []domain.RecoveryCode) error
+	// UseTOTPStep records the time step of an accepted code and reports false when it was already used.
+	UseTOTPStep(ctx context.Context, userID uint, step int64) (bool, error)
+	UseRecoveryCode(ctx context.Context, userID uint, codeHash string, usedAt int64) (bool, error)
+	DeleteByUserID(ctx context.Context, userID uint) error
+}
+
+type TwoFactorRepositoryImpl struct {
+	DB *gorm.DB
+}
+
+func NewTwoFactorRepository(db *gorm.DB) TwoFactorRepository {
+	return &TwoFactorRepositoryImpl{DB: db}
+}
+
+func (r *TwoFactorRepositoryImpl) FindTOTPByUserID(ctx context.Context, userID uint) (*domain.UserTOTP, error) {
+	userTOTP := new(domain.UserTOTP)
+	if err := r.DB.WithContext(ctx).Where("user_id = ?", userID).Take(userTOTP).Error; err != nil {
+		return nil, err
+	}
+	return userTOTP, nil
+}
+
+func (r *TwoFactorRepositoryImpl) SaveTOTP(ctx context.Context, userTOTP *domain.UserTOTP) error {
+	return r.DB.WithContext(ctx).Clauses(clause.OnConflict{UpdateAll: true}).Create(userTOTP).Error
+}
+
+func (r *TwoFactorRepositoryImpl) ConfirmTOTP(ctx context.Context, userTOTP *domain.UserTOTP, recoveryCodes []domain.RecoveryCode) error {
+	return r.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Save(userTOTP).Error; err != nil {
+			return err
+		}
+
+		if err := tx.Where("user_id = ?", userTOTP.UserID).Delete(&domain.RecoveryCode{}).Error; err != nil {
+			return err
+		}
+
+		return tx.Create(&recoveryCodes).Error
+	})
+}
+
+func (r *TwoFactorRepositoryImpl) UseTOTPStep(ctx context.Context, userID uint, step int64) (bool, error) {
+	result := r.DB.WithContext(ctx).
+		Model(&domain.UserTOTP{}).
+		Where("user_id = ? AND last_used_step < ?", userID, step).
+		Update("last_used_step", step)
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected == 1, nil
+}
+
+func (r *TwoFactorRepositoryImpl) UseRecoveryCode(ctx context.Context, userID uint, codeHash string, usedAt int64) (bool, error) {
+	result := r.DB.WithContext(ctx).
+		Model(&domain.RecoveryCode{}).
+		Where("user_id = ? AND code_hash = ? AND used_at = 0", userID, codeHash).
+		Update("used_at", usedAt)
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected == 1, nil
+}
+
+func (r *TwoFactorRepositoryImpl) DeleteByUserID(ctx context.Context, userID uint) error {
+	return r.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("user_id = ?", userID).Delete(&domain.RecoveryCode{}).Error; err != nil {
+			return err
+		}
+
+		return tx.Where("user_id = ?", userID).Delete(&domain.UserTOTP{}).Error
+	})
+}
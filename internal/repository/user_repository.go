@@ -2,6 +2,8 @@ package repository
 
 import (
 	"context"
+	"strings"
+	"time"
 
 	"github.com/Ikhlashmulya/golang-clean-architecture-project-structure/internal/domain"
 	"gorm.io/gorm"
@@ -10,8 +12,38 @@ import (
 type UserRepository interface {
 	Create(ctx context.Context, user *domain.User) error
 	Update(ctx context.Context, user *domain.User) error
+	FindByID(ctx context.Context, id uint) (*domain.User, error)
 	FindByUsername(ctx context.Context, username string) (*domain.User, error)
 	CountByUsername(ctx context.Context, username string) (int64, error)
+	CountByEmail(ctx context.Context, email string) (int64, error)
+	Delete(ctx context.Context, user *domain.User) error
+	// FindDeletedByUsername returns the most recently soft deleted user that was not purged yet.
+	FindDeletedByUsername(ctx context.Context, username string) (*domain.User, error)
+	Restore(ctx context.Context, user *domain.User) error
+	PurgeDeleted(ctx context.Context, deletedBefore time.Time) (int64, error)
+	// Search returns one page of users, the total is only counted when WithTotal is set.
+	Search(ctx context.Context, search *UserSearch) ([]domain.User, int64, error)
+}
+
+// UserSearch pages either with Offset or, when After is set, with a keyset cursor on the sort column.
+type UserSearch struct {
+	NamePrefix     string
+	UsernamePrefix string
+	// CreatedFrom is inclusive and CreatedTo exclusive, both in milliseconds and ignored when 0.
+	CreatedFrom int64
+	CreatedTo   int64
+	SortBy      string
+	Descending  bool
+	Offset      int
+	Limit       int
+	After       *UserSearchCursor
+	WithTotal   bool
+}
+
+// UserSearchCursor is the sort column value and id of the last user on the previous page.
+type UserSearchCursor struct {
+	Value any
+	ID    uint
 }
 
 type UserRepositoryImpl struct {
@@ -42,6 +74,14 @@ func (r *UserRepositoryImpl) Update(ctx context.Context, user *domain.User) erro
 	})
 }
 
+func (r *UserRepositoryImpl) FindByID(ctx context.Context, id uint) (*domain.User, error) {
+	user := new(domain.User)
+	if err := r.DB.WithContext(ctx).Where("id = ?", id).Take(user).Error; err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
 func (r *UserRepositoryImpl) FindByUsername(ctx context.Context, username string) (*domain.User, error) {
 	user := new(domain.User)
 	if err := r.DB.WithContext(ctx).Where("username = ?", username).Take(user).Error; err != nil {
@@ -56,4 +96,104 @@ func (r *UserRepositoryImpl) CountByUsername(ctx context.Context, username strin
 		return 0, err
 	}
 	return countUser, nil
-}
\ No newline at end of file
+}
+
+// CountByEmail also counts soft deleted users, their address stays taken until they are purged.
+func (r *UserRepositoryImpl) CountByEmail(ctx context.Context, email string) (int64, error) {
+	var countUser int64
+	if err := r.DB.WithContext(ctx).Unscoped().Model(&domain.User{}).Where("email = ?", email).Count(&countUser).Error; err != nil {
+		return 0, err
+	}
+	return countUser, nil
+}
+
+func (r *UserRepositoryImpl) Delete(ctx context.Context, user *domain.User) error {
+	return r.DB.WithContext(ctx).Delete(user).Error
+}
+
+func (r *UserRepositoryImpl) FindDeletedByUsername(ctx context.Context, username string) (*domain.User, error) {
+	user := new(domain.User)
+	err := r.DB.WithContext(ctx).
+		Unscoped().
+		Where("username = ? AND deleted_at IS NOT NULL", username).
+		Order("deleted_at DESC").
+		Take(user).Error
+	if err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+func (r *UserRepositoryImpl) Restore(ctx context.Context, user *domain.User) error {
+	if err := r.DB.WithContext(ctx).Unscoped().Model(user).Update("deleted_at", nil).Error; err != nil {
+		return err
+	}
+	user.DeletedAt = gorm.DeletedAt{}
+	return nil
+}
+
+// PurgeDeleted hard deletes users soft deleted before deletedBefore, their tokens, roles and
+// second factors go with them through the ON DELETE CASCADE foreign keys.
+func (r *UserRepositoryImpl) PurgeDeleted(ctx context.Context, deletedBefore time.Time) (int64, error) {
+	result := r.DB.WithContext(ctx).Unscoped().Where("deleted_at < ?", deletedBefore).Delete(&domain.User{})
+	if result.Error != nil {
+		return 0, result.Error
+	}
+	return result.RowsAffected, nil
+}
+
+// likeEscaper escapes LIKE wildcards with '!', which unlike a backslash means the same in every dialect.
+var likeEscaper = strings.NewReplacer("!", "!!", "%", "!%", "_", "!_")
+
+func (r *UserRepositoryImpl) Search(ctx context.Context, search *UserSearch) ([]domain.User, int64, error) {
+	query := r.DB.WithContext(ctx).Model(&domain.User{})
+
+	if search.NamePrefix != "" {
+		query = query.Where("name LIKE ? ESCAPE '!'", likeEscaper.Replace(search.NamePrefix)+"%")
+	}
+
+	if search.UsernamePrefix != "" {
+		query = query.Where("username LIKE ? ESCAPE '!'", likeEscaper.Replace(search.UsernamePrefix)+"%")
+	}
+
+	if search.CreatedFrom > 0 {
+		query = query.Where("created_at >= ?", search.CreatedFrom)
+	}
+
+	if search.CreatedTo > 0 {
+		query = query.Where("created_at < ?", search.CreatedTo)
+	}
+
+	var total int64
+	if search.WithTotal {
+		if err := query.Session(&gorm.Session{}).Count(&total).Error; err != nil {
+			return nil, 0, err
+		}
+	}
+
+	operator, direction := ">", "ASC"
+	if search.Descending {
+		operator, direction = "<", "DESC"
+	}
+
+	if search.After != nil {
+		if search.SortBy == "id" {
+			query = query.Where("id "+operator+" ?", search.After.ID)
+		} else {
+			query = query.Where("("+search.SortBy+" "+operator+" ?) OR ("+search.SortBy+" = ? AND id "+operator+" ?)",
+				search.After.Value, search.After.Value, search.After.ID)
+		}
+	}
+
+	// id breaks ties so the order is stable across pages
+	if search.SortBy != "id" {
+		query = query.Order(search.SortBy + " " + direction)
+	}
+	query = query.Order("id " + direction)
+
+	var users []domain.User
+	if err := query.Offset(search.Offset).Limit(search.Limit).Find(&users).Error; err != nil {
+		return nil, 0, err
+	}
+	return users, total, nil
+}
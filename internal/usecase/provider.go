@@ -0,0 +1,5 @@
+package usecase
+
+import "github.com/google/wire"
+
+var ProviderSet = wire.NewSet(NewUserUsecase)
@@ -1,20 +1,34 @@
 package usecase
 
 import (
+	"bytes"
 	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
 	"time"
 
 	"golang.org/x/crypto/bcrypt"
 
+	"github.com/Ikhlashmulya/golang-clean-architecture-project-structure/config"
 	"github.com/Ikhlashmulya/golang-clean-architecture-project-structure/internal/domain"
 	"github.com/Ikhlashmulya/golang-clean-architecture-project-structure/internal/exception"
+	"github.com/Ikhlashmulya/golang-clean-architecture-project-structure/internal/infrastructure"
 	"github.com/Ikhlashmulya/golang-clean-architecture-project-structure/internal/model"
 	"github.com/Ikhlashmulya/golang-clean-architecture-project-structure/internal/model/mapper"
 	"github.com/Ikhlashmulya/golang-clean-architecture-project-structure/internal/repository"
 	"github.com/go-playground/validator/v10"
 	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
 	"github.com/sirupsen/logrus"
-	"github.com/spf13/viper"
+	"go.opentelemetry.io/otel/trace"
+	"gorm.io/gorm"
 )
 
 type UserUsecase interface {
@@ -23,107 +37,221 @@ type UserUsecase interface {
 	Update(ctx context.Context, request *model.UpdateUserRequest) (*model.UserResponse, error)
 	Current(ctx context.Context, request *model.GetUserRequest) (*model.UserResponse, error)
 	Verify(ctx context.Context, request *model.VerifyUserRequest) (*model.Auth, error)
+	Refresh(ctx context.Context, request *model.RefreshTokenRequest) (*model.TokenResponse, error)
+	Logout(ctx context.Context, request *model.LogoutUserRequest) error
+	LogoutAll(ctx context.Context, request *model.LogoutUserRequest) error
+	ForgotPassword(ctx context.Context, request *model.ForgotPasswordRequest) error
+	ResetPassword(ctx context.Context, request *model.ResetPasswordRequest) error
+	VerifyEmail(ctx context.Context, request *model.VerifyEmailRequest) (*model.UserResponse, error)
+	EnrollTOTP(ctx context.Context, request *model.EnrollTOTPRequest) (*model.TOTPEnrollmentResponse, error)
+	ConfirmTOTP(ctx context.Context, request *model.ConfirmTOTPRequest) (*model.RecoveryCodesResponse, error)
+	DisableTOTP(ctx context.Context, request *model.DisableTOTPRequest) error
+	LoginMFA(ctx context.Context, request *model.LoginMFARequest) (*model.TokenResponse, error)
+	UnlockUser(ctx context.Context, request *model.UnlockUserRequest) error
+	LockUser(ctx context.Context, request *model.LockUserRequest) error
+	SetPassword(ctx context.Context, request *model.SetPasswordRequest) error
+	GrantRole(ctx context.Context, request *model.UserRoleRequest) error
+	RevokeRole(ctx context.Context, request *model.UserRoleRequest) error
+	List(ctx context.Context, request *model.SearchUserRequest) ([]*model.UserResponse, *model.PageMetadata, error)
+	Delete(ctx context.Context, request *model.DeleteUserRequest) error
+	Restore(ctx context.Context, request *model.RestoreUserRequest) (*model.UserResponse, error)
+	PurgeDeleted(ctx context.Context) (int64, error)
 }
 
+// purposes of tokens that may only be exchanged at their own endpoint and never act as access tokens
+const (
+	emailVerificationPurpose = "email_verification"
+	mfaChallengePurpose      = "mfa_challenge"
+)
+
+const recoveryCodeCount = 10
+
+const defaultPageSize = 20
+
 type UserUsecaseImpl struct {
-	UserRepository repository.UserRepository
-	Logger         *logrus.Logger
-	Validate       *validator.Validate
-	Config         *viper.Viper
+	UserRepository               repository.UserRepository
+	RefreshTokenRepository       repository.RefreshTokenRepository
+	PasswordResetTokenRepository repository.PasswordResetTokenRepository
+	TwoFactorRepository          repository.TwoFactorRepository
+	RoleRepository               repository.RoleRepository
+	TokenRevocationStore         repository.TokenRevocationStore
+	LoginAttemptStore            repository.LoginAttemptStore
+	TokenSigner                  infrastructure.TokenSigner
+	Mailer                       infrastructure.Mailer
+	MailQueue                    *infrastructure.MailQueue
+	PasswordBreachChecker        infrastructure.PasswordBreachChecker
+	Metrics                      *infrastructure.Metrics
+	Tracer                       trace.Tracer
+	Logger                       *logrus.Logger
+	Validate                     *validator.Validate
+	Config                       *config.Config
 }
 
-func NewUserUsecase(userRepo repository.UserRepository, log *logrus.Logger,
-	validate *validator.Validate, config *viper.Viper) UserUsecase {
+func NewUserUsecase(userRepo repository.UserRepository, refreshTokenRepo repository.RefreshTokenRepository,
+	passwordResetTokenRepo repository.PasswordResetTokenRepository, twoFactorRepo repository.TwoFactorRepository,
+	roleRepo repository.RoleRepository, tokenRevocationStore repository.TokenRevocationStore, loginAttemptStore repository.LoginAttemptStore,
+	tokenSigner infrastructure.TokenSigner, mailer infrastructure.Mailer, mailQueue *infrastructure.MailQueue,
+	passwordBreachChecker infrastructure.PasswordBreachChecker,
+	metrics *infrastructure.Metrics, tracer trace.Tracer, log *logrus.Logger, validate *validator.Validate, config *config.Config) UserUsecase {
 	return &UserUsecaseImpl{
-		UserRepository: userRepo,
-		Logger:         log,
-		Validate:       validate,
-		Config:         config,
+		UserRepository:               userRepo,
+		RefreshTokenRepository:       refreshTokenRepo,
+		PasswordResetTokenRepository: passwordResetTokenRepo,
+		TwoFactorRepository:          twoFactorRepo,
+		RoleRepository:               roleRepo,
+		TokenRevocationStore:         tokenRevocationStore,
+		LoginAttemptStore:            loginAttemptStore,
+		TokenSigner:                  tokenSigner,
+		Mailer:                       mailer,
+		MailQueue:                    mailQueue,
+		PasswordBreachChecker:        passwordBreachChecker,
+		Metrics:                      metrics,
+		Tracer:                       tracer,
+		Logger:                       log,
+		Validate:                     validate,
+		Config:                       config,
 	}
 }
 
 func (uc *UserUsecaseImpl) Register(ctx context.Context, request *model.RegisterUserRequest) (*model.UserResponse, error) {
+	ctx, span := uc.Tracer.Start(ctx, "UserUsecase.Register")
+	defer span.End()
+
+	// canonical before validation, a username typed in upper case is accepted but stored in lower case
+	request.Username = infrastructure.CanonicalUsername(request.Username)
 	if err := uc.Validate.Struct(request); err != nil {
-		uc.Logger.WithError(err).Error("failed validating request body")
+		uc.log(ctx).WithError(err).Error("failed validating request body")
+		return nil, err
+	}
+
+	if err := uc.checkPasswordBreach(ctx, request.Password); err != nil {
 		return nil, err
 	}
 
 	countUser, err := uc.UserRepository.CountByUsername(ctx, request.Username)
 	if err != nil {
-		uc.Logger.WithError(err).Error("failed count user by username")
+		uc.log(ctx).WithError(err).Error("failed count user by username")
 		return nil, exception.ErrInternalServerError
 	}
 
 	if countUser > 0 {
-		uc.Logger.Warn("user already exists")
+		uc.log(ctx).Warn("user already exists")
 		return nil, exception.ErrUserAlreadyExist
 	}
 
-	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(request.Password), bcrypt.DefaultCost)
+	// a deleted account keeps its username until it is purged so it can still be restored
+	if _, err := uc.UserRepository.FindDeletedByUsername(ctx, request.Username); err == nil {
+		uc.log(ctx).Warn("username belongs to a deleted user")
+		return nil, exception.ErrUserAlreadyExist
+	} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+		uc.log(ctx).WithError(err).Error("failed find deleted user by username")
+		return nil, exception.ErrInternalServerError
+	}
+
+	countEmail, err := uc.UserRepository.CountByEmail(ctx, request.Email)
+	if err != nil {
+		uc.log(ctx).WithError(err).Error("failed count user by email")
+		return nil, exception.ErrInternalServerError
+	}
+
+	if countEmail > 0 {
+		uc.log(ctx).Warn("email already exists")
+		return nil, exception.ErrEmailAlreadyExist
+	}
+
+	hashedPassword, err := uc.hashPassword(ctx, request.Password)
 	if err != nil {
-		uc.Logger.WithError(err).Error("failed hashing password")
+		uc.log(ctx).WithError(err).Error("failed hashing password")
 		return nil, exception.ErrInternalServerError
 	}
 
 	user := new(domain.User)
 	user.Name = request.Name
 	user.Username = request.Username
+	user.Email = &request.Email
 	user.Password = string(hashedPassword)
+	if request.EmailVerified {
+		user.EmailVerifiedAt = time.Now().UnixMilli()
+	}
 
 	if err := uc.UserRepository.Create(ctx, user); err != nil {
-		uc.Logger.WithError(err).Error("failed create user to database")
+		uc.log(ctx).WithError(err).Error("failed create user to database")
 		return nil, exception.ErrInternalServerError
 	}
+	uc.Metrics.UserRegistered()
+
+	if !request.EmailVerified {
+		uc.queueEmailVerificationMail(ctx, *user)
+	}
 
 	return mapper.ToUserResponse(user), nil
 }
 
 func (uc *UserUsecaseImpl) Login(ctx context.Context, request *model.LoginUserRequest) (*model.TokenResponse, error) {
+	ctx, span := uc.Tracer.Start(ctx, "UserUsecase.Login")
+	defer span.End()
+
 	if err := uc.Validate.Struct(request); err != nil {
-		uc.Logger.WithError(err).Error("failed validating request body")
+		uc.log(ctx).WithError(err).Error("failed validating request body")
+		return nil, err
+	}
+	request.Username = infrastructure.CanonicalUsername(request.Username)
+
+	throttles := uc.loginThrottles(request.Username, request.IPAddress)
+	if err := uc.checkLoginThrottles(ctx, throttles); err != nil {
 		return nil, err
 	}
 
 	user, err := uc.UserRepository.FindByUsername(ctx, request.Username)
 	if err != nil {
-		uc.Logger.WithError(err).Error("failed find user by username")
+		uc.log(ctx).WithError(err).Error("failed find user by username")
+		uc.addLoginFailure(ctx, throttles)
+		uc.Metrics.LoginFailed()
 		return nil, exception.ErrUserNotFound
 	}
 
-	if err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(request.Password)); err != nil {
-		uc.Logger.WithError(err).Error("failed to compare hashedPassword and password")
+	if err := uc.comparePassword(ctx, user.Password, request.Password); err != nil {
+		uc.log(ctx).WithError(err).Error("failed to compare hashedPassword and password")
+		uc.addLoginFailure(ctx, throttles)
+		uc.Metrics.LoginFailed()
 		return nil, exception.ErrUserPasswordNotMatch
 	}
 
-	claims := jwt.MapClaims{
-		"id":       user.ID,
-		"username": user.Username,
-		"exp":      time.Now().Add(2 * time.Hour).Unix(),
+	if user.LockedAt != 0 {
+		uc.log(ctx).Warn("login attempt on locked account")
+		return nil, exception.ErrAccountLocked
 	}
 
-	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte(uc.Config.GetString("JWT_SECRET_KEY")))
-	if err != nil {
-		uc.Logger.WithError(err).Error("failed sign token")
+	if user.EmailVerifiedAt == 0 && !uc.Config.Current().Auth.AllowUnverifiedLogin {
+		uc.log(ctx).Warn("login attempt with unverified email")
+		return nil, exception.ErrEmailNotVerified
+	}
+
+	userTOTP, err := uc.TwoFactorRepository.FindTOTPByUserID(ctx, user.ID)
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		uc.log(ctx).WithError(err).Error("failed find user totp")
 		return nil, exception.ErrInternalServerError
 	}
 
-	tokenResponse := &model.TokenResponse{
-		AccessToken: token,
-		TokenType:   "Bearer",
+	if userTOTP != nil && userTOTP.ConfirmedAt != 0 {
+		return uc.issueMFAChallenge(ctx, user)
 	}
 
-	return tokenResponse, nil
+	return uc.issueLoginToken(ctx, user)
 }
 
 func (uc *UserUsecaseImpl) Update(ctx context.Context, request *model.UpdateUserRequest) (*model.UserResponse, error) {
+	ctx, span := uc.Tracer.Start(ctx, "UserUsecase.Update")
+	defer span.End()
+
 	if err := uc.Validate.Struct(request); err != nil {
-		uc.Logger.WithError(err).Error("failed validating request body")
+		uc.log(ctx).WithError(err).Error("failed validating request body")
 		return nil, err
 	}
 
 	user, err := uc.UserRepository.FindByUsername(ctx, request.Username)
 	if err != nil {
-		uc.Logger.WithError(err).Error("failed find user by username")
+		uc.log(ctx).WithError(err).Error("failed find user by username")
 		return nil, exception.ErrUserNotFound
 	}
 
@@ -131,10 +259,31 @@ func (uc *UserUsecaseImpl) Update(ctx context.Context, request *model.UpdateUser
 		user.Name = request.Name
 	}
 
+	emailChanged := request.Email != "" && (user.Email == nil || *user.Email != request.Email)
+	if emailChanged {
+		countEmail, err := uc.UserRepository.CountByEmail(ctx, request.Email)
+		if err != nil {
+			uc.log(ctx).WithError(err).Error("failed count user by email")
+			return nil, exception.ErrInternalServerError
+		}
+
+		if countEmail > 0 {
+			uc.log(ctx).Warn("email already exists")
+			return nil, exception.ErrEmailAlreadyExist
+		}
+
+		user.Email = &request.Email
+		user.EmailVerifiedAt = 0
+	}
+
 	if request.Password != "" {
-		hashedPassword, err := bcrypt.GenerateFromPassword([]byte(request.Password), bcrypt.DefaultCost)
+		if err := uc.checkPasswordBreach(ctx, request.Password); err != nil {
+			return nil, err
+		}
+
+		hashedPassword, err := uc.hashPassword(ctx, request.Password)
 		if err != nil {
-			uc.Logger.WithError(err).Error("failed hashing password")
+			uc.log(ctx).WithError(err).Error("failed hashing password")
 			return nil, exception.ErrInternalServerError
 		}
 
@@ -142,22 +291,29 @@ func (uc *UserUsecaseImpl) Update(ctx context.Context, request *model.UpdateUser
 	}
 
 	if err := uc.UserRepository.Update(ctx, user); err != nil {
-		uc.Logger.WithError(err).Error("failed update user to database")
+		uc.log(ctx).WithError(err).Error("failed update user to database")
 		return nil, exception.ErrInternalServerError
 	}
 
+	if emailChanged {
+		uc.queueEmailVerificationMail(ctx, *user)
+	}
+
 	return mapper.ToUserResponse(user), nil
 }
 
 func (uc *UserUsecaseImpl) Current(ctx context.Context, request *model.GetUserRequest) (*model.UserResponse, error) {
+	ctx, span := uc.Tracer.Start(ctx, "UserUsecase.Current")
+	defer span.End()
+
 	if err := uc.Validate.Struct(request); err != nil {
-		uc.Logger.WithError(err).Error("failed validating request body")
+		uc.log(ctx).WithError(err).Error("failed validating request body")
 		return nil, err
 	}
 
 	user, err := uc.UserRepository.FindByUsername(ctx, request.Username)
 	if err != nil {
-		uc.Logger.WithError(err).Error("failed find user by username")
+		uc.log(ctx).WithError(err).Error("failed find user by username")
 		return nil, exception.ErrUserNotFound
 	}
 
@@ -165,31 +321,1339 @@ func (uc *UserUsecaseImpl) Current(ctx context.Context, request *model.GetUserRe
 }
 
 func (uc *UserUsecaseImpl) Verify(ctx context.Context, request *model.VerifyUserRequest) (*model.Auth, error) {
-	token, err := jwt.Parse(request.AccessToken, func(t *jwt.Token) (interface{}, error) {
-		return []byte(uc.Config.GetString("JWT_SECRET_KEY")), nil
-	})
+	ctx, span := uc.Tracer.Start(ctx, "UserUsecase.Verify")
+	defer span.End()
+
+	claims, err := uc.TokenSigner.Parse(request.AccessToken)
 	if err != nil {
-		uc.Logger.WithError(err).Error("user unauthorized")
+		uc.log(ctx).WithError(err).Error("user unauthorized")
+		uc.Metrics.TokenRejected("invalid")
 		return nil, exception.ErrUserUnauthorized
 	}
 
-	claims, ok := token.Claims.(jwt.MapClaims)
-	if !ok {
+	username, _ := claims["username"].(string)
+	userID, _ := claims["id"].(float64)
+	tokenID, _ := claims["jti"].(string)
+	sessionID, _ := claims["sid"].(string)
+	issuedAt, _ := claims["iat"].(float64)
+	expiresAt, _ := claims["exp"].(float64)
+	if _, ok := claims["purpose"]; ok || username == "" || tokenID == "" {
+		uc.Metrics.TokenRejected("invalid")
+		return nil, exception.ErrUserUnauthorized
+	}
+
+	revoked, err := uc.TokenRevocationStore.IsRevoked(ctx, tokenID)
+	if err != nil {
+		uc.log(ctx).WithError(err).Error("failed check token revocation")
+		return nil, exception.ErrInternalServerError
+	}
+
+	if revoked {
+		uc.Metrics.TokenRejected("revoked")
+		return nil, exception.ErrUserUnauthorized
+	}
+
+	revokedAt, err := uc.TokenRevocationStore.FindRevokedAtByUserID(ctx, uint(userID))
+	if err != nil {
+		uc.log(ctx).WithError(err).Error("failed find user token revocation")
+		return nil, exception.ErrInternalServerError
+	}
+
+	// revocations are in milliseconds, iat only in seconds, so a token issued within the second after a
+	// revocation would look revoked. Tokens signed before iat_ms was added fall back to iat, and a
+	// token issued in the millisecond of the revocation counts as revoked.
+	issuedAtMilli := int64(issuedAt) * 1000
+	if claimIssuedAtMilli, ok := claims["iat_ms"].(float64); ok {
+		issuedAtMilli = int64(claimIssuedAtMilli)
+	}
+
+	if issuedAtMilli <= revokedAt && revokedAt != 0 {
+		uc.Metrics.TokenRejected("revoked")
 		return nil, exception.ErrUserUnauthorized
 	}
 
-	countUser, err := uc.UserRepository.CountByUsername(ctx, claims["username"].(string))
+	countUser, err := uc.UserRepository.CountByUsername(ctx, username)
 	if err != nil {
-		uc.Logger.WithError(err).Error("failed count user by username")
+		uc.log(ctx).WithError(err).Error("failed count user by username")
 		return nil, exception.ErrInternalServerError
 	}
 
 	if countUser == 0 {
+		uc.Metrics.TokenRejected("user")
 		return nil, exception.ErrUserUnauthorized
 	}
 
+	var roles []string
+	if claimRoles, ok := claims["roles"].([]any); ok {
+		for _, claimRole := range claimRoles {
+			if role, ok := claimRole.(string); ok {
+				roles = append(roles, role)
+			}
+		}
+	}
+
+	var permissions []string
+	if len(roles) > 0 {
+		permissions, err = uc.RoleRepository.FindPermissionNamesByRoleNames(ctx, roles)
+		if err != nil {
+			uc.log(ctx).WithError(err).Error("failed find permissions by role names")
+			return nil, exception.ErrInternalServerError
+		}
+	}
+
 	return &model.Auth{
-		Username: claims["username"].(string),
-		ID:       uint(claims["id"].(float64)),
+		ID:          uint(userID),
+		Username:    username,
+		TokenID:     tokenID,
+		SessionID:   sessionID,
+		ExpiresAt:   int64(expiresAt),
+		Roles:       roles,
+		Permissions: permissions,
+	}, nil
+}
+
+func (uc *UserUsecaseImpl) Refresh(ctx context.Context, request *model.RefreshTokenRequest) (*model.TokenResponse, error) {
+	ctx, span := uc.Tracer.Start(ctx, "UserUsecase.Refresh")
+	defer span.End()
+
+	if err := uc.Validate.Struct(request); err != nil {
+		uc.log(ctx).WithError(err).Error("failed validating request body")
+		return nil, err
+	}
+
+	refreshToken, err := uc.RefreshTokenRepository.FindByTokenHash(ctx, hashToken(request.RefreshToken))
+	if err != nil {
+		uc.log(ctx).WithError(err).Error("failed find refresh token by hash")
+		return nil, exception.ErrRefreshTokenInvalid
+	}
+
+	now := time.Now().UnixMilli()
+
+	if refreshToken.RevokedAt != 0 {
+		uc.log(ctx).WithField("family_id", refreshToken.FamilyID).Warn("revoked refresh token used")
+		return nil, exception.ErrRefreshTokenInvalid
+	}
+
+	if refreshToken.UsedAt != 0 {
+		uc.revokeRefreshTokenFamily(ctx, refreshToken.FamilyID, now)
+		return nil, exception.ErrRefreshTokenInvalid
+	}
+
+	if refreshToken.ExpiresAt <= now {
+		uc.log(ctx).WithField("family_id", refreshToken.FamilyID).Warn("expired refresh token used")
+		return nil, exception.ErrRefreshTokenInvalid
+	}
+
+	consumed, err := uc.RefreshTokenRepository.MarkUsed(ctx, refreshToken.ID, now)
+	if err != nil {
+		uc.log(ctx).WithError(err).Error("failed mark refresh token as used")
+		return nil, exception.ErrInternalServerError
+	}
+
+	// another request rotated this token between the lookup and the update
+	if !consumed {
+		uc.revokeRefreshTokenFamily(ctx, refreshToken.FamilyID, now)
+		return nil, exception.ErrRefreshTokenInvalid
+	}
+
+	user, err := uc.UserRepository.FindByID(ctx, refreshToken.UserID)
+	if err != nil {
+		uc.log(ctx).WithError(err).Error("failed find user by id")
+		return nil, exception.ErrRefreshTokenInvalid
+	}
+
+	return uc.issueToken(ctx, user, refreshToken.FamilyID)
+}
+
+func (uc *UserUsecaseImpl) Logout(ctx context.Context, request *model.LogoutUserRequest) error {
+	ctx, span := uc.Tracer.Start(ctx, "UserUsecase.Logout")
+	defer span.End()
+
+	if err := uc.Validate.Struct(request); err != nil {
+		uc.log(ctx).WithError(err).Error("failed validating request body")
+		return err
+	}
+
+	revokedToken := new(domain.RevokedToken)
+	revokedToken.TokenID = request.TokenID
+	revokedToken.UserID = request.ID
+	revokedToken.ExpiresAt = request.ExpiresAt * 1000
+
+	if err := uc.TokenRevocationStore.Revoke(ctx, revokedToken); err != nil {
+		uc.log(ctx).WithError(err).Error("failed revoke token")
+		return exception.ErrInternalServerError
+	}
+
+	if request.SessionID != "" {
+		if err := uc.RefreshTokenRepository.RevokeFamily(ctx, request.SessionID, time.Now().UnixMilli()); err != nil {
+			uc.log(ctx).WithError(err).Error("failed revoke refresh token family")
+			return exception.ErrInternalServerError
+		}
+	}
+
+	return nil
+}
+
+func (uc *UserUsecaseImpl) LogoutAll(ctx context.Context, request *model.LogoutUserRequest) error {
+	ctx, span := uc.Tracer.Start(ctx, "UserUsecase.LogoutAll")
+	defer span.End()
+
+	if err := uc.Validate.Struct(request); err != nil {
+		uc.log(ctx).WithError(err).Error("failed validating request body")
+		return err
+	}
+
+	return uc.revokeUserTokens(ctx, request.ID)
+}
+
+// ForgotPassword always succeeds once the request is valid, the lookup and the mail delivery happen in the
+// background so neither the response nor its timing reveals whether the username exists. When the mail
+// queue is full the request is dropped, the user can ask again.
+func (uc *UserUsecaseImpl) ForgotPassword(ctx context.Context, request *model.ForgotPasswordRequest) error {
+	ctx, span := uc.Tracer.Start(ctx, "UserUsecase.ForgotPassword")
+	defer span.End()
+
+	if err := uc.Validate.Struct(request); err != nil {
+		uc.log(ctx).WithError(err).Error("failed validating request body")
+		return err
+	}
+
+	username := request.Username
+	if err := uc.MailQueue.Enqueue(ctx, func(ctx context.Context) { uc.sendPasswordResetMail(ctx, username) }); err != nil {
+		uc.log(ctx).WithError(err).Error("failed queue password reset mail")
+	}
+
+	return nil
+}
+
+func (uc *UserUsecaseImpl) ResetPassword(ctx context.Context, request *model.ResetPasswordRequest) error {
+	ctx, span := uc.Tracer.Start(ctx, "UserUsecase.ResetPassword")
+	defer span.End()
+
+	if err := uc.Validate.Struct(request); err != nil {
+		uc.log(ctx).WithError(err).Error("failed validating request body")
+		return err
+	}
+
+	// checked before the token is consumed, so it can be used again with another password
+	if err := uc.checkPasswordBreach(ctx, request.Password); err != nil {
+		return err
+	}
+
+	passwordResetToken, err := uc.PasswordResetTokenRepository.FindByTokenHash(ctx, hashToken(request.Token))
+	if err != nil {
+		uc.log(ctx).WithError(err).Error("failed find password reset token by hash")
+		return exception.ErrResetTokenInvalid
+	}
+
+	now := time.Now().UnixMilli()
+	if passwordResetToken.UsedAt != 0 || passwordResetToken.ExpiresAt <= now {
+		uc.log(ctx).Warn("used or expired password reset token")
+		return exception.ErrResetTokenInvalid
+	}
+
+	consumed, err := uc.PasswordResetTokenRepository.MarkUsed(ctx, passwordResetToken.ID, now)
+	if err != nil {
+		uc.log(ctx).WithError(err).Error("failed mark password reset token as used")
+		return exception.ErrInternalServerError
+	}
+
+	if !consumed {
+		return exception.ErrResetTokenInvalid
+	}
+
+	user, err := uc.UserRepository.FindByID(ctx, passwordResetToken.UserID)
+	if err != nil {
+		uc.log(ctx).WithError(err).Error("failed find user by id")
+		return exception.ErrResetTokenInvalid
+	}
+
+	hashedPassword, err := uc.hashPassword(ctx, request.Password)
+	if err != nil {
+		uc.log(ctx).WithError(err).Error("failed hashing password")
+		return exception.ErrInternalServerError
+	}
+
+	user.Password = string(hashedPassword)
+
+	if err := uc.UserRepository.Update(ctx, user); err != nil {
+		uc.log(ctx).WithError(err).Error("failed update user to database")
+		return exception.ErrInternalServerError
+	}
+
+	// whoever knew the old password must not keep a session
+	return uc.revokeUserTokens(ctx, user.ID)
+}
+
+func (uc *UserUsecaseImpl) VerifyEmail(ctx context.Context, request *model.VerifyEmailRequest) (*model.UserResponse, error) {
+	ctx, span := uc.Tracer.Start(ctx, "UserUsecase.VerifyEmail")
+	defer span.End()
+
+	if err := uc.Validate.Struct(request); err != nil {
+		uc.log(ctx).WithError(err).Error("failed validating request body")
+		return nil, err
+	}
+
+	claims, err := uc.TokenSigner.Parse(request.Token)
+	if err != nil {
+		uc.log(ctx).WithError(err).Warn("invalid email verification token")
+		return nil, exception.ErrEmailTokenInvalid
+	}
+
+	purpose, _ := claims["purpose"].(string)
+	userID, _ := claims["id"].(float64)
+	email, _ := claims["email"].(string)
+	if purpose != emailVerificationPurpose || email == "" {
+		return nil, exception.ErrEmailTokenInvalid
+	}
+
+	user, err := uc.UserRepository.FindByID(ctx, uint(userID))
+	if err != nil {
+		uc.log(ctx).WithError(err).Error("failed find user by id")
+		return nil, exception.ErrEmailTokenInvalid
+	}
+
+	// the link is bound to the address it was sent to, changing the email invalidates it
+	if user.Email == nil || *user.Email != email {
+		return nil, exception.ErrEmailTokenInvalid
+	}
+
+	if user.EmailVerifiedAt == 0 {
+		user.EmailVerifiedAt = time.Now().UnixMilli()
+
+		if err := uc.UserRepository.Update(ctx, user); err != nil {
+			uc.log(ctx).WithError(err).Error("failed update user to database")
+			return nil, exception.ErrInternalServerError
+		}
+	}
+
+	return mapper.ToUserResponse(user), nil
+}
+
+func (uc *UserUsecaseImpl) EnrollTOTP(ctx context.Context, request *model.EnrollTOTPRequest) (*model.TOTPEnrollmentResponse, error) {
+	ctx, span := uc.Tracer.Start(ctx, "UserUsecase.EnrollTOTP")
+	defer span.End()
+
+	if err := uc.Validate.Struct(request); err != nil {
+		uc.log(ctx).WithError(err).Error("failed validating request body")
+		return nil, err
+	}
+
+	user, err := uc.UserRepository.FindByID(ctx, request.ID)
+	if err != nil {
+		uc.log(ctx).WithError(err).Error("failed find user by id")
+		return nil, exception.ErrUserNotFound
+	}
+
+	userTOTP, err := uc.TwoFactorRepository.FindTOTPByUserID(ctx, user.ID)
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		uc.log(ctx).WithError(err).Error("failed find user totp")
+		return nil, exception.ErrInternalServerError
+	}
+
+	if userTOTP != nil && userTOTP.ConfirmedAt != 0 {
+		return nil, exception.ErrMFAAlreadyEnabled
+	}
+
+	secret, err := infrastructure.GenerateTOTPSecret()
+	if err != nil {
+		uc.log(ctx).WithError(err).Error("failed generate totp secret")
+		return nil, exception.ErrInternalServerError
+	}
+
+	// enrolling again replaces a pending secret that was never confirmed
+	if err := uc.TwoFactorRepository.SaveTOTP(ctx, &domain.UserTOTP{UserID: user.ID, Secret: secret}); err != nil {
+		uc.log(ctx).WithError(err).Error("failed save user totp to database")
+		return nil, exception.ErrInternalServerError
+	}
+
+	return &model.TOTPEnrollmentResponse{
+		Secret: secret,
+		URI:    infrastructure.TOTPURI(uc.Config.App.Name, user.Username, secret),
 	}, nil
 }
+
+func (uc *UserUsecaseImpl) ConfirmTOTP(ctx context.Context, request *model.ConfirmTOTPRequest) (*model.RecoveryCodesResponse, error) {
+	ctx, span := uc.Tracer.Start(ctx, "UserUsecase.ConfirmTOTP")
+	defer span.End()
+
+	if err := uc.Validate.Struct(request); err != nil {
+		uc.log(ctx).WithError(err).Error("failed validating request body")
+		return nil, err
+	}
+
+	userTOTP, err := uc.TwoFactorRepository.FindTOTPByUserID(ctx, request.ID)
+	if err != nil {
+		uc.log(ctx).WithError(err).Error("failed find user totp")
+		return nil, exception.ErrMFANotEnrolled
+	}
+
+	if userTOTP.ConfirmedAt != 0 {
+		return nil, exception.ErrMFAAlreadyEnabled
+	}
+
+	step, ok := infrastructure.ValidateTOTP(userTOTP.Secret, request.Code, time.Now())
+	if !ok {
+		return nil, exception.ErrMFACodeInvalid
+	}
+
+	userTOTP.ConfirmedAt = time.Now().UnixMilli()
+	userTOTP.LastUsedStep = step
+
+	codes := make([]string, recoveryCodeCount)
+	recoveryCodes := make([]domain.RecoveryCode, recoveryCodeCount)
+	for i := range codes {
+		code, err := generateRecoveryCode()
+		if err != nil {
+			uc.log(ctx).WithError(err).Error("failed generate recovery code")
+			return nil, exception.ErrInternalServerError
+		}
+
+		codes[i] = code
+		recoveryCodes[i] = domain.RecoveryCode{UserID: userTOTP.UserID, CodeHash: hashToken(normalizeRecoveryCode(code))}
+	}
+
+	if err := uc.TwoFactorRepository.ConfirmTOTP(ctx, userTOTP, recoveryCodes); err != nil {
+		uc.log(ctx).WithError(err).Error("failed confirm user totp to database")
+		return nil, exception.ErrInternalServerError
+	}
+
+	return &model.RecoveryCodesResponse{RecoveryCodes: codes}, nil
+}
+
+func (uc *UserUsecaseImpl) DisableTOTP(ctx context.Context, request *model.DisableTOTPRequest) error {
+	ctx, span := uc.Tracer.Start(ctx, "UserUsecase.DisableTOTP")
+	defer span.End()
+
+	if err := uc.Validate.Struct(request); err != nil {
+		uc.log(ctx).WithError(err).Error("failed validating request body")
+		return err
+	}
+
+	user, err := uc.UserRepository.FindByID(ctx, request.ID)
+	if err != nil {
+		uc.log(ctx).WithError(err).Error("failed find user by id")
+		return exception.ErrUserNotFound
+	}
+
+	if err := uc.comparePassword(ctx, user.Password, request.Password); err != nil {
+		uc.log(ctx).WithError(err).Error("failed to compare hashedPassword and password")
+		return exception.ErrUserPasswordNotMatch
+	}
+
+	if _, err := uc.TwoFactorRepository.FindTOTPByUserID(ctx, user.ID); err != nil {
+		uc.log(ctx).WithError(err).Error("failed find user totp")
+		return exception.ErrMFANotEnrolled
+	}
+
+	if err := uc.TwoFactorRepository.DeleteByUserID(ctx, user.ID); err != nil {
+		uc.log(ctx).WithError(err).Error("failed delete user totp from database")
+		return exception.ErrInternalServerError
+	}
+
+	return nil
+}
+
+// LoginMFA exchanges the challenge returned by Login and a TOTP or recovery code for the real tokens.
+// Every challenge allows a single attempt, a wrong code means logging in with the password again.
+func (uc *UserUsecaseImpl) LoginMFA(ctx context.Context, request *model.LoginMFARequest) (*model.TokenResponse, error) {
+	ctx, span := uc.Tracer.Start(ctx, "UserUsecase.LoginMFA")
+	defer span.End()
+
+	if err := uc.Validate.Struct(request); err != nil {
+		uc.log(ctx).WithError(err).Error("failed validating request body")
+		return nil, err
+	}
+
+	claims, err := uc.TokenSigner.Parse(request.MFAToken)
+	if err != nil {
+		uc.log(ctx).WithError(err).Warn("invalid mfa token")
+		return nil, exception.ErrMFATokenInvalid
+	}
+
+	purpose, _ := claims["purpose"].(string)
+	userID, _ := claims["id"].(float64)
+	tokenID, _ := claims["jti"].(string)
+	expiresAt, _ := claims["exp"].(float64)
+	if purpose != mfaChallengePurpose || tokenID == "" {
+		return nil, exception.ErrMFATokenInvalid
+	}
+
+	user, err := uc.UserRepository.FindByID(ctx, uint(userID))
+	if err != nil {
+		uc.log(ctx).WithError(err).Error("failed find user by id")
+		return nil, exception.ErrMFATokenInvalid
+	}
+
+	// wrong codes count against the same keys as wrong passwords, or a leaked password would give
+	// unlimited code guesses by logging in again after every one
+	throttles := uc.loginThrottles(user.Username, request.IPAddress)
+	if err := uc.checkLoginThrottles(ctx, throttles); err != nil {
+		return nil, err
+	}
+
+	revoked, err := uc.TokenRevocationStore.IsRevoked(ctx, tokenID)
+	if err != nil {
+		uc.log(ctx).WithError(err).Error("failed check token revocation")
+		return nil, exception.ErrInternalServerError
+	}
+
+	if revoked {
+		return nil, exception.ErrMFATokenInvalid
+	}
+
+	revokedToken := &domain.RevokedToken{TokenID: tokenID, UserID: uint(userID), ExpiresAt: int64(expiresAt) * 1000}
+	if err := uc.TokenRevocationStore.Revoke(ctx, revokedToken); err != nil {
+		uc.log(ctx).WithError(err).Error("failed revoke mfa token")
+		return nil, exception.ErrInternalServerError
+	}
+
+	userTOTP, err := uc.TwoFactorRepository.FindTOTPByUserID(ctx, user.ID)
+	if err != nil || userTOTP.ConfirmedAt == 0 {
+		uc.log(ctx).WithError(err).Error("failed find user totp")
+		return nil, exception.ErrMFATokenInvalid
+	}
+
+	accepted, err := uc.useSecondFactor(ctx, userTOTP, request.Code)
+	if err != nil {
+		uc.log(ctx).WithError(err).Error("failed verify second factor")
+		return nil, exception.ErrInternalServerError
+	}
+
+	if !accepted {
+		uc.log(ctx).Warn("invalid two-factor code")
+		uc.addLoginFailure(ctx, throttles)
+		uc.Metrics.LoginFailed()
+		return nil, exception.ErrMFACodeInvalid
+	}
+
+	return uc.issueLoginToken(ctx, user)
+}
+
+// UnlockUser clears the failed login counter of a user that was locked out and lifts a lock set by LockUser.
+func (uc *UserUsecaseImpl) UnlockUser(ctx context.Context, request *model.UnlockUserRequest) error {
+	ctx, span := uc.Tracer.Start(ctx, "UserUsecase.UnlockUser")
+	defer span.End()
+
+	if err := uc.Validate.Struct(request); err != nil {
+		uc.log(ctx).WithError(err).Error("failed validating request body")
+		return err
+	}
+	request.Username = infrastructure.CanonicalUsername(request.Username)
+
+	user, err := uc.UserRepository.FindByUsername(ctx, request.Username)
+	if err != nil {
+		uc.log(ctx).WithError(err).Error("failed find user by username")
+		return exception.ErrUserNotFound
+	}
+
+	if user.LockedAt != 0 {
+		user.LockedAt = 0
+		if err := uc.UserRepository.Update(ctx, user); err != nil {
+			uc.log(ctx).WithError(err).Error("failed update user to database")
+			return exception.ErrInternalServerError
+		}
+	}
+
+	if err := uc.LoginAttemptStore.Reset(ctx, usernameThrottleKey(request.Username)); err != nil {
+		uc.log(ctx).WithError(err).Error("failed reset login attempts")
+		return exception.ErrInternalServerError
+	}
+
+	return nil
+}
+
+// LockUser keeps a user from signing in until UnlockUser is called and revokes every token it holds.
+func (uc *UserUsecaseImpl) LockUser(ctx context.Context, request *model.LockUserRequest) error {
+	ctx, span := uc.Tracer.Start(ctx, "UserUsecase.LockUser")
+	defer span.End()
+
+	if err := uc.Validate.Struct(request); err != nil {
+		uc.log(ctx).WithError(err).Error("failed validating request body")
+		return err
+	}
+	request.Username = infrastructure.CanonicalUsername(request.Username)
+
+	user, err := uc.UserRepository.FindByUsername(ctx, request.Username)
+	if err != nil {
+		uc.log(ctx).WithError(err).Error("failed find user by username")
+		return exception.ErrUserNotFound
+	}
+
+	if user.LockedAt == 0 {
+		user.LockedAt = time.Now().UnixMilli()
+		if err := uc.UserRepository.Update(ctx, user); err != nil {
+			uc.log(ctx).WithError(err).Error("failed update user to database")
+			return exception.ErrInternalServerError
+		}
+	}
+
+	return uc.revokeUserTokens(ctx, user.ID)
+}
+
+// SetPassword replaces the password of a user without asking for the current one, it is meant for operators.
+// Every session of the user is signed out.
+func (uc *UserUsecaseImpl) SetPassword(ctx context.Context, request *model.SetPasswordRequest) error {
+	ctx, span := uc.Tracer.Start(ctx, "UserUsecase.SetPassword")
+	defer span.End()
+
+	if err := uc.Validate.Struct(request); err != nil {
+		uc.log(ctx).WithError(err).Error("failed validating request body")
+		return err
+	}
+	request.Username = infrastructure.CanonicalUsername(request.Username)
+
+	if err := uc.checkPasswordBreach(ctx, request.Password); err != nil {
+		return err
+	}
+
+	user, err := uc.UserRepository.FindByUsername(ctx, request.Username)
+	if err != nil {
+		uc.log(ctx).WithError(err).Error("failed find user by username")
+		return exception.ErrUserNotFound
+	}
+
+	hashedPassword, err := uc.hashPassword(ctx, request.Password)
+	if err != nil {
+		uc.log(ctx).WithError(err).Error("failed hashing password")
+		return exception.ErrInternalServerError
+	}
+
+	user.Password = string(hashedPassword)
+	if err := uc.UserRepository.Update(ctx, user); err != nil {
+		uc.log(ctx).WithError(err).Error("failed update user to database")
+		return exception.ErrInternalServerError
+	}
+
+	return uc.revokeUserTokens(ctx, user.ID)
+}
+
+func (uc *UserUsecaseImpl) GrantRole(ctx context.Context, request *model.UserRoleRequest) error {
+	ctx, span := uc.Tracer.Start(ctx, "UserUsecase.GrantRole")
+	defer span.End()
+
+	if err := uc.Validate.Struct(request); err != nil {
+		uc.log(ctx).WithError(err).Error("failed validating request body")
+		return err
+	}
+
+	user, role, err := uc.findUserAndRole(ctx, request)
+	if err != nil {
+		return err
+	}
+
+	if err := uc.RoleRepository.AddUserRole(ctx, &domain.UserRole{UserID: user.ID, RoleID: role.ID}); err != nil {
+		uc.log(ctx).WithError(err).Error("failed add user role to database")
+		return exception.ErrInternalServerError
+	}
+
+	return nil
+}
+
+// RevokeRole removes a role and invalidates the access tokens of the user, the role claim in them
+// would otherwise stay valid until they expire. Refreshing issues a token without the role.
+func (uc *UserUsecaseImpl) RevokeRole(ctx context.Context, request *model.UserRoleRequest) error {
+	ctx, span := uc.Tracer.Start(ctx, "UserUsecase.RevokeRole")
+	defer span.End()
+
+	if err := uc.Validate.Struct(request); err != nil {
+		uc.log(ctx).WithError(err).Error("failed validating request body")
+		return err
+	}
+
+	user, role, err := uc.findUserAndRole(ctx, request)
+	if err != nil {
+		return err
+	}
+
+	removed, err := uc.RoleRepository.RemoveUserRole(ctx, user.ID, role.ID)
+	if err != nil {
+		uc.log(ctx).WithError(err).Error("failed remove user role from database")
+		return exception.ErrInternalServerError
+	}
+
+	if !removed {
+		return nil
+	}
+
+	if err := uc.TokenRevocationStore.RevokeAllByUserID(ctx, user.ID, time.Now().UnixMilli()); err != nil {
+		uc.log(ctx).WithError(err).Error("failed revoke all user tokens")
+		return exception.ErrInternalServerError
+	}
+
+	return nil
+}
+
+func (uc *UserUsecaseImpl) List(ctx context.Context, request *model.SearchUserRequest) ([]*model.UserResponse, *model.PageMetadata, error) {
+	ctx, span := uc.Tracer.Start(ctx, "UserUsecase.List")
+	defer span.End()
+
+	if err := uc.Validate.Struct(request); err != nil {
+		uc.log(ctx).WithError(err).Error("failed validating request body")
+		return nil, nil, err
+	}
+
+	sort := request.Sort
+	if sort == "" {
+		sort = "id"
+	}
+
+	size := request.Size
+	if size == 0 {
+		size = defaultPageSize
+	}
+
+	search := &repository.UserSearch{
+		NamePrefix:     request.Name,
+		UsernamePrefix: request.Username,
+		CreatedFrom:    request.CreatedAfter,
+		CreatedTo:      request.CreatedBefore,
+		SortBy:         strings.TrimPrefix(sort, "-"),
+		Descending:     strings.HasPrefix(sort, "-"),
+	}
+
+	if request.Page > 0 {
+		search.Offset = (request.Page - 1) * size
+		search.Limit = size
+		search.WithTotal = true
+	} else {
+		if request.Cursor != "" {
+			after, err := decodeUserCursor(request.Cursor, sort)
+			if err != nil {
+				uc.log(ctx).WithError(err).Warn("invalid user cursor")
+				return nil, nil, exception.ErrCursorInvalid
+			}
+			search.After = after
+		}
+		// one extra user tells whether there is a next page
+		search.Limit = size + 1
+	}
+
+	users, total, err := uc.UserRepository.Search(ctx, search)
+	if err != nil {
+		uc.log(ctx).WithError(err).Error("failed search users")
+		return nil, nil, exception.ErrInternalServerError
+	}
+
+	paging := &model.PageMetadata{Size: size}
+	if request.Page > 0 {
+		paging.Page = request.Page
+		paging.TotalItem = total
+		paging.TotalPage = (total + int64(size) - 1) / int64(size)
+	} else if len(users) > size {
+		users = users[:size]
+		paging.NextCursor, err = encodeUserCursor(sort, &users[size-1])
+		if err != nil {
+			uc.log(ctx).WithError(err).Error("failed encode user cursor")
+			return nil, nil, exception.ErrInternalServerError
+		}
+	}
+
+	responses := make([]*model.UserResponse, len(users))
+	for i := range users {
+		responses[i] = mapper.ToUserResponse(&users[i])
+	}
+
+	return responses, paging, nil
+}
+
+// Delete soft deletes the current user and logs them out everywhere, Restore can undo it
+// within ACCOUNT_RESTORE_WINDOW.
+func (uc *UserUsecaseImpl) Delete(ctx context.Context, request *model.DeleteUserRequest) error {
+	ctx, span := uc.Tracer.Start(ctx, "UserUsecase.Delete")
+	defer span.End()
+
+	if err := uc.Validate.Struct(request); err != nil {
+		uc.log(ctx).WithError(err).Error("failed validating request body")
+		return err
+	}
+
+	user, err := uc.UserRepository.FindByID(ctx, request.ID)
+	if err != nil {
+		uc.log(ctx).WithError(err).Error("failed find user by id")
+		return exception.ErrUserNotFound
+	}
+
+	if err := uc.comparePassword(ctx, user.Password, request.Password); err != nil {
+		uc.log(ctx).WithError(err).Error("failed to compare hashedPassword and password")
+		return exception.ErrUserPasswordNotMatch
+	}
+
+	if err := uc.UserRepository.Delete(ctx, user); err != nil {
+		uc.log(ctx).WithError(err).Error("failed delete user from database")
+		return exception.ErrInternalServerError
+	}
+
+	return uc.revokeUserTokens(ctx, user.ID)
+}
+
+// Restore undoes a deletion, it is guarded by the login throttle because it checks the password.
+func (uc *UserUsecaseImpl) Restore(ctx context.Context, request *model.RestoreUserRequest) (*model.UserResponse, error) {
+	ctx, span := uc.Tracer.Start(ctx, "UserUsecase.Restore")
+	defer span.End()
+
+	if err := uc.Validate.Struct(request); err != nil {
+		uc.log(ctx).WithError(err).Error("failed validating request body")
+		return nil, err
+	}
+	request.Username = infrastructure.CanonicalUsername(request.Username)
+
+	throttles := uc.loginThrottles(request.Username, request.IPAddress)
+	if err := uc.checkLoginThrottles(ctx, throttles); err != nil {
+		return nil, err
+	}
+
+	user, err := uc.UserRepository.FindDeletedByUsername(ctx, request.Username)
+	if err != nil {
+		uc.log(ctx).WithError(err).Error("failed find deleted user by username")
+		uc.addLoginFailure(ctx, throttles)
+		return nil, exception.ErrUserNotFound
+	}
+
+	restoreWindow := uc.Config.Account.RestoreWindow
+	if time.Since(user.DeletedAt.Time) > restoreWindow {
+		uc.log(ctx).Warn("restore attempt after the restore window")
+		return nil, exception.ErrUserNotFound
+	}
+
+	if err := uc.comparePassword(ctx, user.Password, request.Password); err != nil {
+		uc.log(ctx).WithError(err).Error("failed to compare hashedPassword and password")
+		uc.addLoginFailure(ctx, throttles)
+		return nil, exception.ErrUserPasswordNotMatch
+	}
+
+	if err := uc.UserRepository.Restore(ctx, user); err != nil {
+		uc.log(ctx).WithError(err).Error("failed restore user to database")
+		return nil, exception.ErrInternalServerError
+	}
+
+	if err := uc.LoginAttemptStore.Reset(ctx, usernameThrottleKey(request.Username)); err != nil {
+		uc.log(ctx).WithError(err).Error("failed reset login attempts")
+	}
+
+	return mapper.ToUserResponse(user), nil
+}
+
+// PurgeDeleted hard deletes users that were deleted longer than ACCOUNT_PURGE_RETENTION ago.
+func (uc *UserUsecaseImpl) PurgeDeleted(ctx context.Context) (int64, error) {
+	ctx, span := uc.Tracer.Start(ctx, "UserUsecase.PurgeDeleted")
+	defer span.End()
+
+	retention := uc.Config.Account.PurgeRetention
+
+	purged, err := uc.UserRepository.PurgeDeleted(ctx, time.Now().Add(-retention))
+	if err != nil {
+		uc.log(ctx).WithError(err).Error("failed purge deleted users")
+		return 0, exception.ErrInternalServerError
+	}
+
+	return purged, nil
+}
+
+func (uc *UserUsecaseImpl) findUserAndRole(ctx context.Context, request *model.UserRoleRequest) (*domain.User, *domain.Role, error) {
+	user, err := uc.UserRepository.FindByUsername(ctx, infrastructure.CanonicalUsername(request.Username))
+	if err != nil {
+		uc.log(ctx).WithError(err).Error("failed find user by username")
+		return nil, nil, exception.ErrUserNotFound
+	}
+
+	role, err := uc.RoleRepository.FindByName(ctx, request.Role)
+	if err != nil {
+		uc.log(ctx).WithError(err).Error("failed find role by name")
+		return nil, nil, exception.ErrRoleNotFound
+	}
+
+	return user, role, nil
+}
+
+func (uc *UserUsecaseImpl) useSecondFactor(ctx context.Context, userTOTP *domain.UserTOTP, code string) (bool, error) {
+	if step, ok := infrastructure.ValidateTOTP(userTOTP.Secret, code, time.Now()); ok {
+		// a code stays valid for its whole time window, remembering the step stops it being replayed
+		return uc.TwoFactorRepository.UseTOTPStep(ctx, userTOTP.UserID, step)
+	}
+
+	return uc.TwoFactorRepository.UseRecoveryCode(ctx, userTOTP.UserID, hashToken(normalizeRecoveryCode(code)), time.Now().UnixMilli())
+}
+
+// loginThrottle is a failed login counter, a client ip gets more attempts than a username
+// because many users can share one address.
+type loginThrottle struct {
+	key          string
+	freeAttempts int
+	threshold    int
+}
+
+// usernameThrottleKey counts the failures of every spelling of a username together.
+func usernameThrottleKey(username string) string {
+	return "username:" + infrastructure.CanonicalUsername(username)
+}
+
+func (uc *UserUsecaseImpl) loginThrottles(username, ipAddress string) []loginThrottle {
+	settings := uc.Config.Current().Auth.LoginThrottle
+	throttles := []loginThrottle{{
+		key:          usernameThrottleKey(username),
+		freeAttempts: settings.FreeAttempts,
+		threshold:    settings.LockoutThreshold,
+	}}
+
+	if ipAddress != "" {
+		throttles = append(throttles, loginThrottle{
+			key:          "ip:" + ipAddress,
+			freeAttempts: settings.IPFreeAttempts,
+			threshold:    settings.IPLockoutThreshold,
+		})
+	}
+
+	return throttles
+}
+
+func (uc *UserUsecaseImpl) checkLoginThrottles(ctx context.Context, throttles []loginThrottle) error {
+	now := time.Now()
+	lockoutDuration := uc.Config.Current().Auth.LoginThrottle.LockoutDuration
+
+	var retryAfter time.Duration
+	for _, throttle := range throttles {
+		attempt, err := uc.LoginAttemptStore.Find(ctx, throttle.key, now.Add(-lockoutDuration).UnixMilli())
+		if err != nil {
+			uc.log(ctx).WithError(err).Error("failed find login attempts")
+			return exception.ErrInternalServerError
+		}
+
+		blockedUntil := time.UnixMilli(attempt.LastFailedAt).Add(uc.loginDelay(attempt.Failures, throttle))
+		if wait := blockedUntil.Sub(now); wait > retryAfter {
+			retryAfter = wait
+		}
+	}
+
+	if retryAfter > 0 {
+		uc.log(ctx).Warn("login attempt while throttled")
+		return &exception.LockedError{RetryAfter: retryAfter}
+	}
+
+	return nil
+}
+
+func (uc *UserUsecaseImpl) addLoginFailure(ctx context.Context, throttles []loginThrottle) {
+	now := time.Now()
+	resetBefore := now.Add(-uc.Config.Current().Auth.LoginThrottle.LockoutDuration).UnixMilli()
+
+	for _, throttle := range throttles {
+		if _, err := uc.LoginAttemptStore.AddFailure(ctx, throttle.key, now.UnixMilli(), resetBefore); err != nil {
+			uc.log(ctx).WithError(err).Error("failed add login failure")
+		}
+	}
+}
+
+// loginDelay is how long a key waits after its last failure. The free attempts cost nothing, then
+// the delay doubles from LOGIN_THROTTLE_BASE_DELAY up to LOGIN_THROTTLE_MAX_DELAY until the threshold
+// is reached and the key is locked for LOGIN_LOCKOUT_DURATION.
+func (uc *UserUsecaseImpl) loginDelay(failures int, throttle loginThrottle) time.Duration {
+	settings := uc.Config.Current().Auth.LoginThrottle
+	if failures >= throttle.threshold {
+		return settings.LockoutDuration
+	}
+
+	exponent := failures - throttle.freeAttempts
+	if exponent < 0 {
+		return 0
+	}
+
+	maxDelay := settings.MaxDelay
+	delay := settings.BaseDelay
+	for ; exponent > 0 && delay < maxDelay; exponent-- {
+		delay *= 2
+	}
+
+	if delay > maxDelay {
+		return maxDelay
+	}
+	return delay
+}
+
+func (uc *UserUsecaseImpl) issueMFAChallenge(ctx context.Context, user *domain.User) (*model.TokenResponse, error) {
+	expire := uc.Config.Auth.MFATokenExpire
+	now := time.Now()
+
+	token, err := uc.TokenSigner.Sign(jwt.MapClaims{
+		"id":      user.ID,
+		"jti":     uuid.NewString(),
+		"purpose": mfaChallengePurpose,
+		"iat":     now.Unix(),
+		"exp":     now.Add(expire).Unix(),
+	})
+	if err != nil {
+		uc.log(ctx).WithError(err).Error("failed sign mfa token")
+		return nil, exception.ErrInternalServerError
+	}
+
+	return &model.TokenResponse{
+		ExpiresIn:   int64(expire.Seconds()),
+		MFARequired: true,
+		MFAToken:    token,
+	}, nil
+}
+
+func (uc *UserUsecaseImpl) queueEmailVerificationMail(ctx context.Context, user domain.User) {
+	if err := uc.MailQueue.Enqueue(ctx, func(ctx context.Context) { uc.sendEmailVerificationMail(ctx, user) }); err != nil {
+		uc.log(ctx).WithError(err).Error("failed queue email verification mail")
+	}
+}
+
+func (uc *UserUsecaseImpl) sendEmailVerificationMail(ctx context.Context, user domain.User) {
+	// runs on the mail queue, the span joins the trace of the request that queued it
+	ctx, span := uc.Tracer.Start(ctx, "UserUsecase.sendEmailVerificationMail")
+	defer span.End()
+
+	if user.Email == nil {
+		return
+	}
+
+	expire := uc.Config.Auth.EmailVerification.TokenExpire
+	now := time.Now()
+
+	token, err := uc.TokenSigner.Sign(jwt.MapClaims{
+		"id":      user.ID,
+		"email":   *user.Email,
+		"purpose": emailVerificationPurpose,
+		"iat":     now.Unix(),
+		"exp":     now.Add(expire).Unix(),
+	})
+	if err != nil {
+		uc.log(ctx).WithError(err).Error("failed sign email verification token")
+		return
+	}
+
+	link, err := withToken(uc.Config.Auth.EmailVerification.URL, token)
+	if err != nil {
+		uc.log(ctx).WithError(err).Error("failed parse email verification url")
+		return
+	}
+
+	message := &model.MailMessage{
+		To:      *user.Email,
+		Subject: "Verify your email address",
+		Body: fmt.Sprintf("Hi %s,\n\nPlease confirm your email address by opening the link below. It expires in %s.\n\n%s\n",
+			user.Name, expire, link),
+	}
+
+	if err := uc.Mailer.Send(ctx, message); err != nil {
+		uc.log(ctx).WithError(err).Error("failed send email verification mail")
+	}
+}
+
+func (uc *UserUsecaseImpl) sendPasswordResetMail(ctx context.Context, username string) {
+	ctx, span := uc.Tracer.Start(ctx, "UserUsecase.sendPasswordResetMail")
+	defer span.End()
+
+	user, err := uc.UserRepository.FindByUsername(ctx, infrastructure.CanonicalUsername(username))
+	if err != nil {
+		uc.log(ctx).WithError(err).Warn("password reset requested for unknown user")
+		return
+	}
+
+	if user.Email == nil {
+		uc.log(ctx).Warn("password reset requested for user without email")
+		return
+	}
+
+	rawToken, err := generateRandomToken()
+	if err != nil {
+		uc.log(ctx).WithError(err).Error("failed generate password reset token")
+		return
+	}
+
+	now := time.Now()
+
+	// only the most recently requested link stays valid
+	if err := uc.PasswordResetTokenRepository.MarkUsedByUserID(ctx, user.ID, now.UnixMilli()); err != nil {
+		uc.log(ctx).WithError(err).Error("failed invalidate previous password reset tokens")
+		return
+	}
+
+	passwordResetToken := new(domain.PasswordResetToken)
+	passwordResetToken.UserID = user.ID
+	passwordResetToken.TokenHash = hashToken(rawToken)
+	passwordResetToken.ExpiresAt = now.Add(uc.Config.Auth.PasswordReset.TokenExpire).UnixMilli()
+
+	if err := uc.PasswordResetTokenRepository.Create(ctx, passwordResetToken); err != nil {
+		uc.log(ctx).WithError(err).Error("failed create password reset token to database")
+		return
+	}
+
+	link, err := withToken(uc.Config.Auth.PasswordReset.URL, rawToken)
+	if err != nil {
+		uc.log(ctx).WithError(err).Error("failed parse password reset url")
+		return
+	}
+
+	message := &model.MailMessage{
+		To:      *user.Email,
+		Subject: "Reset your password",
+		Body: fmt.Sprintf("Hi %s,\n\nUse the link below to choose a new password. It expires in %s.\n\n%s\n\n"+
+			"If you did not ask for a password reset you can ignore this email.\n",
+			user.Name, uc.Config.Auth.PasswordReset.TokenExpire, link),
+	}
+
+	if err := uc.Mailer.Send(ctx, message); err != nil {
+		uc.log(ctx).WithError(err).Error("failed send password reset mail")
+	}
+}
+
+func (uc *UserUsecaseImpl) revokeUserTokens(ctx context.Context, userID uint) error {
+	now := time.Now().UnixMilli()
+
+	if err := uc.TokenRevocationStore.RevokeAllByUserID(ctx, userID, now); err != nil {
+		uc.log(ctx).WithError(err).Error("failed revoke all user tokens")
+		return exception.ErrInternalServerError
+	}
+
+	if err := uc.RefreshTokenRepository.RevokeByUserID(ctx, userID, now); err != nil {
+		uc.log(ctx).WithError(err).Error("failed revoke user refresh tokens")
+		return exception.ErrInternalServerError
+	}
+
+	return nil
+}
+
+// log returns the request scoped logger carried by ctx, or the usecase logger outside of a request.
+func (uc *UserUsecaseImpl) log(ctx context.Context) *logrus.Entry {
+	return infrastructure.LoggerFromContext(ctx, uc.Logger)
+}
+
+// hashPassword and comparePassword run bcrypt in their own span, it is often the slowest part of a request.
+func (uc *UserUsecaseImpl) hashPassword(ctx context.Context, password string) ([]byte, error) {
+	_, span := uc.Tracer.Start(ctx, "bcrypt.GenerateFromPassword")
+	defer span.End()
+
+	return bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+}
+
+func (uc *UserUsecaseImpl) comparePassword(ctx context.Context, hashedPassword, password string) error {
+	_, span := uc.Tracer.Start(ctx, "bcrypt.CompareHashAndPassword")
+	defer span.End()
+
+	return bcrypt.CompareHashAndPassword([]byte(hashedPassword), []byte(password))
+}
+
+// checkPasswordBreach refuses a new password listed in the breach corpus.
+func (uc *UserUsecaseImpl) checkPasswordBreach(ctx context.Context, password string) error {
+	ctx, span := uc.Tracer.Start(ctx, "PasswordBreachChecker.IsBreached")
+	defer span.End()
+
+	breached, err := uc.PasswordBreachChecker.IsBreached(ctx, password)
+	if err != nil {
+		uc.log(ctx).WithError(err).Error("failed check password breach")
+		return exception.ErrInternalServerError
+	}
+
+	if breached {
+		uc.log(ctx).Warn("password found in breach corpus")
+		return exception.ErrPasswordBreached
+	}
+
+	return nil
+}
+
+// issueLoginToken starts a new session once every factor was checked and counts the successful login.
+func (uc *UserUsecaseImpl) issueLoginToken(ctx context.Context, user *domain.User) (*model.TokenResponse, error) {
+	response, err := uc.issueToken(ctx, user, uuid.NewString())
+	if err != nil {
+		return nil, err
+	}
+
+	// only the username counter is cleared, a valid login must not hide guessing from the same ip
+	if err := uc.LoginAttemptStore.Reset(ctx, usernameThrottleKey(user.Username)); err != nil {
+		uc.log(ctx).WithError(err).Error("failed reset login attempts")
+	}
+
+	uc.Metrics.LoginSucceeded()
+	return response, nil
+}
+
+// issueToken signs a new access token and stores a new refresh token belonging to the given rotation family.
+func (uc *UserUsecaseImpl) issueToken(ctx context.Context, user *domain.User, familyID string) (*model.TokenResponse, error) {
+	// refresh, mfa and restore all end here, so a locked user cannot get new tokens on any path
+	if user.LockedAt != 0 {
+		uc.log(ctx).Warn("token requested for locked account")
+		return nil, exception.ErrAccountLocked
+	}
+
+	accessTokenExpire := uc.Config.Auth.JWT.AccessTokenExpire
+	refreshTokenExpire := uc.Config.Auth.JWT.RefreshTokenExpire
+
+	roles, err := uc.RoleRepository.FindNamesByUserID(ctx, user.ID)
+	if err != nil {
+		uc.log(ctx).WithError(err).Error("failed find roles by user id")
+		return nil, exception.ErrInternalServerError
+	}
+
+	if roles == nil {
+		roles = []string{}
+	}
+
+	now := time.Now()
+	claims := jwt.MapClaims{
+		"id":       user.ID,
+		"username": user.Username,
+		"roles":    roles,
+		"jti":      uuid.NewString(),
+		"sid":      familyID,
+		"iat":      now.Unix(),
+		"iat_ms":   now.UnixMilli(),
+		"exp":      now.Add(accessTokenExpire).Unix(),
+	}
+
+	token, err := uc.TokenSigner.Sign(claims)
+	if err != nil {
+		uc.log(ctx).WithError(err).Error("failed sign token")
+		return nil, exception.ErrInternalServerError
+	}
+
+	rawRefreshToken, err := generateRandomToken()
+	if err != nil {
+		uc.log(ctx).WithError(err).Error("failed generate refresh token")
+		return nil, exception.ErrInternalServerError
+	}
+
+	refreshToken := new(domain.RefreshToken)
+	refreshToken.UserID = user.ID
+	refreshToken.FamilyID = familyID
+	refreshToken.TokenHash = hashToken(rawRefreshToken)
+	refreshToken.ExpiresAt = now.Add(refreshTokenExpire).UnixMilli()
+
+	if err := uc.RefreshTokenRepository.Create(ctx, refreshToken); err != nil {
+		uc.log(ctx).WithError(err).Error("failed create refresh token to database")
+		return nil, exception.ErrInternalServerError
+	}
+
+	tokenResponse := &model.TokenResponse{
+		AccessToken:  token,
+		TokenType:    "Bearer",
+		ExpiresIn:    int64(accessTokenExpire.Seconds()),
+		RefreshToken: rawRefreshToken,
+	}
+
+	return tokenResponse, nil
+}
+
+func (uc *UserUsecaseImpl) revokeRefreshTokenFamily(ctx context.Context, familyID string, now int64) {
+	uc.log(ctx).WithField("family_id", familyID).Warn("refresh token reuse detected, revoking token family")
+
+	if err := uc.RefreshTokenRepository.RevokeFamily(ctx, familyID, now); err != nil {
+		uc.log(ctx).WithError(err).Error("failed revoke refresh token family")
+	}
+}
+
+func generateRandomToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// userCursor is sent to clients as opaque base64, it is bound to the sort it was created for.
+type userCursor struct {
+	Sort  string `json:"sort"`
+	Value any    `json:"value,omitempty"`
+	ID    uint   `json:"id"`
+}
+
+func encodeUserCursor(sort string, user *domain.User) (string, error) {
+	cursor := &userCursor{Sort: sort, ID: user.ID}
+	switch strings.TrimPrefix(sort, "-") {
+	case "name":
+		cursor.Value = user.Name
+	case "username":
+		cursor.Value = user.Username
+	case "created_at":
+		cursor.Value = user.CreatedAt
+	}
+
+	data, err := json.Marshal(cursor)
+	if err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(data), nil
+}
+
+func decodeUserCursor(encoded, sort string) (*repository.UserSearchCursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, err
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+
+	cursor := new(userCursor)
+	if err := decoder.Decode(cursor); err != nil {
+		return nil, err
+	}
+
+	if cursor.Sort != sort {
+		return nil, fmt.Errorf("cursor was created for sort %q", cursor.Sort)
+	}
+
+	after := &repository.UserSearchCursor{ID: cursor.ID}
+	switch strings.TrimPrefix(sort, "-") {
+	case "name", "username":
+		value, ok := cursor.Value.(string)
+		if !ok {
+			return nil, errors.New("cursor value is not a string")
+		}
+		after.Value = value
+	case "created_at":
+		number, ok := cursor.Value.(json.Number)
+		if !ok {
+			return nil, errors.New("cursor value is not a number")
+		}
+		value, err := number.Int64()
+		if err != nil {
+			return nil, err
+		}
+		after.Value = value
+	}
+
+	return after, nil
+}
+
+// withToken appends the token as a query parameter of the link sent by mail.
+func withToken(rawURL, token string) (string, error) {
+	link, err := url.Parse(rawURL)
+	if err != nil {
+		return "", err
+	}
+
+	query := link.Query()
+	query.Set("token", token)
+	link.RawQuery = query.Encode()
+
+	return link.String(), nil
+}
+
+// generateRecoveryCode returns a code such as "k3v9q-x2m7p", short enough to be typed by hand.
+func generateRecoveryCode() (string, error) {
+	b := make([]byte, 10)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+
+	// 32 symbols without i, l and o so the modulo below is unbiased and codes are easy to read
+	const alphabet = "abcdefghjkmnpqrstuvwxyz123456789"
+	code := make([]byte, len(b))
+	for i := range b {
+		code[i] = alphabet[int(b[i])%len(alphabet)]
+	}
+
+	return string(code[:5]) + "-" + string(code[5:]), nil
+}
+
+func normalizeRecoveryCode(code string) string {
+	return strings.ToLower(strings.NewReplacer("-", "", " ", "").Replace(code))
+}
+
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
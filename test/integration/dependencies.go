@@ -0,0 +1,27 @@
+package integration
+
+import (
+	"github.com/Ikhlashmulya/golang-clean-architecture-project-structure/config"
+	"github.com/Ikhlashmulya/golang-clean-architecture-project-structure/internal/delivery"
+	"github.com/Ikhlashmulya/golang-clean-architecture-project-structure/internal/infrastructure"
+	"github.com/Ikhlashmulya/golang-clean-architecture-project-structure/internal/repository"
+	"github.com/Ikhlashmulya/golang-clean-architecture-project-structure/internal/usecase"
+	"github.com/golang-migrate/migrate/v4"
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+// dependencies is the graph the e2e suite runs against, built by initializeDependencies.
+type dependencies struct {
+	Config         *config.Config
+	Log            *logrus.Logger
+	Lifecycle      *infrastructure.Lifecycle
+	Server         *delivery.Server
+	DB             *gorm.DB
+	Migrate        *migrate.Migrate
+	UserRepository repository.UserRepository
+	RoleRepository repository.RoleRepository
+	TokenSigner    infrastructure.TokenSigner
+	Mailer         *infrastructure.InMemoryMailer
+	UserUsecase    usecase.UserUsecase
+}
@@ -4,32 +4,15 @@ import (
 	"testing"
 
 	"github.com/Ikhlashmulya/golang-clean-architecture-project-structure/config"
-	"github.com/Ikhlashmulya/golang-clean-architecture-project-structure/internal/delivery/http/handler"
-	"github.com/Ikhlashmulya/golang-clean-architecture-project-structure/internal/delivery/http/middleware"
-	"github.com/Ikhlashmulya/golang-clean-architecture-project-structure/internal/delivery/http/route"
-	"github.com/Ikhlashmulya/golang-clean-architecture-project-structure/internal/domain"
 	"github.com/Ikhlashmulya/golang-clean-architecture-project-structure/internal/infrastructure"
-	"github.com/Ikhlashmulya/golang-clean-architecture-project-structure/internal/repository"
-	"github.com/Ikhlashmulya/golang-clean-architecture-project-structure/internal/usecase"
-	"github.com/go-playground/validator/v10"
 	"github.com/gofiber/fiber/v2"
-	"github.com/sirupsen/logrus"
-	"github.com/spf13/viper"
 	"github.com/stretchr/testify/suite"
-	"gorm.io/gorm"
 )
 
 type e2eTestSuite struct {
 	suite.Suite
-	Config         *viper.Viper
-	App            *fiber.App
-	DB             *gorm.DB
-	Log            *logrus.Logger
-	Validate       *validator.Validate
-	UserRepository repository.UserRepository
-	UserUsecase    usecase.UserUsecase
-	UserHandler    *handler.UserHandler
-	AuthMiddleware fiber.Handler
+	*dependencies
+	App *fiber.App
 }
 
 func TestE2eSuite(t *testing.T) {
@@ -37,22 +20,25 @@ func TestE2eSuite(t *testing.T) {
 }
 
 func (s *e2eTestSuite) SetupSuite() {
-	s.Config = config.New()
-	s.DB = infrastructure.NewGorm(s.Config)
-	s.Log = infrastructure.NewLogger(s.Config)
-	s.App = infrastructure.NewFiber(s.Config)
-	s.Validate = infrastructure.NewValidator(s.Config)
-	s.UserRepository = repository.NewUserRepository(s.DB)
-	s.UserUsecase = usecase.NewUserUsecase(s.UserRepository, s.Log, s.Validate, s.Config)
-	s.UserHandler = handler.NewUserHandler(s.UserUsecase, s.Log)
-	s.AuthMiddleware = middleware.NewAuth(s.UserUsecase, s.Log)
-	route.RegisterRoute(s.App, s.UserHandler, s.AuthMiddleware)
+	config := config.New()
+	dependencies, err := initializeDependencies(config, infrastructure.NewLogger(config))
+	s.Require().NoError(err)
+	s.dependencies = dependencies
+	s.App = s.Server.App
+}
+
+func (s *e2eTestSuite) TearDownSuite() {
+	sourceErr, databaseErr := s.Migrate.Close()
+	s.Require().NoError(sourceErr)
+	s.Require().NoError(databaseErr)
+	s.Require().NoError(s.Lifecycle.Stop())
 }
 
 func (s *e2eTestSuite) SetupTest() {
-	s.Require().NoError(s.DB.Migrator().AutoMigrate(&domain.User{}))
+	s.Mailer.Reset()
+	s.Require().NoError(s.Migrate.Up())
 }
 
 func (s *e2eTestSuite) TearDownTest() {
-	s.Require().NoError(s.DB.Migrator().DropTable("users"))
+	s.Require().NoError(s.Migrate.Down())
 }
@@ -6,8 +6,13 @@ import (
 	"io"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
+	"regexp"
 	"strings"
+	"time"
 
+	"github.com/Ikhlashmulya/golang-clean-architecture-project-structure/internal/domain"
+	"github.com/Ikhlashmulya/golang-clean-architecture-project-structure/internal/infrastructure"
 	"github.com/Ikhlashmulya/golang-clean-architecture-project-structure/internal/model"
 )
 
@@ -15,7 +20,8 @@ func (s *e2eTestSuite) TestUserRegisterSuccess() {
 	requestBody := &model.RegisterUserRequest{
 		Name:     "John Doe",
 		Username: "johndoe",
-		Password: "johndoe123",
+		Email:    "johndoe@example.com",
+		Password: "Correct-Horse-42",
 	}
 
 	bodyJSON, err := json.Marshal(requestBody)
@@ -42,6 +48,7 @@ func (s *e2eTestSuite) TestUserRegisterFailedValidation() {
 	requestBody := &model.RegisterUserRequest{
 		Name:     "",
 		Username: "",
+		Email:    "",
 		Password: "",
 	}
 
@@ -62,6 +69,8 @@ func (s *e2eTestSuite) TestUserRegisterFailedValidation() {
 	err = json.Unmarshal(bytes, &responseBody)
 	s.Assert().NoError(err)
 
+	s.Assert().Equal("application/problem+json", response.Header.Get("Content-Type"))
+	s.Assert().Equal("VALIDATION_FAILED", responseBody["code"])
 	s.Assert().NotEmpty(responseBody["errors"])
 }
 
@@ -71,7 +80,8 @@ func (s *e2eTestSuite) TestUserRegisterFailedUserAlreadyExists() {
 	requestBody := &model.RegisterUserRequest{
 		Name:     "John Doe",
 		Username: "johndoe",
-		Password: "johndoe123",
+		Email:    "johndoe@example.com",
+		Password: "Correct-Horse-42",
 	}
 
 	bodyJSON, err := json.Marshal(requestBody)
@@ -91,7 +101,37 @@ func (s *e2eTestSuite) TestUserRegisterFailedUserAlreadyExists() {
 	err = json.Unmarshal(bytes, &responseBody)
 	s.Assert().NoError(err)
 
-	s.Assert().NotEmpty(responseBody["errors"])
+	s.Assert().Equal("application/problem+json", response.Header.Get("Content-Type"))
+	s.Assert().Equal("USERNAME_TAKEN", responseBody["code"])
+}
+
+func (s *e2eTestSuite) TestUserRegisterFailedUsernameCollision() {
+	s.TestUserRegisterSuccess()
+
+	bodyJSON, err := json.Marshal(&model.RegisterUserRequest{
+		Name:     "John Doe",
+		Username: "JohnDoe",
+		Email:    "john.doe@example.com",
+		Password: "Correct-Horse-42",
+	})
+	s.Assert().NoError(err)
+
+	request := httptest.NewRequest(http.MethodPost, "/api/users", strings.NewReader(string(bodyJSON)))
+	request.Header.Add("content-type", "application/json")
+
+	response, err := s.App.Test(request)
+	s.Assert().NoError(err)
+	s.Assert().Equal(http.StatusBadRequest, response.StatusCode)
+
+	bytes, err := io.ReadAll(response.Body)
+	s.Assert().NoError(err)
+
+	responseBody := new(model.ProblemResponse)
+	s.Assert().NoError(json.Unmarshal(bytes, responseBody))
+	s.Assert().Equal("USERNAME_TAKEN", responseBody.Code)
+
+	_, err = s.UserUsecase.Login(context.Background(), &model.LoginUserRequest{Username: "JOHNDOE", Password: "Correct-Horse-42"})
+	s.Assert().NoError(err)
 }
 
 func (s *e2eTestSuite) TestUserLoginSuccess() {
@@ -99,7 +139,7 @@ func (s *e2eTestSuite) TestUserLoginSuccess() {
 
 	requestBody := &model.LoginUserRequest{
 		Username: "johndoe",
-		Password: "johndoe123",
+		Password: "Correct-Horse-42",
 	}
 
 	bodyJSON, err := json.Marshal(requestBody)
@@ -128,7 +168,7 @@ func (s *e2eTestSuite) TestUserLoginFailedUserNotFound() {
 
 	requestBody := &model.LoginUserRequest{
 		Username: "wrongjohndoe",
-		Password: "johndoe123",
+		Password: "Correct-Horse-42",
 	}
 
 	bodyJSON, err := json.Marshal(requestBody)
@@ -148,7 +188,8 @@ func (s *e2eTestSuite) TestUserLoginFailedUserNotFound() {
 	err = json.Unmarshal(bytes, &responseBody)
 	s.Assert().NoError(err)
 
-	s.Assert().NotEmpty(responseBody["errors"])
+	s.Assert().Equal("application/problem+json", response.Header.Get("Content-Type"))
+	s.Assert().Equal("USER_NOT_FOUND", responseBody["code"])
 }
 
 func (s *e2eTestSuite) TestUserLoginFailedPasswordNotMatch() {
@@ -176,7 +217,65 @@ func (s *e2eTestSuite) TestUserLoginFailedPasswordNotMatch() {
 	err = json.Unmarshal(bytes, &responseBody)
 	s.Assert().NoError(err)
 
-	s.Assert().NotEmpty(responseBody["errors"])
+	s.Assert().Equal("application/problem+json", response.Header.Get("Content-Type"))
+	s.Assert().Equal("PASSWORD_MISMATCH", responseBody["code"])
+}
+
+func (s *e2eTestSuite) TestUserLoginFailedThrottled() {
+	s.TestUserRegisterSuccess()
+
+	bodyJSON, err := json.Marshal(&model.LoginUserRequest{Username: "johndoe", Password: "wrongpassword"})
+	s.Assert().NoError(err)
+
+	login := func() *http.Response {
+		request := httptest.NewRequest(http.MethodPost, "/api/users/_login", strings.NewReader(string(bodyJSON)))
+		request.Header.Add("content-type", "application/json")
+
+		response, err := s.App.Test(request)
+		s.Assert().NoError(err)
+
+		return response
+	}
+
+	for i := 0; i < s.Config.Auth.LoginThrottle.FreeAttempts; i++ {
+		s.Assert().Equal(http.StatusBadRequest, login().StatusCode)
+	}
+
+	response := login()
+	s.Assert().Equal(http.StatusTooManyRequests, response.StatusCode)
+	s.Assert().NotEmpty(response.Header.Get("Retry-After"))
+
+	err = s.UserUsecase.UnlockUser(context.Background(), &model.UnlockUserRequest{Username: "johndoe"})
+	s.Assert().NoError(err)
+
+	s.Assert().Equal(http.StatusBadRequest, login().StatusCode)
+}
+
+func (s *e2eTestSuite) TestUserLoginFailedLocked() {
+	s.TestUserRegisterSuccess()
+
+	err := s.UserUsecase.LockUser(context.Background(), &model.LockUserRequest{Username: "johndoe"})
+	s.Assert().NoError(err)
+
+	bodyJSON, err := json.Marshal(&model.LoginUserRequest{Username: "johndoe", Password: "Correct-Horse-42"})
+	s.Assert().NoError(err)
+
+	login := func() *http.Response {
+		request := httptest.NewRequest(http.MethodPost, "/api/users/_login", strings.NewReader(string(bodyJSON)))
+		request.Header.Add("content-type", "application/json")
+
+		response, err := s.App.Test(request)
+		s.Assert().NoError(err)
+
+		return response
+	}
+
+	s.Assert().Equal(http.StatusForbidden, login().StatusCode)
+
+	err = s.UserUsecase.UnlockUser(context.Background(), &model.UnlockUserRequest{Username: "johndoe"})
+	s.Assert().NoError(err)
+
+	s.Assert().Equal(http.StatusOK, login().StatusCode)
 }
 
 func (s *e2eTestSuite) TestUserLoginFailedValidation() {
@@ -204,6 +303,8 @@ func (s *e2eTestSuite) TestUserLoginFailedValidation() {
 	err = json.Unmarshal(bytes, &responseBody)
 	s.Assert().NoError(err)
 
+	s.Assert().Equal("application/problem+json", response.Header.Get("Content-Type"))
+	s.Assert().Equal("VALIDATION_FAILED", responseBody["code"])
 	s.Assert().NotEmpty(responseBody["errors"])
 }
 
@@ -246,7 +347,8 @@ func (s *e2eTestSuite) TestUserCurrentFailedUnauthorized() {
 	err = json.Unmarshal(bytes, &responseBody)
 	s.Assert().NoError(err)
 
-	s.Assert().NotEmpty(responseBody["errors"])
+	s.Assert().Equal("application/problem+json", response.Header.Get("Content-Type"))
+	s.Assert().Equal("UNAUTHORIZED", responseBody["code"])
 }
 
 func (s *e2eTestSuite) TestUserUpdateSuccess() {
@@ -254,7 +356,7 @@ func (s *e2eTestSuite) TestUserUpdateSuccess() {
 
 	requestBody := &model.UpdateUserRequest{
 		Name:     "John Doe Update",
-		Password: "johndoeupdate",
+		Password: "Staple-Battery-88",
 	}
 
 	bodyJSON, err := json.Marshal(requestBody)
@@ -278,10 +380,21 @@ func (s *e2eTestSuite) TestUserUpdateSuccess() {
 	s.Assert().Equal(requestBody.Name, responseBody.Data.Name)
 }
 
+func (s *e2eTestSuite) TestUserCurrentFailedBearerWithoutToken() {
+	for _, authorization := range []string{"Bearer", "Bearer ", "Bearer a b"} {
+		request := httptest.NewRequest(http.MethodGet, "/api/users/_current", nil)
+		request.Header.Add("Authorization", authorization)
+
+		response, err := s.App.Test(request)
+		s.Assert().NoError(err)
+		s.Assert().Equal(http.StatusUnauthorized, response.StatusCode)
+	}
+}
+
 func (s *e2eTestSuite) TestUserUpdateFailedUnauthorized() {
 	requestBody := &model.UpdateUserRequest{
 		Name:     "John Doe Update",
-		Password: "johndoeupdate",
+		Password: "Staple-Battery-88",
 	}
 
 	bodyJSON, err := json.Marshal(requestBody)
@@ -301,13 +414,631 @@ func (s *e2eTestSuite) TestUserUpdateFailedUnauthorized() {
 	err = json.Unmarshal(bytes, &responseBody)
 	s.Assert().NoError(err)
 
-	s.Assert().NotEmpty(responseBody["errors"])
+	s.Assert().Equal("application/problem+json", response.Header.Get("Content-Type"))
+	s.Assert().Equal("UNAUTHORIZED", responseBody["code"])
+}
+
+func (s *e2eTestSuite) TestUserRefreshSuccess() {
+	s.TestUserRegisterSuccess()
+	tokenResponse, err := s.UserUsecase.Login(context.Background(), &model.LoginUserRequest{Username: "johndoe", Password: "Correct-Horse-42"})
+	s.Assert().NoError(err)
+
+	response := s.refresh(tokenResponse.RefreshToken)
+	s.Assert().Equal(http.StatusOK, response.StatusCode)
+
+	bytes, err := io.ReadAll(response.Body)
+	s.Assert().NoError(err)
+
+	responseBody := new(model.WebResponse[*model.TokenResponse])
+	err = json.Unmarshal(bytes, responseBody)
+	s.Assert().NoError(err)
+
+	s.Assert().NotEmpty(responseBody.Data.AccessToken)
+	s.Assert().NotEmpty(responseBody.Data.ExpiresIn)
+	s.Assert().NotEmpty(responseBody.Data.RefreshToken)
+	s.Assert().NotEqual(tokenResponse.RefreshToken, responseBody.Data.RefreshToken)
+}
+
+func (s *e2eTestSuite) TestUserRefreshFailedReuseDetected() {
+	s.TestUserRegisterSuccess()
+	tokenResponse, err := s.UserUsecase.Login(context.Background(), &model.LoginUserRequest{Username: "johndoe", Password: "Correct-Horse-42"})
+	s.Assert().NoError(err)
+
+	response := s.refresh(tokenResponse.RefreshToken)
+	s.Assert().Equal(http.StatusOK, response.StatusCode)
+
+	bytes, err := io.ReadAll(response.Body)
+	s.Assert().NoError(err)
+
+	rotated := new(model.WebResponse[*model.TokenResponse])
+	err = json.Unmarshal(bytes, rotated)
+	s.Assert().NoError(err)
+
+	// replaying the old token must fail and revoke the token it was rotated into
+	response = s.refresh(tokenResponse.RefreshToken)
+	s.Assert().Equal(http.StatusUnauthorized, response.StatusCode)
+
+	response = s.refresh(rotated.Data.RefreshToken)
+	s.Assert().Equal(http.StatusUnauthorized, response.StatusCode)
+}
+
+func (s *e2eTestSuite) TestUserLogoutSuccess() {
+	token := s.GetTokenUser()
+
+	request := httptest.NewRequest(http.MethodDelete, "/api/users/_current/sessions/_current", nil)
+	request.Header.Add("Authorization", "Bearer "+token)
+
+	response, err := s.App.Test(request)
+	s.Assert().NoError(err)
+	s.Assert().Equal(http.StatusOK, response.StatusCode)
+
+	request = httptest.NewRequest(http.MethodGet, "/api/users/_current", nil)
+	request.Header.Add("Authorization", "Bearer "+token)
+
+	response, err = s.App.Test(request)
+	s.Assert().NoError(err)
+	s.Assert().Equal(http.StatusUnauthorized, response.StatusCode)
+}
+
+func (s *e2eTestSuite) TestUserLogoutAllSuccess() {
+	token := s.GetTokenUser()
+	otherSession, err := s.UserUsecase.Login(context.Background(), &model.LoginUserRequest{Username: "johndoe", Password: "Correct-Horse-42"})
+	s.Assert().NoError(err)
+
+	request := httptest.NewRequest(http.MethodDelete, "/api/users/_current/sessions", nil)
+	request.Header.Add("Authorization", "Bearer "+token)
+
+	response, err := s.App.Test(request)
+	s.Assert().NoError(err)
+	s.Assert().Equal(http.StatusOK, response.StatusCode)
+
+	request = httptest.NewRequest(http.MethodGet, "/api/users/_current", nil)
+	request.Header.Add("Authorization", "Bearer "+otherSession.AccessToken)
+
+	response, err = s.App.Test(request)
+	s.Assert().NoError(err)
+	s.Assert().Equal(http.StatusUnauthorized, response.StatusCode)
+
+	response = s.refresh(otherSession.RefreshToken)
+	s.Assert().Equal(http.StatusUnauthorized, response.StatusCode)
+}
+
+func (s *e2eTestSuite) TestUserResetPasswordSuccess() {
+	s.TestUserRegisterSuccess()
+
+	bodyJSON, err := json.Marshal(&model.ForgotPasswordRequest{Username: "johndoe"})
+	s.Assert().NoError(err)
+
+	request := httptest.NewRequest(http.MethodPost, "/api/users/_forgot-password", strings.NewReader(string(bodyJSON)))
+	request.Header.Add("content-type", "application/json")
+
+	response, err := s.App.Test(request)
+	s.Assert().NoError(err)
+	s.Assert().Equal(http.StatusAccepted, response.StatusCode)
+
+	token := s.GetMailToken("johndoe@example.com", "Reset your password")
+
+	bodyJSON, err = json.Marshal(&model.ResetPasswordRequest{Token: token, Password: "Battery-Staple-77"})
+	s.Assert().NoError(err)
+
+	request = httptest.NewRequest(http.MethodPost, "/api/users/_reset-password", strings.NewReader(string(bodyJSON)))
+	request.Header.Add("content-type", "application/json")
+
+	response, err = s.App.Test(request)
+	s.Assert().NoError(err)
+	s.Assert().Equal(http.StatusOK, response.StatusCode)
+
+	_, err = s.UserUsecase.Login(context.Background(), &model.LoginUserRequest{Username: "johndoe", Password: "Battery-Staple-77"})
+	s.Assert().NoError(err)
+
+	// the token is single use
+	request = httptest.NewRequest(http.MethodPost, "/api/users/_reset-password", strings.NewReader(string(bodyJSON)))
+	request.Header.Add("content-type", "application/json")
+
+	response, err = s.App.Test(request)
+	s.Assert().NoError(err)
+	s.Assert().Equal(http.StatusBadRequest, response.StatusCode)
+}
+
+func (s *e2eTestSuite) TestUserForgotPasswordUnknownUser() {
+	bodyJSON, err := json.Marshal(&model.ForgotPasswordRequest{Username: "wrongjohndoe"})
+	s.Assert().NoError(err)
+
+	request := httptest.NewRequest(http.MethodPost, "/api/users/_forgot-password", strings.NewReader(string(bodyJSON)))
+	request.Header.Add("content-type", "application/json")
+
+	response, err := s.App.Test(request)
+	s.Assert().NoError(err)
+	s.Assert().Equal(http.StatusAccepted, response.StatusCode)
+}
+
+func (s *e2eTestSuite) TestUserVerifyEmailSuccess() {
+	s.TestUserRegisterSuccess()
+	token := s.GetMailToken("johndoe@example.com", "Verify your email address")
+
+	bodyJSON, err := json.Marshal(&model.VerifyEmailRequest{Token: token})
+	s.Assert().NoError(err)
+
+	request := httptest.NewRequest(http.MethodPost, "/api/users/_verify-email", strings.NewReader(string(bodyJSON)))
+	request.Header.Add("content-type", "application/json")
+
+	response, err := s.App.Test(request)
+	s.Assert().NoError(err)
+	s.Assert().Equal(http.StatusOK, response.StatusCode)
+
+	bytes, err := io.ReadAll(response.Body)
+	s.Assert().NoError(err)
+
+	responseBody := new(model.WebResponse[*model.UserResponse])
+	err = json.Unmarshal(bytes, responseBody)
+	s.Assert().NoError(err)
+
+	s.Assert().Equal("johndoe@example.com", responseBody.Data.Email)
+	s.Assert().NotEmpty(responseBody.Data.EmailVerifiedAt)
+}
+
+func (s *e2eTestSuite) TestUserVerifyEmailFailedInvalidToken() {
+	bodyJSON, err := json.Marshal(&model.VerifyEmailRequest{Token: "wrongtoken"})
+	s.Assert().NoError(err)
+
+	request := httptest.NewRequest(http.MethodPost, "/api/users/_verify-email", strings.NewReader(string(bodyJSON)))
+	request.Header.Add("content-type", "application/json")
+
+	response, err := s.App.Test(request)
+	s.Assert().NoError(err)
+	s.Assert().Equal(http.StatusBadRequest, response.StatusCode)
+}
+
+// GetMailToken waits for the latest mail with the given subject and returns the token from its link.
+func (s *e2eTestSuite) TestUserTwoFactorSuccess() {
+	token := s.GetTokenUser()
+
+	request := httptest.NewRequest(http.MethodPost, "/api/users/_current/mfa/totp", nil)
+	request.Header.Add("Authorization", "Bearer "+token)
+
+	response, err := s.App.Test(request)
+	s.Assert().NoError(err)
+	s.Assert().Equal(http.StatusCreated, response.StatusCode)
+
+	bytes, err := io.ReadAll(response.Body)
+	s.Assert().NoError(err)
+
+	enrollment := new(model.WebResponse[*model.TOTPEnrollmentResponse])
+	err = json.Unmarshal(bytes, enrollment)
+	s.Assert().NoError(err)
+
+	code, err := infrastructure.TOTPCode(enrollment.Data.Secret, time.Now())
+	s.Assert().NoError(err)
+
+	request = httptest.NewRequest(http.MethodPost, "/api/users/_current/mfa/totp/_confirm", strings.NewReader(`{"code":"`+code+`"}`))
+	request.Header.Add("content-type", "application/json")
+	request.Header.Add("Authorization", "Bearer "+token)
+
+	response, err = s.App.Test(request)
+	s.Assert().NoError(err)
+	s.Assert().Equal(http.StatusOK, response.StatusCode)
+
+	bytes, err = io.ReadAll(response.Body)
+	s.Assert().NoError(err)
+
+	recoveryCodes := new(model.WebResponse[*model.RecoveryCodesResponse])
+	err = json.Unmarshal(bytes, recoveryCodes)
+	s.Assert().NoError(err)
+	s.Assert().Len(recoveryCodes.Data.RecoveryCodes, 10)
+
+	challenge, err := s.UserUsecase.Login(context.Background(), &model.LoginUserRequest{Username: "johndoe", Password: "Correct-Horse-42"})
+	s.Assert().NoError(err)
+	s.Assert().True(challenge.MFARequired)
+	s.Assert().Empty(challenge.AccessToken)
+
+	bodyJSON, err := json.Marshal(&model.LoginMFARequest{MFAToken: challenge.MFAToken, Code: recoveryCodes.Data.RecoveryCodes[0]})
+	s.Assert().NoError(err)
+
+	request = httptest.NewRequest(http.MethodPost, "/api/users/_login/mfa", strings.NewReader(string(bodyJSON)))
+	request.Header.Add("content-type", "application/json")
+
+	response, err = s.App.Test(request)
+	s.Assert().NoError(err)
+	s.Assert().Equal(http.StatusOK, response.StatusCode)
+
+	bytes, err = io.ReadAll(response.Body)
+	s.Assert().NoError(err)
+
+	tokenResponse := new(model.WebResponse[*model.TokenResponse])
+	err = json.Unmarshal(bytes, tokenResponse)
+	s.Assert().NoError(err)
+	s.Assert().NotEmpty(tokenResponse.Data.AccessToken)
+	s.Assert().NotEmpty(tokenResponse.Data.RefreshToken)
+
+	// a recovery code can only be used once
+	challenge, err = s.UserUsecase.Login(context.Background(), &model.LoginUserRequest{Username: "johndoe", Password: "Correct-Horse-42"})
+	s.Assert().NoError(err)
+
+	_, err = s.UserUsecase.LoginMFA(context.Background(), &model.LoginMFARequest{MFAToken: challenge.MFAToken, Code: recoveryCodes.Data.RecoveryCodes[0]})
+	s.Assert().Error(err)
+}
+
+func (s *e2eTestSuite) TestUserDeleteAndRestoreSuccess() {
+	token := s.GetTokenUser()
+
+	request := httptest.NewRequest(http.MethodDelete, "/api/users/_current", strings.NewReader(`{"password":"Correct-Horse-42"}`))
+	request.Header.Add("content-type", "application/json")
+	request.Header.Add("Authorization", "Bearer "+token)
+
+	response, err := s.App.Test(request)
+	s.Assert().NoError(err)
+	s.Assert().Equal(http.StatusOK, response.StatusCode)
+
+	request = httptest.NewRequest(http.MethodGet, "/api/users/_current", nil)
+	request.Header.Add("Authorization", "Bearer "+token)
+
+	response, err = s.App.Test(request)
+	s.Assert().NoError(err)
+	s.Assert().Equal(http.StatusUnauthorized, response.StatusCode)
+
+	_, err = s.UserUsecase.Login(context.Background(), &model.LoginUserRequest{Username: "johndoe", Password: "Correct-Horse-42"})
+	s.Assert().Error(err)
+
+	request = httptest.NewRequest(http.MethodPost, "/api/users/_restore", strings.NewReader(`{"username":"johndoe","password":"Correct-Horse-42"}`))
+	request.Header.Add("content-type", "application/json")
+
+	response, err = s.App.Test(request)
+	s.Assert().NoError(err)
+	s.Assert().Equal(http.StatusOK, response.StatusCode)
+
+	_, err = s.UserUsecase.Login(context.Background(), &model.LoginUserRequest{Username: "johndoe", Password: "Correct-Horse-42"})
+	s.Assert().NoError(err)
+}
+
+func (s *e2eTestSuite) TestUserRegisterAfterPurgeSuccess() {
+	s.TestUserRegisterSuccess()
+
+	user, err := s.UserRepository.FindByUsername(context.Background(), "johndoe")
+	s.Assert().NoError(err)
+	s.Assert().NoError(s.UserRepository.Delete(context.Background(), user))
+
+	// the username stays reserved until the account is purged
+	_, err = s.UserUsecase.Register(context.Background(), &model.RegisterUserRequest{
+		Name: "John Doe", Username: "johndoe", Email: "john@example.com", Password: "Correct-Horse-42",
+	})
+	s.Assert().Error(err)
+
+	retention := s.Config.Account.PurgeRetention
+	err = s.DB.Model(&domain.User{}).Unscoped().Where("id = ?", user.ID).Update("deleted_at", time.Now().Add(-retention-time.Hour)).Error
+	s.Assert().NoError(err)
+
+	purged, err := s.UserUsecase.PurgeDeleted(context.Background())
+	s.Assert().NoError(err)
+	s.Assert().Equal(int64(1), purged)
+
+	s.TestUserRegisterSuccess()
+}
+
+func (s *e2eTestSuite) GetMailToken(to, subject string) string {
+	var body string
+	s.Require().Eventually(func() bool {
+		messages := s.Mailer.Messages()
+		for i := len(messages) - 1; i >= 0; i-- {
+			if messages[i].To == to && messages[i].Subject == subject {
+				body = messages[i].Body
+				return true
+			}
+		}
+		return false
+	}, 5*time.Second, 50*time.Millisecond)
+
+	link, err := url.Parse(regexp.MustCompile(`https?://\S+`).FindString(body))
+	s.Require().NoError(err)
+
+	token := link.Query().Get("token")
+	s.Require().NotEmpty(token)
+
+	return token
+}
+
+func (s *e2eTestSuite) refresh(refreshToken string) *http.Response {
+	bodyJSON, err := json.Marshal(&model.RefreshTokenRequest{RefreshToken: refreshToken})
+	s.Assert().NoError(err)
+
+	request := httptest.NewRequest(http.MethodPost, "/api/users/_refresh", strings.NewReader(string(bodyJSON)))
+	request.Header.Add("content-type", "application/json")
+
+	response, err := s.App.Test(request)
+	s.Assert().NoError(err)
+
+	return response
 }
 
 func (s *e2eTestSuite) GetTokenUser() string {
 	s.TestUserRegisterSuccess()
-	tokenResponse, err := s.UserUsecase.Login(context.Background(), &model.LoginUserRequest{Username: "johndoe", Password: "johndoe123"})
+	tokenResponse, err := s.UserUsecase.Login(context.Background(), &model.LoginUserRequest{Username: "johndoe", Password: "Correct-Horse-42"})
 	s.Assert().NoError(err)
 
 	return tokenResponse.AccessToken
 }
+
+func (s *e2eTestSuite) TestAdminGrantRoleSuccess() {
+	token := s.GetTokenAdmin()
+
+	s.Assert().NoError(s.UserRepository.Create(context.Background(), &domain.User{Name: "Jane Doe", Username: "janedoe", Password: "secret"}))
+
+	request := httptest.NewRequest(http.MethodPut, "/api/admin/users/janedoe/roles/admin", nil)
+	request.Header.Add("Authorization", "Bearer "+token)
+
+	response, err := s.App.Test(request)
+	s.Assert().NoError(err)
+	s.Assert().Equal(http.StatusOK, response.StatusCode)
+
+	user, err := s.UserRepository.FindByUsername(context.Background(), "janedoe")
+	s.Assert().NoError(err)
+
+	roles, err := s.RoleRepository.FindNamesByUserID(context.Background(), user.ID)
+	s.Assert().NoError(err)
+	s.Assert().Equal([]string{"admin"}, roles)
+}
+
+func (s *e2eTestSuite) TestAdminGrantRoleFailedForbidden() {
+	token := s.GetTokenUser()
+
+	request := httptest.NewRequest(http.MethodPut, "/api/admin/users/johndoe/roles/admin", nil)
+	request.Header.Add("Authorization", "Bearer "+token)
+
+	response, err := s.App.Test(request)
+	s.Assert().NoError(err)
+	s.Assert().Equal(http.StatusForbidden, response.StatusCode)
+}
+
+func (s *e2eTestSuite) TestAdminUnlockSuccess() {
+	token := s.GetTokenAdmin()
+
+	request := httptest.NewRequest(http.MethodPost, "/api/admin/users/johndoe/_unlock", nil)
+	request.Header.Add("Authorization", "Bearer "+token)
+
+	response, err := s.App.Test(request)
+	s.Assert().NoError(err)
+	s.Assert().Equal(http.StatusOK, response.StatusCode)
+}
+
+func (s *e2eTestSuite) TestAdminListUsersSuccess() {
+	token := s.GetTokenAdmin()
+
+	for _, username := range []string{"janedoe", "jimdoe", "alice"} {
+		s.Assert().NoError(s.UserRepository.Create(context.Background(), &domain.User{Name: username, Username: username, Password: "secret"}))
+	}
+
+	list := func(query string) *model.WebResponse[[]*model.UserResponse] {
+		request := httptest.NewRequest(http.MethodGet, "/api/admin/users?"+query, nil)
+		request.Header.Add("Authorization", "Bearer "+token)
+
+		response, err := s.App.Test(request)
+		s.Assert().NoError(err)
+		s.Assert().Equal(http.StatusOK, response.StatusCode)
+
+		bytes, err := io.ReadAll(response.Body)
+		s.Assert().NoError(err)
+
+		responseBody := new(model.WebResponse[[]*model.UserResponse])
+		err = json.Unmarshal(bytes, responseBody)
+		s.Assert().NoError(err)
+
+		return responseBody
+	}
+
+	responseBody := list("username=j&sort=-username&page=1&size=2")
+	s.Assert().Len(responseBody.Data, 2)
+	s.Assert().Equal("johndoe", responseBody.Data[0].Username)
+	s.Assert().Equal("jimdoe", responseBody.Data[1].Username)
+	s.Assert().Equal(int64(3), responseBody.Paging.TotalItem)
+	s.Assert().Equal(int64(2), responseBody.Paging.TotalPage)
+
+	responseBody = list("sort=username&size=3")
+	s.Assert().Len(responseBody.Data, 3)
+	s.Assert().Equal("alice", responseBody.Data[0].Username)
+	s.Assert().NotEmpty(responseBody.Paging.NextCursor)
+
+	responseBody = list("sort=username&size=3&cursor=" + responseBody.Paging.NextCursor)
+	s.Assert().Len(responseBody.Data, 1)
+	s.Assert().Equal("johndoe", responseBody.Data[0].Username)
+	s.Assert().Empty(responseBody.Paging.NextCursor)
+}
+
+func (s *e2eTestSuite) TestAdminListUsersFailedForbidden() {
+	token := s.GetTokenUser()
+
+	request := httptest.NewRequest(http.MethodGet, "/api/admin/users", nil)
+	request.Header.Add("Authorization", "Bearer "+token)
+
+	response, err := s.App.Test(request)
+	s.Assert().NoError(err)
+	s.Assert().Equal(http.StatusForbidden, response.StatusCode)
+}
+
+func (s *e2eTestSuite) GetTokenAdmin() string {
+	s.TestUserRegisterSuccess()
+	err := s.UserUsecase.GrantRole(context.Background(), &model.UserRoleRequest{Username: "johndoe", Role: "admin"})
+	s.Assert().NoError(err)
+
+	tokenResponse, err := s.UserUsecase.Login(context.Background(), &model.LoginUserRequest{Username: "johndoe", Password: "Correct-Horse-42"})
+	s.Assert().NoError(err)
+
+	return tokenResponse.AccessToken
+}
+
+func (s *e2eTestSuite) TestJWKSSuccess() {
+	request := httptest.NewRequest(http.MethodGet, "/.well-known/jwks.json", nil)
+
+	response, err := s.App.Test(request)
+	s.Assert().NoError(err)
+	s.Assert().Equal(http.StatusOK, response.StatusCode)
+
+	bytes, err := io.ReadAll(response.Body)
+	s.Assert().NoError(err)
+
+	responseBody := new(model.JSONWebKeySet)
+	err = json.Unmarshal(bytes, responseBody)
+	s.Assert().NoError(err)
+
+	s.Assert().NotNil(responseBody.Keys)
+}
+
+func (s *e2eTestSuite) TestCORSAllowedOrigin() {
+	request := httptest.NewRequest(http.MethodOptions, "/api/users", nil)
+	request.Header.Add("Origin", "http://localhost:5173")
+	request.Header.Add("Access-Control-Request-Method", http.MethodPost)
+
+	response, err := s.App.Test(request)
+	s.Assert().NoError(err)
+	s.Assert().Equal(http.StatusNoContent, response.StatusCode)
+	s.Assert().Equal("http://localhost:5173", response.Header.Get("Access-Control-Allow-Origin"))
+}
+
+func (s *e2eTestSuite) TestCORSFailedUnknownOrigin() {
+	request := httptest.NewRequest(http.MethodGet, "/.well-known/jwks.json", nil)
+	request.Header.Add("Origin", "https://evil.example.com")
+
+	response, err := s.App.Test(request)
+	s.Assert().NoError(err)
+	s.Assert().Equal(http.StatusOK, response.StatusCode)
+	s.Assert().Empty(response.Header.Get("Access-Control-Allow-Origin"))
+}
+
+func (s *e2eTestSuite) TestHealthzSuccess() {
+	request := httptest.NewRequest(http.MethodGet, "/healthz", nil)
+
+	response, err := s.App.Test(request)
+	s.Assert().NoError(err)
+	s.Assert().Equal(http.StatusOK, response.StatusCode)
+
+	bytes, err := io.ReadAll(response.Body)
+	s.Assert().NoError(err)
+
+	responseBody := new(model.HealthResponse)
+	s.Assert().NoError(json.Unmarshal(bytes, responseBody))
+	s.Assert().Equal("ok", responseBody.Status)
+}
+
+func (s *e2eTestSuite) TestReadyzSuccess() {
+	request := httptest.NewRequest(http.MethodGet, "/readyz", nil)
+
+	response, err := s.App.Test(request)
+	s.Assert().NoError(err)
+	s.Assert().Equal(http.StatusOK, response.StatusCode)
+
+	bytes, err := io.ReadAll(response.Body)
+	s.Assert().NoError(err)
+
+	responseBody := new(model.HealthResponse)
+	s.Assert().NoError(json.Unmarshal(bytes, responseBody))
+	s.Assert().Equal("ok", responseBody.Status)
+	s.Assert().Equal("ok", responseBody.Checks["database"].Status)
+}
+
+func (s *e2eTestSuite) TestMetricsSuccess() {
+	s.TestUserRegisterSuccess()
+
+	for _, path := range []string{"/johndoe/unknown", "/unknown/path"} {
+		response, err := s.App.Test(httptest.NewRequest(http.MethodGet, path, nil))
+		s.Assert().NoError(err)
+		s.Assert().Equal(http.StatusNotFound, response.StatusCode)
+	}
+
+	request := httptest.NewRequest(http.MethodGet, "/metrics", nil)
+	response, err := s.App.Test(request)
+	s.Assert().NoError(err)
+	s.Assert().Equal(http.StatusOK, response.StatusCode)
+
+	bytes, err := io.ReadAll(response.Body)
+	s.Assert().NoError(err)
+
+	body := string(bytes)
+	s.Assert().Contains(body, `http_requests_total{method="POST",route="/api/users",status="201"}`)
+	s.Assert().Contains(body, `http_request_duration_seconds_bucket{method="POST",route="/api/users",status="201",le="+Inf"}`)
+	s.Assert().Contains(body, `http_requests_total{method="GET",route="unmatched",status="404"}`)
+	s.Assert().NotContains(body, "johndoe/unknown")
+	s.Assert().Contains(body, "user_registrations_total")
+	s.Assert().Contains(body, "go_sql_max_open_connections")
+}
+
+func (s *e2eTestSuite) TestRequestIDSuccess() {
+	request := httptest.NewRequest(http.MethodGet, "/healthz", nil)
+	request.Header.Set("X-Request-ID", "3f6c1e0a-request")
+
+	response, err := s.App.Test(request)
+	s.Assert().NoError(err)
+	s.Assert().Equal("3f6c1e0a-request", response.Header.Get("X-Request-ID"))
+
+	response, err = s.App.Test(httptest.NewRequest(http.MethodGet, "/healthz", nil))
+	s.Assert().NoError(err)
+	s.Assert().NotEmpty(response.Header.Get("X-Request-ID"))
+}
+
+func (s *e2eTestSuite) TestProblemFailedValidation() {
+	bodyJSON, err := json.Marshal(&model.RegisterUserRequest{Name: "John Doe", Username: "johndoe", Email: "johndoe", Password: "secret"})
+	s.Assert().NoError(err)
+
+	request := httptest.NewRequest(http.MethodPost, "/api/users", strings.NewReader(string(bodyJSON)))
+	request.Header.Add("content-type", "application/json")
+
+	response, err := s.App.Test(request)
+	s.Assert().NoError(err)
+	s.Assert().Equal(http.StatusBadRequest, response.StatusCode)
+	s.Assert().Equal("application/problem+json", response.Header.Get("Content-Type"))
+
+	bytes, err := io.ReadAll(response.Body)
+	s.Assert().NoError(err)
+
+	responseBody := new(model.ProblemResponse)
+	s.Assert().NoError(json.Unmarshal(bytes, responseBody))
+	s.Assert().Equal("VALIDATION_FAILED", responseBody.Code)
+	s.Assert().Equal(http.StatusBadRequest, responseBody.Status)
+	s.Assert().Equal("/api/users", responseBody.Instance)
+	s.Assert().NotEmpty(responseBody.Type)
+	s.Assert().Contains(responseBody.Errors, model.ProblemViolation{
+		Field: "email", Rule: "email", Message: "email must be a valid email address",
+	})
+	s.Assert().Contains(responseBody.Errors, model.ProblemViolation{
+		Field: "password", Rule: "min", Param: "8", Message: "password must be at least 8 characters in length",
+	})
+}
+
+func (s *e2eTestSuite) TestProblemTranslatedSuccess() {
+	bodyJSON, err := json.Marshal(&model.RegisterUserRequest{Name: "John Doe", Username: "johndoe", Email: "johndoe", Password: "secret"})
+	s.Assert().NoError(err)
+
+	request := httptest.NewRequest(http.MethodPost, "/api/users", strings.NewReader(string(bodyJSON)))
+	request.Header.Add("content-type", "application/json")
+	request.Header.Add("accept-language", "id-ID,id;q=0.9,en;q=0.8")
+
+	response, err := s.App.Test(request)
+	s.Assert().NoError(err)
+	s.Assert().Equal(http.StatusBadRequest, response.StatusCode)
+	s.Assert().Equal("id", response.Header.Get("Content-Language"))
+
+	bytes, err := io.ReadAll(response.Body)
+	s.Assert().NoError(err)
+
+	responseBody := new(model.ProblemResponse)
+	s.Assert().NoError(json.Unmarshal(bytes, responseBody))
+	s.Assert().Equal("VALIDATION_FAILED", responseBody.Code)
+	s.Assert().Equal("request memiliki field yang tidak valid", responseBody.Detail)
+	s.Assert().Contains(responseBody.Errors, model.ProblemViolation{
+		Field: "email", Rule: "email", Message: "email harus berupa alamat email yang valid",
+	})
+}
+
+func (s *e2eTestSuite) TestProblemFailedMalformedBody() {
+	request := httptest.NewRequest(http.MethodPost, "/api/users", strings.NewReader(`{"username": `))
+	request.Header.Add("content-type", "application/json")
+
+	response, err := s.App.Test(request)
+	s.Assert().NoError(err)
+	s.Assert().Equal(http.StatusBadRequest, response.StatusCode)
+
+	bytes, err := io.ReadAll(response.Body)
+	s.Assert().NoError(err)
+
+	responseBody := new(model.ProblemResponse)
+	s.Assert().NoError(json.Unmarshal(bytes, responseBody))
+	s.Assert().Equal("MALFORMED_REQUEST", responseBody.Code)
+	s.Assert().NotContains(string(bytes), "unexpected end of JSON input")
+}
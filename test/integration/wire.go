@@ -0,0 +1,26 @@
+//go:build wireinject
+
+package integration
+
+import (
+	"github.com/Ikhlashmulya/golang-clean-architecture-project-structure/config"
+	"github.com/Ikhlashmulya/golang-clean-architecture-project-structure/internal/delivery"
+	"github.com/Ikhlashmulya/golang-clean-architecture-project-structure/internal/infrastructure"
+	"github.com/Ikhlashmulya/golang-clean-architecture-project-structure/internal/repository"
+	"github.com/Ikhlashmulya/golang-clean-architecture-project-structure/internal/usecase"
+	"github.com/google/wire"
+	"github.com/sirupsen/logrus"
+)
+
+// initializeDependencies wires the server like cmd/web, except that mails are kept in memory so tests can read them.
+func initializeDependencies(config *config.Config, logger *logrus.Logger) (*dependencies, error) {
+	wire.Build(
+		infrastructure.ProviderSet,
+		infrastructure.InMemoryMailerSet,
+		repository.ProviderSet,
+		usecase.ProviderSet,
+		delivery.ProviderSet,
+		wire.Struct(new(dependencies), "*"),
+	)
+	return nil, nil
+}
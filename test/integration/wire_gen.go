@@ -0,0 +1,88 @@
+// Code generated by Wire. DO NOT EDIT.
+
+//go:generate go run -mod=mod github.com/google/wire/cmd/wire
+//go:build !wireinject
+// +build !wireinject
+
+package integration
+
+import (
+	"github.com/Ikhlashmulya/golang-clean-architecture-project-structure/config"
+	"github.com/Ikhlashmulya/golang-clean-architecture-project-structure/internal/delivery"
+	"github.com/Ikhlashmulya/golang-clean-architecture-project-structure/internal/delivery/http/handler"
+	"github.com/Ikhlashmulya/golang-clean-architecture-project-structure/internal/delivery/http/middleware"
+	"github.com/Ikhlashmulya/golang-clean-architecture-project-structure/internal/delivery/worker"
+	"github.com/Ikhlashmulya/golang-clean-architecture-project-structure/internal/infrastructure"
+	"github.com/Ikhlashmulya/golang-clean-architecture-project-structure/internal/repository"
+	"github.com/Ikhlashmulya/golang-clean-architecture-project-structure/internal/usecase"
+	"github.com/sirupsen/logrus"
+)
+
+// Injectors from wire.go:
+
+// initializeDependencies wires the server like cmd/web, except that mails are kept in memory so tests can read them.
+func initializeDependencies(config2 *config.Config, logger *logrus.Logger) (*dependencies, error) {
+	lifecycle := infrastructure.NewLifecycle(config2, logger)
+	universalTranslator, err := infrastructure.NewTranslator()
+	if err != nil {
+		return nil, err
+	}
+	app := infrastructure.NewFiber(config2, universalTranslator, logger)
+	health := infrastructure.NewHealth(config2, lifecycle, logger)
+	metrics := infrastructure.NewMetrics()
+	tracerProvider, err := infrastructure.NewTracerProvider(config2, lifecycle)
+	if err != nil {
+		return nil, err
+	}
+	tracer := infrastructure.NewTracer(tracerProvider)
+	db, err := infrastructure.NewGorm(config2, lifecycle, health, metrics, tracer, logger)
+	if err != nil {
+		return nil, err
+	}
+	userRepository := repository.NewUserRepository(db)
+	refreshTokenRepository := repository.NewRefreshTokenRepository(db)
+	passwordResetTokenRepository := repository.NewPasswordResetTokenRepository(db)
+	twoFactorRepository := repository.NewTwoFactorRepository(db)
+	roleRepository := repository.NewRoleRepository(db)
+	tokenRevocationStore := repository.NewTokenRevocationStore(config2, db)
+	loginAttemptStore := repository.NewLoginAttemptStore(config2, db)
+	tokenSigner, err := infrastructure.NewTokenSigner(config2)
+	if err != nil {
+		return nil, err
+	}
+	inMemoryMailer := infrastructure.NewInMemoryMailer()
+	mailQueue := infrastructure.NewMailQueue(config2, lifecycle, logger)
+	passwordBreachChecker, err := infrastructure.NewPasswordBreachChecker(config2, lifecycle)
+	if err != nil {
+		return nil, err
+	}
+	validate, err := infrastructure.NewValidator(config2, universalTranslator)
+	if err != nil {
+		return nil, err
+	}
+	userUsecase := usecase.NewUserUsecase(userRepository, refreshTokenRepository, passwordResetTokenRepository, twoFactorRepository, roleRepository, tokenRevocationStore, loginAttemptStore, tokenSigner, inMemoryMailer, mailQueue, passwordBreachChecker, metrics, tracer, logger, validate, config2)
+	userHandler := handler.NewUserHandler(userUsecase, logger)
+	jwksHandler := handler.NewJWKSHandler(tokenSigner)
+	healthHandler := handler.NewHealthHandler(health)
+	v := middleware.NewAuth(userUsecase, logger)
+	userPurger := worker.NewUserPurger(userUsecase, logger, config2)
+	server := delivery.NewServer(app, config2, lifecycle, userHandler, jwksHandler, healthHandler, v, userPurger, metrics, tracerProvider, logger)
+	migrate, err := infrastructure.NewMigrate(config2, logger)
+	if err != nil {
+		return nil, err
+	}
+	integrationDependencies := &dependencies{
+		Config:         config2,
+		Log:            logger,
+		Lifecycle:      lifecycle,
+		Server:         server,
+		DB:             db,
+		Migrate:        migrate,
+		UserRepository: userRepository,
+		RoleRepository: roleRepository,
+		TokenSigner:    tokenSigner,
+		Mailer:         inMemoryMailer,
+		UserUsecase:    userUsecase,
+	}
+	return integrationDependencies, nil
+}
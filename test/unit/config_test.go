@@ -0,0 +1,193 @@
+package unit
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/Ikhlashmulya/golang-clean-architecture-project-structure/config"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+func setRequiredEnv(t *testing.T) {
+	t.Setenv("DB_HOST", "localhost")
+	t.Setenv("DB_PORT", "3306")
+	t.Setenv("DB_NAME", "go-rest-api")
+	t.Setenv("JWT_SECRET_KEY", "secretkey")
+	t.Setenv("EMAIL_VERIFICATION_URL", "http://localhost:3000/verify-email")
+	t.Setenv("PASSWORD_RESET_URL", "http://localhost:3000/reset-password")
+}
+
+func TestConfig(t *testing.T) {
+	t.Run("success env only with defaults", func(t *testing.T) {
+		setRequiredEnv(t)
+		t.Setenv("LOGIN_LOCKOUT_DURATION", "15m")
+		// an empty variable counts as unset, so the defaults apply
+		t.Setenv("APP_PORT", "")
+		t.Setenv("DB_DRIVER", "")
+		t.Setenv("JWT_ACCESS_TOKEN_EXPIRE", "")
+		t.Setenv("JWT_VERIFICATION_KEY_FILES", "old.pem, older.pem")
+
+		cfg, err := config.Load(filepath.Join(t.TempDir(), ".env"))
+		assert.NoError(t, err)
+		assert.Equal(t, 3000, cfg.App.Port)
+		assert.Equal(t, "mysql", cfg.DB.Driver)
+		assert.Equal(t, 2*time.Hour, cfg.Auth.JWT.AccessTokenExpire)
+		assert.Equal(t, 15*time.Minute, cfg.Auth.LoginThrottle.LockoutDuration)
+		assert.Equal(t, []string{"old.pem", "older.pem"}, cfg.Auth.JWT.VerificationKeyFiles)
+	})
+
+	t.Run("success env overrides file", func(t *testing.T) {
+		setRequiredEnv(t)
+		file := filepath.Join(t.TempDir(), ".env")
+		assert.NoError(t, os.WriteFile(file, []byte("APP_PORT=4000\nAPP_NAME=From File\n"), 0o600))
+		t.Setenv("APP_PORT", "5000")
+		t.Setenv("APP_NAME", "")
+
+		cfg, err := config.Load(file)
+		assert.NoError(t, err)
+		assert.Equal(t, 5000, cfg.App.Port)
+		assert.Equal(t, "From File", cfg.App.Name)
+	})
+
+	t.Run("success secret file", func(t *testing.T) {
+		setRequiredEnv(t)
+		secretFile := filepath.Join(t.TempDir(), "jwt_secret")
+		assert.NoError(t, os.WriteFile(secretFile, []byte("filesecret\n"), 0o600))
+		t.Setenv("JWT_SECRET_KEY", "")
+		t.Setenv("JWT_SECRET_KEY_FILE", secretFile)
+
+		cfg, err := config.Load("")
+		assert.NoError(t, err)
+		assert.Equal(t, "filesecret", cfg.Auth.JWT.SecretKey)
+	})
+
+	t.Run("failed secret and secret file", func(t *testing.T) {
+		setRequiredEnv(t)
+		t.Setenv("JWT_SECRET_KEY_FILE", filepath.Join(t.TempDir(), "jwt_secret"))
+
+		_, err := config.Load("")
+		assert.ErrorContains(t, err, "set either JWT_SECRET_KEY or JWT_SECRET_KEY_FILE")
+	})
+
+	t.Run("failed every invalid setting reported", func(t *testing.T) {
+		setRequiredEnv(t)
+		t.Setenv("APP_PORT", "70000")
+		t.Setenv("DB_DRIVER", "oracle")
+		t.Setenv("DB_NAME", "")
+		t.Setenv("JWT_SECRET_KEY", "")
+		t.Setenv("POOL_MAX", "many")
+		t.Setenv("APP_SHUTDOWN_READINESS_DELAY", "30")
+
+		_, err := config.Load("")
+		assert.ErrorContains(t, err, "APP_PORT")
+		assert.ErrorContains(t, err, "DB_DRIVER")
+		assert.ErrorContains(t, err, "DB_NAME")
+		assert.ErrorContains(t, err, "JWT_SECRET_KEY")
+		assert.ErrorContains(t, err, "POOL_MAX")
+		assert.ErrorContains(t, err, "APP_SHUTDOWN_READINESS_DELAY")
+	})
+}
+
+func TestConfigReload(t *testing.T) {
+	writeConfig := func(t *testing.T, file, content string) {
+		assert.NoError(t, os.WriteFile(file, []byte(content), 0o600))
+	}
+
+	setup := func(t *testing.T) (*config.Config, *config.Reloader, string) {
+		setRequiredEnv(t)
+		t.Setenv("APP_PORT", "")
+		t.Setenv("LOG_LEVEL", "")
+		t.Setenv("CORS_ALLOW_ORIGINS", "")
+		t.Setenv("LOGIN_THROTTLE_FREE_ATTEMPTS", "")
+
+		file := filepath.Join(t.TempDir(), ".env")
+		writeConfig(t, file, "APP_PORT=4000\nLOG_LEVEL=4\n")
+		cfg, err := config.Load(file)
+		assert.NoError(t, err)
+
+		return cfg, config.NewReloader(cfg, file, logrus.New()), file
+	}
+
+	t.Run("success safe settings applied", func(t *testing.T) {
+		cfg, reloader, file := setup(t)
+		changes := reloader.Subscribe()
+
+		writeConfig(t, file, "APP_PORT=4000\nLOG_LEVEL=5\nCORS_ALLOW_ORIGINS=https://example.com\nLOGIN_THROTTLE_FREE_ATTEMPTS=5\n")
+		assert.NoError(t, reloader.Reload())
+
+		change := <-changes
+		assert.ElementsMatch(t, []string{"LOG_LEVEL", "CORS_ALLOW_ORIGINS", "LOGIN_THROTTLE_FREE_ATTEMPTS"}, change.Keys)
+		assert.Same(t, cfg.Current(), change.Config)
+		assert.Equal(t, 5, cfg.Current().Log.Level)
+		assert.Equal(t, []string{"https://example.com"}, cfg.Current().CORS.AllowOrigins)
+		assert.Equal(t, 5, cfg.Current().Auth.LoginThrottle.FreeAttempts)
+		// the startup values are left untouched
+		assert.Equal(t, 4, cfg.Log.Level)
+	})
+
+	t.Run("success unsafe settings refused", func(t *testing.T) {
+		cfg, reloader, file := setup(t)
+		changes := reloader.Subscribe()
+
+		writeConfig(t, file, "APP_PORT=5000\nLOG_LEVEL=6\n")
+		assert.NoError(t, reloader.Reload())
+
+		change := <-changes
+		assert.Equal(t, []string{"LOG_LEVEL"}, change.Keys)
+		assert.Equal(t, 4000, cfg.Current().App.Port)
+		assert.Equal(t, 6, cfg.Current().Log.Level)
+	})
+
+	t.Run("success nothing reloadable changed", func(t *testing.T) {
+		cfg, reloader, file := setup(t)
+		changes := reloader.Subscribe()
+
+		writeConfig(t, file, "APP_PORT=5000\nLOG_LEVEL=4\n")
+		assert.NoError(t, reloader.Reload())
+
+		assert.Len(t, changes, 0)
+		assert.Same(t, cfg, cfg.Current())
+	})
+
+	t.Run("failed invalid configuration kept", func(t *testing.T) {
+		cfg, reloader, file := setup(t)
+
+		writeConfig(t, file, "APP_PORT=4000\nLOG_LEVEL=9\n")
+		assert.ErrorContains(t, reloader.Reload(), "LOG_LEVEL")
+		assert.Equal(t, 4, cfg.Current().Log.Level)
+	})
+
+	t.Run("success file watched", func(t *testing.T) {
+		cfg, reloader, file := setup(t)
+		changes := reloader.Subscribe()
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		watching := make(chan error)
+		go func() { watching <- reloader.Watch(ctx) }()
+
+		// the watcher may not be registered yet, keep writing until it notices
+		ticker := time.NewTicker(200 * time.Millisecond)
+		defer ticker.Stop()
+		timeout := time.After(5 * time.Second)
+		for done := false; !done; {
+			select {
+			case change := <-changes:
+				assert.Equal(t, []string{"LOG_LEVEL"}, change.Keys)
+				done = true
+			case <-ticker.C:
+				writeConfig(t, file, "APP_PORT=4000\nLOG_LEVEL=2\n")
+			case <-timeout:
+				t.Fatal("configuration file change not noticed")
+			}
+		}
+		assert.Equal(t, 2, cfg.Current().Log.Level)
+
+		cancel()
+		assert.NoError(t, <-watching)
+	})
+}
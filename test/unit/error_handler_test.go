@@ -0,0 +1,120 @@
+package unit
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/Ikhlashmulya/golang-clean-architecture-project-structure/config"
+	"github.com/Ikhlashmulya/golang-clean-architecture-project-structure/internal/exception"
+	"github.com/Ikhlashmulya/golang-clean-architecture-project-structure/internal/infrastructure"
+	"github.com/Ikhlashmulya/golang-clean-architecture-project-structure/internal/model"
+	"github.com/gofiber/fiber/v2"
+	"github.com/sirupsen/logrus"
+	"github.com/sirupsen/logrus/hooks/test"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestErrorHandler(t *testing.T) {
+	translator, err := infrastructure.NewTranslator()
+	assert.NoError(t, err)
+
+	problem := func(t *testing.T, handlerErr error, acceptLanguage string) (*http.Response, *model.ProblemResponse, *test.Hook) {
+		logger, hook := test.NewNullLogger()
+		app := fiber.New(fiber.Config{
+			ErrorHandler: exception.NewErrorHandler(func(ctx context.Context) *logrus.Entry {
+				return logrus.NewEntry(logger)
+			}, translator),
+		})
+		app.Get("/api/users/_current", func(c *fiber.Ctx) error { return handlerErr })
+
+		request := httptest.NewRequest(http.MethodGet, "/api/users/_current", nil)
+		request.Header.Set(fiber.HeaderAcceptLanguage, acceptLanguage)
+		response, err := app.Test(request)
+		assert.NoError(t, err)
+		assert.Equal(t, "application/problem+json", response.Header.Get(fiber.HeaderContentType))
+
+		bytes, err := io.ReadAll(response.Body)
+		assert.NoError(t, err)
+		responseBody := new(model.ProblemResponse)
+		assert.NoError(t, json.Unmarshal(bytes, responseBody))
+
+		return response, responseBody, hook
+	}
+
+	t.Run("success app error rendered", func(t *testing.T) {
+		response, body, hook := problem(t, exception.ErrUserNotFound, "")
+		assert.Equal(t, http.StatusNotFound, response.StatusCode)
+		assert.Equal(t, &model.ProblemResponse{
+			Type:     "urn:problem-type:user-not-found",
+			Title:    "Not Found",
+			Status:   http.StatusNotFound,
+			Detail:   "User is not found",
+			Instance: "/api/users/_current",
+			Code:     "USER_NOT_FOUND",
+		}, body)
+		assert.Empty(t, hook.AllEntries())
+	})
+
+	t.Run("success locked error sets retry after", func(t *testing.T) {
+		response, body, _ := problem(t, &exception.LockedError{RetryAfter: 1500 * time.Millisecond}, "")
+		assert.Equal(t, http.StatusTooManyRequests, response.StatusCode)
+		assert.Equal(t, "2", response.Header.Get(fiber.HeaderRetryAfter))
+		assert.Equal(t, "LOGIN_THROTTLED", body.Code)
+	})
+
+	t.Run("failed unknown error logged and not leaked", func(t *testing.T) {
+		response, body, hook := problem(t, errors.New("dial tcp 10.0.0.7:3306: connection refused"), "")
+		assert.Equal(t, http.StatusInternalServerError, response.StatusCode)
+		assert.Equal(t, "INTERNAL_ERROR", body.Code)
+		assert.NotContains(t, body.Detail, "10.0.0.7")
+		assert.Equal(t, logrus.ErrorLevel, hook.LastEntry().Level)
+		assert.EqualError(t, hook.LastEntry().Data[logrus.ErrorKey].(error), "dial tcp 10.0.0.7:3306: connection refused")
+	})
+
+	t.Run("success detail translated to accepted language", func(t *testing.T) {
+		response, body, _ := problem(t, exception.ErrUserNotFound, "fr-FR, id-ID;q=0.8, en;q=0.5")
+		assert.Equal(t, "id", response.Header.Get(fiber.HeaderContentLanguage))
+		assert.Equal(t, "Pengguna tidak ditemukan", body.Detail)
+		assert.Equal(t, "USER_NOT_FOUND", body.Code)
+	})
+
+	t.Run("success unsupported language falls back to english", func(t *testing.T) {
+		response, body, _ := problem(t, exception.ErrUserNotFound, "fr-FR")
+		assert.Equal(t, "en", response.Header.Get(fiber.HeaderContentLanguage))
+		assert.Equal(t, "User is not found", body.Detail)
+	})
+
+	t.Run("success violations named by json tag and translated", func(t *testing.T) {
+		validate, err := infrastructure.NewValidator(new(config.Config), translator)
+		assert.NoError(t, err)
+		validationErr := validate.Struct(&model.RegisterUserRequest{
+			Name: "John Doe", Username: "johndoe", Email: "johndoe", Password: "secret",
+		})
+
+		_, body, _ := problem(t, validationErr, "")
+		assert.Equal(t, "VALIDATION_FAILED", body.Code)
+		assert.Equal(t, []model.ProblemViolation{
+			{Field: "email", Rule: "email", Message: "email must be a valid email address"},
+			{Field: "password", Rule: "min", Param: "8", Message: "password must be at least 8 characters in length"},
+		}, body.Errors)
+
+		_, body, _ = problem(t, validationErr, "id")
+		assert.Equal(t, "request memiliki field yang tidak valid", body.Detail)
+		assert.Equal(t, "email harus berupa alamat email yang valid", body.Errors[0].Message)
+	})
+}
+
+func TestAppErrorIs(t *testing.T) {
+	violations := []exception.Violation{{Field: "Username", Rule: "required"}}
+	err := exception.ErrValidationFailed.WithViolations(violations)
+
+	assert.ErrorIs(t, err, exception.ErrValidationFailed)
+	assert.NotErrorIs(t, err, exception.ErrRequestMalformed)
+	assert.Empty(t, exception.ErrValidationFailed.Violations)
+}
@@ -0,0 +1,96 @@
+package unit
+
+import (
+	"context"
+	"errors"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/Ikhlashmulya/golang-clean-architecture-project-structure/config"
+	"github.com/Ikhlashmulya/golang-clean-architecture-project-structure/internal/infrastructure"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+func newHealth(lifecycle *infrastructure.Lifecycle) *infrastructure.Health {
+	cfg := new(config.Config)
+	cfg.App.HealthCheckTimeout = 50 * time.Millisecond
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+
+	return infrastructure.NewHealth(cfg, lifecycle, logger)
+}
+
+func TestHealthReady(t *testing.T) {
+	t.Run("success every check passes", func(t *testing.T) {
+		health := newHealth(newLifecycle(time.Second))
+		health.Register(infrastructure.HealthCheck{
+			Name:  "database",
+			Check: func(ctx context.Context) error { return nil },
+		})
+
+		response := health.Ready(context.Background())
+		assert.Equal(t, infrastructure.HealthStatusOK, response.Status)
+		assert.Equal(t, infrastructure.HealthStatusOK, response.Checks["database"].Status)
+		assert.Empty(t, response.Checks["database"].Error)
+	})
+
+	t.Run("failed check reported without details", func(t *testing.T) {
+		health := newHealth(newLifecycle(time.Second))
+		health.Register(infrastructure.HealthCheck{
+			Name:  "database",
+			Check: func(ctx context.Context) error { return nil },
+		})
+		health.Register(infrastructure.HealthCheck{
+			Name:  "smtp",
+			Check: func(ctx context.Context) error { return errors.New("dial tcp 10.0.0.3:587: connection refused") },
+		})
+
+		response := health.Ready(context.Background())
+		assert.Equal(t, infrastructure.HealthStatusFail, response.Status)
+		assert.Equal(t, infrastructure.HealthStatusOK, response.Checks["database"].Status)
+		assert.Equal(t, infrastructure.HealthStatusFail, response.Checks["smtp"].Status)
+		assert.Equal(t, "unavailable", response.Checks["smtp"].Error)
+	})
+
+	t.Run("failed check timeout", func(t *testing.T) {
+		health := newHealth(newLifecycle(time.Second))
+		health.Register(infrastructure.HealthCheck{
+			Name: "slow",
+			Check: func(ctx context.Context) error {
+				<-ctx.Done()
+				return ctx.Err()
+			},
+		})
+		health.Register(infrastructure.HealthCheck{
+			Name:    "slower",
+			Timeout: 100 * time.Millisecond,
+			Check: func(ctx context.Context) error {
+				<-ctx.Done()
+				return ctx.Err()
+			},
+		})
+
+		response := health.Ready(context.Background())
+		assert.Equal(t, infrastructure.HealthStatusFail, response.Status)
+		assert.Equal(t, "timeout", response.Checks["slow"].Error)
+		assert.GreaterOrEqual(t, response.Checks["slow"].LatencyMs, float64(50))
+		assert.GreaterOrEqual(t, response.Checks["slower"].LatencyMs, float64(100))
+	})
+
+	t.Run("failed shutting down", func(t *testing.T) {
+		lifecycle := newLifecycle(time.Second)
+		health := newHealth(lifecycle)
+		health.Register(infrastructure.HealthCheck{
+			Name:  "database",
+			Check: func(ctx context.Context) error { return nil },
+		})
+
+		assert.NoError(t, lifecycle.Stop())
+
+		response := health.Ready(context.Background())
+		assert.Equal(t, infrastructure.HealthStatusShuttingDown, response.Status)
+		assert.Empty(t, response.Checks)
+	})
+}
@@ -0,0 +1,118 @@
+package unit
+
+import (
+	"context"
+	"errors"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/Ikhlashmulya/golang-clean-architecture-project-structure/config"
+	"github.com/Ikhlashmulya/golang-clean-architecture-project-structure/internal/infrastructure"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+func newLifecycle(timeout time.Duration) *infrastructure.Lifecycle {
+	cfg := new(config.Config)
+	cfg.App.ShutdownTimeout = timeout
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+
+	return infrastructure.NewLifecycle(cfg, logger)
+}
+
+func TestLifecycle(t *testing.T) {
+	t.Run("success stopped in reverse order", func(t *testing.T) {
+		lifecycle := newLifecycle(time.Second)
+		var events []string
+		for _, name := range []string{"database", "worker", "http server"} {
+			name := name
+			lifecycle.Append(infrastructure.Hook{
+				Name: name,
+				OnStart: func(ctx context.Context) error {
+					events = append(events, "start "+name)
+					return nil
+				},
+				OnStop: func(ctx context.Context) error {
+					events = append(events, "stop "+name)
+					return nil
+				},
+			})
+		}
+
+		assert.NoError(t, lifecycle.Start(context.Background()))
+		assert.False(t, lifecycle.ShuttingDown())
+		assert.NoError(t, lifecycle.Stop())
+		assert.True(t, lifecycle.ShuttingDown())
+		assert.Equal(t, []string{
+			"start database", "start worker", "start http server",
+			"stop http server", "stop worker", "stop database",
+		}, events)
+	})
+
+	t.Run("success worker cancelled and awaited", func(t *testing.T) {
+		lifecycle := newLifecycle(time.Second)
+		finished := false
+		lifecycle.Go("worker", func(ctx context.Context) {
+			<-ctx.Done()
+			finished = true
+		})
+
+		assert.NoError(t, lifecycle.Start(context.Background()))
+		assert.NoError(t, lifecycle.Stop())
+		assert.True(t, finished)
+	})
+
+	t.Run("success not started hooks only closed", func(t *testing.T) {
+		lifecycle := newLifecycle(time.Second)
+		var events []string
+		lifecycle.Append(infrastructure.Hook{
+			Name:   "database",
+			OnStop: func(ctx context.Context) error { events = append(events, "stop database"); return nil },
+		})
+		lifecycle.Go("worker", func(ctx context.Context) { events = append(events, "run worker") })
+
+		assert.NoError(t, lifecycle.Stop())
+		assert.Equal(t, []string{"stop database"}, events)
+	})
+
+	t.Run("failed start stops started hooks", func(t *testing.T) {
+		lifecycle := newLifecycle(time.Second)
+		var events []string
+		lifecycle.Append(infrastructure.Hook{
+			Name:    "database",
+			OnStart: func(ctx context.Context) error { return nil },
+			OnStop:  func(ctx context.Context) error { events = append(events, "stop database"); return nil },
+		})
+		lifecycle.Append(infrastructure.Hook{
+			Name:    "http server",
+			OnStart: func(ctx context.Context) error { return errors.New("address already in use") },
+			OnStop:  func(ctx context.Context) error { events = append(events, "stop http server"); return nil },
+		})
+
+		err := lifecycle.Start(context.Background())
+		assert.ErrorContains(t, err, "error starting http server")
+		assert.Equal(t, []string{"stop database"}, events)
+	})
+
+	t.Run("failed worker exceeding timeout", func(t *testing.T) {
+		lifecycle := newLifecycle(50 * time.Millisecond)
+		release := make(chan struct{})
+		defer close(release)
+		lifecycle.Go("stuck worker", func(ctx context.Context) { <-release })
+
+		closed := false
+		lifecycle.Append(infrastructure.Hook{
+			Name:   "database",
+			OnStop: func(ctx context.Context) error { closed = true; return nil },
+		})
+
+		assert.NoError(t, lifecycle.Start(context.Background()))
+		err := lifecycle.Stop()
+		assert.ErrorContains(t, err, "stuck worker: did not stop in time")
+		assert.ErrorIs(t, err, context.DeadlineExceeded)
+		// a stuck component does not keep the others open
+		assert.True(t, closed)
+	})
+}
@@ -0,0 +1,170 @@
+package unit
+
+import (
+	"context"
+	"io"
+	"net"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/Ikhlashmulya/golang-clean-architecture-project-structure/config"
+	"github.com/Ikhlashmulya/golang-clean-architecture-project-structure/internal/infrastructure"
+	"github.com/Ikhlashmulya/golang-clean-architecture-project-structure/internal/model"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+func newMailQueue(lifecycle *infrastructure.Lifecycle, size int) *infrastructure.MailQueue {
+	cfg := new(config.Config)
+	cfg.Mail.QueueSize = size
+	cfg.Mail.QueueWorkers = 1
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+
+	return infrastructure.NewMailQueue(cfg, lifecycle, logger)
+}
+
+func TestMailQueue(t *testing.T) {
+	t.Run("success drained on stop", func(t *testing.T) {
+		lifecycle := newLifecycle(time.Second)
+		queue := newMailQueue(lifecycle, 10)
+
+		var sent atomic.Int32
+		for i := 0; i < 5; i++ {
+			assert.NoError(t, queue.Enqueue(ctx, func(ctx context.Context) {
+				time.Sleep(10 * time.Millisecond)
+				sent.Add(1)
+			}))
+		}
+
+		assert.NoError(t, lifecycle.Stop())
+		assert.Equal(t, int32(5), sent.Load())
+		assert.ErrorIs(t, queue.Enqueue(ctx, func(ctx context.Context) {}), infrastructure.ErrMailQueueClosed)
+	})
+
+	t.Run("success job keeps request values but not its cancellation", func(t *testing.T) {
+		lifecycle := newLifecycle(time.Second)
+		queue := newMailQueue(lifecycle, 1)
+
+		entry := logrus.NewEntry(logrus.New()).WithField("request_id", "42")
+		requestCtx, cancel := context.WithCancel(infrastructure.ContextWithLogger(ctx, entry))
+
+		done := make(chan error, 1)
+		assert.NoError(t, queue.Enqueue(requestCtx, func(ctx context.Context) {
+			time.Sleep(20 * time.Millisecond)
+			assert.Same(t, entry, infrastructure.LoggerFromContext(ctx, logrus.New()))
+			done <- ctx.Err()
+		}))
+		cancel()
+
+		assert.NoError(t, <-done)
+		assert.NoError(t, lifecycle.Stop())
+	})
+
+	t.Run("failed full", func(t *testing.T) {
+		lifecycle := newLifecycle(time.Second)
+		queue := newMailQueue(lifecycle, 1)
+
+		release := make(chan struct{})
+		started := make(chan struct{})
+		assert.NoError(t, queue.Enqueue(ctx, func(ctx context.Context) {
+			close(started)
+			<-release
+		}))
+		<-started
+
+		assert.NoError(t, queue.Enqueue(ctx, func(ctx context.Context) {}))
+		assert.ErrorIs(t, queue.Enqueue(ctx, func(ctx context.Context) {}), infrastructure.ErrMailQueueFull)
+
+		close(release)
+		assert.NoError(t, lifecycle.Stop())
+	})
+
+	t.Run("failed job cancelled after the shutdown timeout", func(t *testing.T) {
+		lifecycle := newLifecycle(50 * time.Millisecond)
+		queue := newMailQueue(lifecycle, 1)
+
+		finished := make(chan struct{})
+		assert.NoError(t, queue.Enqueue(ctx, func(ctx context.Context) {
+			<-ctx.Done()
+			close(finished)
+		}))
+
+		assert.Error(t, lifecycle.Stop())
+		select {
+		case <-finished:
+		default:
+			t.Error("job still running after the queue was closed")
+		}
+	})
+
+	t.Run("failed queued jobs dropped after the shutdown timeout", func(t *testing.T) {
+		lifecycle := newLifecycle(50 * time.Millisecond)
+		queue := newMailQueue(lifecycle, 1)
+
+		var ran atomic.Int32
+		started := make(chan struct{})
+		assert.NoError(t, queue.Enqueue(ctx, func(ctx context.Context) {
+			close(started)
+			<-ctx.Done()
+			ran.Add(1)
+		}))
+		<-started
+		assert.NoError(t, queue.Enqueue(ctx, func(ctx context.Context) {
+			ran.Add(1)
+		}))
+
+		assert.Error(t, lifecycle.Stop())
+		assert.Equal(t, int32(1), ran.Load())
+	})
+}
+
+func TestSMTPMailer(t *testing.T) {
+	// the server accepts connections but never greets, like a mail server that hangs
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	defer listener.Close()
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			// closed with the listener when the test ends
+			defer conn.Close()
+		}
+	}()
+
+	addr := listener.Addr().(*net.TCPAddr)
+	mailer := &infrastructure.SMTPMailer{Host: "127.0.0.1", Port: addr.Port, From: "no-reply@example.com"}
+	message := &model.MailMessage{To: "johndoe@example.com", Subject: "Subject", Body: "Body"}
+
+	t.Run("failed timeout", func(t *testing.T) {
+		mailer.Timeout = 100 * time.Millisecond
+
+		start := time.Now()
+		assert.Error(t, mailer.Send(context.Background(), message))
+		assert.Less(t, time.Since(start), time.Second)
+	})
+
+	t.Run("failed context cancelled", func(t *testing.T) {
+		mailer.Timeout = 0
+		ctx, cancel := context.WithCancel(context.Background())
+		time.AfterFunc(100*time.Millisecond, cancel)
+
+		start := time.Now()
+		assert.Error(t, mailer.Send(ctx, message))
+		assert.Less(t, time.Since(start), time.Second)
+	})
+
+	t.Run("failed unreachable", func(t *testing.T) {
+		closed, err := net.Listen("tcp", "127.0.0.1:0")
+		assert.NoError(t, err)
+		port := closed.Addr().(*net.TCPAddr).Port
+		closed.Close()
+
+		unreachable := &infrastructure.SMTPMailer{Host: "127.0.0.1", Port: port, From: "no-reply@example.com", Timeout: time.Second}
+		assert.Error(t, unreachable.Send(context.Background(), message))
+	})
+}
@@ -0,0 +1,94 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: internal/repository/password_reset_token_repository.go
+
+// Package mocks is a generated GoMock package.
+package mocks
+
+import (
+	context "context"
+	reflect "reflect"
+
+	domain "github.com/Ikhlashmulya/golang-clean-architecture-project-structure/internal/domain"
+	gomock "go.uber.org/mock/gomock"
+)
+
+// MockPasswordResetTokenRepository is a mock of PasswordResetTokenRepository interface.
+type MockPasswordResetTokenRepository struct {
+	ctrl     *gomock.Controller
+	recorder *MockPasswordResetTokenRepositoryMockRecorder
+}
+
+// MockPasswordResetTokenRepositoryMockRecorder is the mock recorder for MockPasswordResetTokenRepository.
+type MockPasswordResetTokenRepositoryMockRecorder struct {
+	mock *MockPasswordResetTokenRepository
+}
+
+// NewMockPasswordResetTokenRepository creates a new mock instance.
+func NewMockPasswordResetTokenRepository(ctrl *gomock.Controller) *MockPasswordResetTokenRepository {
+	mock := &MockPasswordResetTokenRepository{ctrl: ctrl}
+	mock.recorder = &MockPasswordResetTokenRepositoryMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockPasswordResetTokenRepository) EXPECT() *MockPasswordResetTokenRepositoryMockRecorder {
+	return m.recorder
+}
+
+// Create mocks base method.
+func (m *MockPasswordResetTokenRepository) Create(ctx context.Context, passwordResetToken *domain.PasswordResetToken) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Create", ctx, passwordResetToken)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Create indicates an expected call of Create.
+func (mr *MockPasswordResetTokenRepositoryMockRecorder) Create(ctx, passwordResetToken interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Create", reflect.TypeOf((*MockPasswordResetTokenRepository)(nil).Create), ctx, passwordResetToken)
+}
+
+// FindByTokenHash mocks base method.
+func (m *MockPasswordResetTokenRepository) FindByTokenHash(ctx context.Context, tokenHash string) (*domain.PasswordResetToken, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "FindByTokenHash", ctx, tokenHash)
+	ret0, _ := ret[0].(*domain.PasswordResetToken)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// FindByTokenHash indicates an expected call of FindByTokenHash.
+func (mr *MockPasswordResetTokenRepositoryMockRecorder) FindByTokenHash(ctx, tokenHash interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FindByTokenHash", reflect.TypeOf((*MockPasswordResetTokenRepository)(nil).FindByTokenHash), ctx, tokenHash)
+}
+
+// MarkUsed mocks base method.
+func (m *MockPasswordResetTokenRepository) MarkUsed(ctx context.Context, id uint, usedAt int64) (bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "MarkUsed", ctx, id, usedAt)
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// MarkUsed indicates an expected call of MarkUsed.
+func (mr *MockPasswordResetTokenRepositoryMockRecorder) MarkUsed(ctx, id, usedAt interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "MarkUsed", reflect.TypeOf((*MockPasswordResetTokenRepository)(nil).MarkUsed), ctx, id, usedAt)
+}
+
+// MarkUsedByUserID mocks base method.
+func (m *MockPasswordResetTokenRepository) MarkUsedByUserID(ctx context.Context, userID uint, usedAt int64) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "MarkUsedByUserID", ctx, userID, usedAt)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// MarkUsedByUserID indicates an expected call of MarkUsedByUserID.
+func (mr *MockPasswordResetTokenRepositoryMockRecorder) MarkUsedByUserID(ctx, userID, usedAt interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "MarkUsedByUserID", reflect.TypeOf((*MockPasswordResetTokenRepository)(nil).MarkUsedByUserID), ctx, userID, usedAt)
+}
@@ -0,0 +1,110 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: internal/repository/role_repository.go
+
+// Package mocks is a generated GoMock package.
+package mocks
+
+import (
+	context "context"
+	reflect "reflect"
+
+	domain "github.com/Ikhlashmulya/golang-clean-architecture-project-structure/internal/domain"
+	gomock "go.uber.org/mock/gomock"
+)
+
+// MockRoleRepository is a mock of RoleRepository interface.
+type MockRoleRepository struct {
+	ctrl     *gomock.Controller
+	recorder *MockRoleRepositoryMockRecorder
+}
+
+// MockRoleRepositoryMockRecorder is the mock recorder for MockRoleRepository.
+type MockRoleRepositoryMockRecorder struct {
+	mock *MockRoleRepository
+}
+
+// NewMockRoleRepository creates a new mock instance.
+func NewMockRoleRepository(ctrl *gomock.Controller) *MockRoleRepository {
+	mock := &MockRoleRepository{ctrl: ctrl}
+	mock.recorder = &MockRoleRepositoryMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockRoleRepository) EXPECT() *MockRoleRepositoryMockRecorder {
+	return m.recorder
+}
+
+// AddUserRole mocks base method.
+func (m *MockRoleRepository) AddUserRole(ctx context.Context, userRole *domain.UserRole) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "AddUserRole", ctx, userRole)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// AddUserRole indicates an expected call of AddUserRole.
+func (mr *MockRoleRepositoryMockRecorder) AddUserRole(ctx, userRole interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AddUserRole", reflect.TypeOf((*MockRoleRepository)(nil).AddUserRole), ctx, userRole)
+}
+
+// FindByName mocks base method.
+func (m *MockRoleRepository) FindByName(ctx context.Context, name string) (*domain.Role, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "FindByName", ctx, name)
+	ret0, _ := ret[0].(*domain.Role)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// FindByName indicates an expected call of FindByName.
+func (mr *MockRoleRepositoryMockRecorder) FindByName(ctx, name interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FindByName", reflect.TypeOf((*MockRoleRepository)(nil).FindByName), ctx, name)
+}
+
+// FindNamesByUserID mocks base method.
+func (m *MockRoleRepository) FindNamesByUserID(ctx context.Context, userID uint) ([]string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "FindNamesByUserID", ctx, userID)
+	ret0, _ := ret[0].([]string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// FindNamesByUserID indicates an expected call of FindNamesByUserID.
+func (mr *MockRoleRepositoryMockRecorder) FindNamesByUserID(ctx, userID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FindNamesByUserID", reflect.TypeOf((*MockRoleRepository)(nil).FindNamesByUserID), ctx, userID)
+}
+
+// FindPermissionNamesByRoleNames mocks base method.
+func (m *MockRoleRepository) FindPermissionNamesByRoleNames(ctx context.Context, roleNames []string) ([]string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "FindPermissionNamesByRoleNames", ctx, roleNames)
+	ret0, _ := ret[0].([]string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// FindPermissionNamesByRoleNames indicates an expected call of FindPermissionNamesByRoleNames.
+func (mr *MockRoleRepositoryMockRecorder) FindPermissionNamesByRoleNames(ctx, roleNames interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FindPermissionNamesByRoleNames", reflect.TypeOf((*MockRoleRepository)(nil).FindPermissionNamesByRoleNames), ctx, roleNames)
+}
+
+// RemoveUserRole mocks base method.
+func (m *MockRoleRepository) RemoveUserRole(ctx context.Context, userID, roleID uint) (bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RemoveUserRole", ctx, userID, roleID)
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// RemoveUserRole indicates an expected call of RemoveUserRole.
+func (mr *MockRoleRepositoryMockRecorder) RemoveUserRole(ctx, userID, roleID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RemoveUserRole", reflect.TypeOf((*MockRoleRepository)(nil).RemoveUserRole), ctx, userID, roleID)
+}
@@ -0,0 +1,123 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: internal/repository/two_factor_repository.go
+
+// Package mocks is a generated GoMock package.
+package mocks
+
+import (
+	context "context"
+	reflect "reflect"
+
+	domain "github.com/Ikhlashmulya/golang-clean-architecture-project-structure/internal/domain"
+	gomock "go.uber.org/mock/gomock"
+)
+
+// MockTwoFactorRepository is a mock of TwoFactorRepository interface.
+type MockTwoFactorRepository struct {
+	ctrl     *gomock.Controller
+	recorder *MockTwoFactorRepositoryMockRecorder
+}
+
+// MockTwoFactorRepositoryMockRecorder is the mock recorder for MockTwoFactorRepository.
+type MockTwoFactorRepositoryMockRecorder struct {
+	mock *MockTwoFactorRepository
+}
+
+// NewMockTwoFactorRepository creates a new mock instance.
+func NewMockTwoFactorRepository(ctrl *gomock.Controller) *MockTwoFactorRepository {
+	mock := &MockTwoFactorRepository{ctrl: ctrl}
+	mock.recorder = &MockTwoFactorRepositoryMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockTwoFactorRepository) EXPECT() *MockTwoFactorRepositoryMockRecorder {
+	return m.recorder
+}
+
+// ConfirmTOTP mocks base method.
+func (m *MockTwoFactorRepository) ConfirmTOTP(ctx context.Context, userTOTP *domain.UserTOTP, recoveryCodes []domain.RecoveryCode) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ConfirmTOTP", ctx, userTOTP, recoveryCodes)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// ConfirmTOTP indicates an expected call of ConfirmTOTP.
+func (mr *MockTwoFactorRepositoryMockRecorder) ConfirmTOTP(ctx, userTOTP, recoveryCodes interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ConfirmTOTP", reflect.TypeOf((*MockTwoFactorRepository)(nil).ConfirmTOTP), ctx, userTOTP, recoveryCodes)
+}
+
+// DeleteByUserID mocks base method.
+func (m *MockTwoFactorRepository) DeleteByUserID(ctx context.Context, userID uint) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteByUserID", ctx, userID)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeleteByUserID indicates an expected call of DeleteByUserID.
+func (mr *MockTwoFactorRepositoryMockRecorder) DeleteByUserID(ctx, userID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteByUserID", reflect.TypeOf((*MockTwoFactorRepository)(nil).DeleteByUserID), ctx, userID)
+}
+
+// FindTOTPByUserID mocks base method.
+func (m *MockTwoFactorRepository) FindTOTPByUserID(ctx context.Context, userID uint) (*domain.UserTOTP, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "FindTOTPByUserID", ctx, userID)
+	ret0, _ := ret[0].(*domain.UserTOTP)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// FindTOTPByUserID indicates an expected call of FindTOTPByUserID.
+func (mr *MockTwoFactorRepositoryMockRecorder) FindTOTPByUserID(ctx, userID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FindTOTPByUserID", reflect.TypeOf((*MockTwoFactorRepository)(nil).FindTOTPByUserID), ctx, userID)
+}
+
+// SaveTOTP mocks base method.
+func (m *MockTwoFactorRepository) SaveTOTP(ctx context.Context, userTOTP *domain.UserTOTP) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SaveTOTP", ctx, userTOTP)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SaveTOTP indicates an expected call of SaveTOTP.
+func (mr *MockTwoFactorRepositoryMockRecorder) SaveTOTP(ctx, userTOTP interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SaveTOTP", reflect.TypeOf((*MockTwoFactorRepository)(nil).SaveTOTP), ctx, userTOTP)
+}
+
+// UseRecoveryCode mocks base method.
+func (m *MockTwoFactorRepository) UseRecoveryCode(ctx context.Context, userID uint, codeHash string, usedAt int64) (bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UseRecoveryCode", ctx, userID, codeHash, usedAt)
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// UseRecoveryCode indicates an expected call of UseRecoveryCode.
+func (mr *MockTwoFactorRepositoryMockRecorder) UseRecoveryCode(ctx, userID, codeHash, usedAt interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UseRecoveryCode", reflect.TypeOf((*MockTwoFactorRepository)(nil).UseRecoveryCode), ctx, userID, codeHash, usedAt)
+}
+
+// UseTOTPStep mocks base method.
+func (m *MockTwoFactorRepository) UseTOTPStep(ctx context.Context, userID uint, step int64) (bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UseTOTPStep", ctx, userID, step)
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// UseTOTPStep indicates an expected call of UseTOTPStep.
+func (mr *MockTwoFactorRepositoryMockRecorder) UseTOTPStep(ctx, userID, step interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UseTOTPStep", reflect.TypeOf((*MockTwoFactorRepository)(nil).UseTOTPStep), ctx, userID, step)
+}
@@ -7,8 +7,10 @@ package mocks
 import (
 	context "context"
 	reflect "reflect"
+	time "time"
 
 	domain "github.com/Ikhlashmulya/golang-clean-architecture-project-structure/internal/domain"
+	repository "github.com/Ikhlashmulya/golang-clean-architecture-project-structure/internal/repository"
 	gomock "go.uber.org/mock/gomock"
 )
 
@@ -35,6 +37,21 @@ func (m *MockUserRepository) EXPECT() *MockUserRepositoryMockRecorder {
 	return m.recorder
 }
 
+// CountByEmail mocks base method.
+func (m *MockUserRepository) CountByEmail(ctx context.Context, email string) (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CountByEmail", ctx, email)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CountByEmail indicates an expected call of CountByEmail.
+func (mr *MockUserRepositoryMockRecorder) CountByEmail(ctx, email interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CountByEmail", reflect.TypeOf((*MockUserRepository)(nil).CountByEmail), ctx, email)
+}
+
 // CountByUsername mocks base method.
 func (m *MockUserRepository) CountByUsername(ctx context.Context, username string) (int64, error) {
 	m.ctrl.T.Helper()
@@ -64,6 +81,35 @@ func (mr *MockUserRepositoryMockRecorder) Create(ctx, user interface{}) *gomock.
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Create", reflect.TypeOf((*MockUserRepository)(nil).Create), ctx, user)
 }
 
+// Delete mocks base method.
+func (m *MockUserRepository) Delete(ctx context.Context, user *domain.User) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Delete", ctx, user)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Delete indicates an expected call of Delete.
+func (mr *MockUserRepositoryMockRecorder) Delete(ctx, user interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Delete", reflect.TypeOf((*MockUserRepository)(nil).Delete), ctx, user)
+}
+
+// FindByID mocks base method.
+func (m *MockUserRepository) FindByID(ctx context.Context, id uint) (*domain.User, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "FindByID", ctx, id)
+	ret0, _ := ret[0].(*domain.User)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// FindByID indicates an expected call of FindByID.
+func (mr *MockUserRepositoryMockRecorder) FindByID(ctx, id interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FindByID", reflect.TypeOf((*MockUserRepository)(nil).FindByID), ctx, id)
+}
+
 // FindByUsername mocks base method.
 func (m *MockUserRepository) FindByUsername(ctx context.Context, username string) (*domain.User, error) {
 	m.ctrl.T.Helper()
@@ -79,6 +125,66 @@ func (mr *MockUserRepositoryMockRecorder) FindByUsername(ctx, username interface
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FindByUsername", reflect.TypeOf((*MockUserRepository)(nil).FindByUsername), ctx, username)
 }
 
+// FindDeletedByUsername mocks base method.
+func (m *MockUserRepository) FindDeletedByUsername(ctx context.Context, username string) (*domain.User, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "FindDeletedByUsername", ctx, username)
+	ret0, _ := ret[0].(*domain.User)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// FindDeletedByUsername indicates an expected call of FindDeletedByUsername.
+func (mr *MockUserRepositoryMockRecorder) FindDeletedByUsername(ctx, username interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FindDeletedByUsername", reflect.TypeOf((*MockUserRepository)(nil).FindDeletedByUsername), ctx, username)
+}
+
+// PurgeDeleted mocks base method.
+func (m *MockUserRepository) PurgeDeleted(ctx context.Context, deletedBefore time.Time) (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "PurgeDeleted", ctx, deletedBefore)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// PurgeDeleted indicates an expected call of PurgeDeleted.
+func (mr *MockUserRepositoryMockRecorder) PurgeDeleted(ctx, deletedBefore interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "PurgeDeleted", reflect.TypeOf((*MockUserRepository)(nil).PurgeDeleted), ctx, deletedBefore)
+}
+
+// Restore mocks base method.
+func (m *MockUserRepository) Restore(ctx context.Context, user *domain.User) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Restore", ctx, user)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Restore indicates an expected call of Restore.
+func (mr *MockUserRepositoryMockRecorder) Restore(ctx, user interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Restore", reflect.TypeOf((*MockUserRepository)(nil).Restore), ctx, user)
+}
+
+// Search mocks base method.
+func (m *MockUserRepository) Search(ctx context.Context, search *repository.UserSearch) ([]domain.User, int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Search", ctx, search)
+	ret0, _ := ret[0].([]domain.User)
+	ret1, _ := ret[1].(int64)
+	ret2, _ := ret[2].(error)
+	return ret0, ret1, ret2
+}
+
+// Search indicates an expected call of Search.
+func (mr *MockUserRepositoryMockRecorder) Search(ctx, search interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Search", reflect.TypeOf((*MockUserRepository)(nil).Search), ctx, search)
+}
+
 // Update mocks base method.
 func (m *MockUserRepository) Update(ctx context.Context, user *domain.User) error {
 	m.ctrl.T.Helper()
@@ -0,0 +1,235 @@
+package unit
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/Ikhlashmulya/golang-clean-architecture-project-structure/config"
+	"github.com/Ikhlashmulya/golang-clean-architecture-project-structure/internal/exception"
+	"github.com/Ikhlashmulya/golang-clean-architecture-project-structure/internal/infrastructure"
+	"github.com/Ikhlashmulya/golang-clean-architecture-project-structure/internal/model"
+	"github.com/Ikhlashmulya/golang-clean-architecture-project-structure/test/unit/mocks"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/mock/gomock"
+)
+
+// writeHIBPFile writes passwords in the format of the Have I Been Pwned corpus, ordered by hash.
+func writeHIBPFile(t *testing.T, path string, lineBreak string, passwords ...string) {
+	lines := make([]string, 0, len(passwords))
+	for i, password := range passwords {
+		sum := sha1.Sum([]byte(password))
+		lines = append(lines, fmt.Sprintf("%s:%d", strings.ToUpper(hex.EncodeToString(sum[:])), i%1000+1))
+	}
+	sort.Strings(lines)
+
+	assert.NoError(t, os.WriteFile(path, []byte(strings.Join(lines, lineBreak)+lineBreak), 0o600))
+}
+
+// writeHIBPRangeDir writes passwords in the range files of the Have I Been Pwned corpus, with an
+// empty file for every other prefix starting with 0000 so the first range exists.
+func writeHIBPRangeDir(t *testing.T, dir string, passwords ...string) {
+	ranges := make(map[string][]string)
+	for i := 0; i < 16; i++ {
+		ranges[fmt.Sprintf("0000%X", i)] = nil
+	}
+	for i, password := range passwords {
+		sum := sha1.Sum([]byte(password))
+		hash := strings.ToUpper(hex.EncodeToString(sum[:]))
+		ranges[hash[:5]] = append(ranges[hash[:5]], fmt.Sprintf("%s:%d", hash[5:], i+1))
+	}
+
+	for prefix, lines := range ranges {
+		sort.Strings(lines)
+		content := strings.Join(lines, "\r\n")
+		assert.NoError(t, os.WriteFile(filepath.Join(dir, prefix+".txt"), []byte(content), 0o600))
+	}
+}
+
+func TestHIBPRangeChecker(t *testing.T) {
+	t.Run("success listed passwords found", func(t *testing.T) {
+		dir := t.TempDir()
+		writeHIBPRangeDir(t, dir, "123456", "password", "qwerty")
+
+		cfg := new(config.Config)
+		cfg.Auth.PasswordPolicy.BreachFile = dir
+		checker, err := infrastructure.NewPasswordBreachChecker(cfg, nil)
+		assert.NoError(t, err)
+		assert.IsType(t, &infrastructure.HIBPRangeChecker{}, checker)
+
+		for _, password := range []string{"123456", "password", "qwerty"} {
+			breached, err := checker.IsBreached(ctx, password)
+			assert.NoError(t, err)
+			assert.True(t, breached, password)
+		}
+	})
+
+	t.Run("success unlisted password in an existing range", func(t *testing.T) {
+		dir := t.TempDir()
+		writeHIBPRangeDir(t, dir, "123456")
+
+		// another password of the same range, only its prefix file is read
+		sum := sha1.Sum([]byte("123456"))
+		prefix := strings.ToUpper(hex.EncodeToString(sum[:]))[:5]
+		content, err := os.ReadFile(filepath.Join(dir, prefix+".txt"))
+		assert.NoError(t, err)
+		content = append(content, []byte("\r\n"+strings.Repeat("F", 35)+":3")...)
+		assert.NoError(t, os.WriteFile(filepath.Join(dir, prefix+".txt"), content, 0o600))
+
+		checker, err := infrastructure.NewHIBPRangeChecker(dir)
+		assert.NoError(t, err)
+
+		breached, err := checker.IsBreached(ctx, "123456")
+		assert.NoError(t, err)
+		assert.True(t, breached)
+	})
+
+	t.Run("failed missing range file", func(t *testing.T) {
+		dir := t.TempDir()
+		writeHIBPRangeDir(t, dir)
+
+		checker, err := infrastructure.NewHIBPRangeChecker(dir)
+		assert.NoError(t, err)
+
+		_, err = checker.IsBreached(ctx, "Correct-Horse-42")
+		assert.Error(t, err)
+	})
+
+	t.Run("failed not a range directory", func(t *testing.T) {
+		dir := t.TempDir()
+		writeHIBPFile(t, filepath.Join(dir, "00000.txt"), "\n", "123456")
+
+		_, err := infrastructure.NewHIBPRangeChecker(dir)
+		assert.Error(t, err)
+	})
+}
+
+func TestHIBPFileChecker(t *testing.T) {
+	t.Run("success listed passwords found among many", func(t *testing.T) {
+		passwords := make([]string, 100000)
+		for i := range passwords {
+			passwords[i] = fmt.Sprintf("breached-%d", i)
+		}
+		path := filepath.Join(t.TempDir(), "pwnedpasswords.txt")
+		writeHIBPFile(t, path, "\n", passwords...)
+
+		checker, err := infrastructure.NewHIBPFileChecker(path)
+		assert.NoError(t, err)
+		defer checker.Close()
+
+		for i := 0; i < len(passwords); i += 997 {
+			breached, err := checker.IsBreached(ctx, passwords[i])
+			assert.NoError(t, err)
+			assert.True(t, breached, passwords[i])
+		}
+
+		for _, password := range []string{"Correct-Horse-42", "breached-100000", ""} {
+			breached, err := checker.IsBreached(ctx, password)
+			assert.NoError(t, err)
+			assert.False(t, breached, password)
+		}
+	})
+
+	t.Run("success windows line breaks", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "pwnedpasswords.txt")
+		writeHIBPFile(t, path, "\r\n", "123456", "password", "qwerty")
+
+		checker, err := infrastructure.NewHIBPFileChecker(path)
+		assert.NoError(t, err)
+		defer checker.Close()
+
+		breached, err := checker.IsBreached(ctx, "qwerty")
+		assert.NoError(t, err)
+		assert.True(t, breached)
+	})
+
+	t.Run("success replaced file reopened", func(t *testing.T) {
+		dir := t.TempDir()
+		path := filepath.Join(dir, "pwnedpasswords.txt")
+		writeHIBPFile(t, path, "\n", "123456")
+
+		checker, err := infrastructure.NewHIBPFileChecker(path)
+		assert.NoError(t, err)
+		defer checker.Close()
+
+		breached, err := checker.IsBreached(ctx, "Correct-Horse-42")
+		assert.NoError(t, err)
+		assert.False(t, breached)
+
+		refreshed := filepath.Join(dir, "pwnedpasswords.txt.new")
+		writeHIBPFile(t, refreshed, "\n", "123456", "Correct-Horse-42")
+		assert.NoError(t, os.Rename(refreshed, path))
+
+		breached, err = checker.IsBreached(ctx, "Correct-Horse-42")
+		assert.NoError(t, err)
+		assert.True(t, breached)
+	})
+
+	t.Run("failed not a sha1 corpus", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "pwnedpasswords-ntlm.txt")
+		assert.NoError(t, os.WriteFile(path, []byte("32ED87BDB5FDC5E9CBA88547376818D4:37359195\n"), 0o600))
+
+		_, err := infrastructure.NewHIBPFileChecker(path)
+		assert.Error(t, err)
+	})
+
+	t.Run("success noop without breach file", func(t *testing.T) {
+		checker, err := infrastructure.NewPasswordBreachChecker(new(config.Config), nil)
+		assert.NoError(t, err)
+
+		breached, err := checker.IsBreached(ctx, "123456")
+		assert.NoError(t, err)
+		assert.False(t, breached)
+	})
+}
+
+func TestPasswordBreach(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "pwnedpasswords.txt")
+	writeHIBPFile(t, path, "\n", "Correct-Horse-42")
+	checker, err := infrastructure.NewHIBPFileChecker(path)
+	assert.NoError(t, err)
+	defer checker.Close()
+
+	ctrl := gomock.NewController(t)
+	userRepository := mocks.NewMockUserRepository(ctrl)
+	userUsecase := newUserUsecase(t, userUsecaseMocks{UserRepository: userRepository, PasswordBreachChecker: checker})
+
+	t.Run("failed register", func(t *testing.T) {
+		_, err := userUsecase.Register(ctx, &model.RegisterUserRequest{
+			Name:     "John Doe",
+			Username: "johndoe",
+			Email:    "johndoe@example.com",
+			Password: "Correct-Horse-42",
+		})
+		assert.ErrorIs(t, err, exception.ErrPasswordBreached)
+	})
+
+	t.Run("failed update", func(t *testing.T) {
+		userRepository.EXPECT().FindByUsername(gomock.Any(), "johndoe").Return(createUser(t), nil)
+
+		_, err := userUsecase.Update(ctx, &model.UpdateUserRequest{Username: "johndoe", Password: "Correct-Horse-42"})
+		assert.ErrorIs(t, err, exception.ErrPasswordBreached)
+	})
+
+	t.Run("failed reset password before the token is used", func(t *testing.T) {
+		err := userUsecase.ResetPassword(ctx, &model.ResetPasswordRequest{Token: "token", Password: "Correct-Horse-42"})
+		assert.ErrorIs(t, err, exception.ErrPasswordBreached)
+	})
+
+	t.Run("failed corpus unreadable", func(t *testing.T) {
+		dir := t.TempDir()
+		writeHIBPRangeDir(t, dir)
+		rangeChecker, err := infrastructure.NewHIBPRangeChecker(dir)
+		assert.NoError(t, err)
+
+		userUsecase := newUserUsecase(t, userUsecaseMocks{PasswordBreachChecker: rangeChecker})
+
+		err = userUsecase.ResetPassword(ctx, &model.ResetPasswordRequest{Token: "token", Password: "Correct-Horse-42"})
+		assert.ErrorIs(t, err, exception.ErrInternalServerError)
+	})
+}
@@ -0,0 +1,105 @@
+package unit
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/Ikhlashmulya/golang-clean-architecture-project-structure/config"
+	"github.com/Ikhlashmulya/golang-clean-architecture-project-structure/internal/delivery/http/middleware"
+	"github.com/Ikhlashmulya/golang-clean-architecture-project-structure/internal/infrastructure"
+	"github.com/gofiber/fiber/v2"
+	"github.com/sirupsen/logrus"
+	"github.com/sirupsen/logrus/hooks/test"
+	"github.com/stretchr/testify/assert"
+)
+
+func newRequestLogApp(cfg *config.Config) (*fiber.App, *test.Hook) {
+	logger, hook := test.NewNullLogger()
+	logger.SetOutput(io.Discard)
+
+	app := fiber.New()
+	app.Use(middleware.NewRequestID(logger))
+	app.Use(middleware.NewAccessLog(cfg, logger))
+	app.Get("/api/users/:username", middleware.NewRouteLogger(logger), func(c *fiber.Ctx) error {
+		infrastructure.LoggerFromContext(c.UserContext(), logger).Info("handled")
+		return c.SendStatus(fiber.StatusOK)
+	})
+	app.Get("/api/failing", func(c *fiber.Ctx) error {
+		return fiber.ErrInternalServerError
+	})
+
+	return app, hook
+}
+
+func TestRequestID(t *testing.T) {
+	cfg := new(config.Config)
+	cfg.Log.Access.SamplePercent = 100
+
+	t.Run("success caller id echoed and logged", func(t *testing.T) {
+		app, hook := newRequestLogApp(cfg)
+		request := httptest.NewRequest(http.MethodGet, "/api/users/johndoe", nil)
+		request.Header.Set(fiber.HeaderXRequestID, "f3b1c2d4-req")
+
+		response, err := app.Test(request)
+		assert.NoError(t, err)
+		assert.Equal(t, "f3b1c2d4-req", response.Header.Get(fiber.HeaderXRequestID))
+		for _, entry := range hook.AllEntries() {
+			assert.Equal(t, "f3b1c2d4-req", entry.Data["request_id"])
+		}
+		// the handler logs through the request logger, which got the matched route
+		assert.Equal(t, "/api/users/:username", hook.AllEntries()[0].Data["route"])
+	})
+
+	t.Run("success generated when missing or not a plain token", func(t *testing.T) {
+		app, _ := newRequestLogApp(cfg)
+		for _, requestID := range []string{"", "forged\" level=error"} {
+			request := httptest.NewRequest(http.MethodGet, "/api/users/johndoe", nil)
+			request.Header.Set(fiber.HeaderXRequestID, requestID)
+
+			response, err := app.Test(request)
+			assert.NoError(t, err)
+			assert.Len(t, response.Header.Get(fiber.HeaderXRequestID), 36)
+		}
+	})
+}
+
+func TestAccessLog(t *testing.T) {
+	t.Run("success configured fields written", func(t *testing.T) {
+		cfg := new(config.Config)
+		cfg.Log.Access.Fields = []string{"method", "route", "status"}
+		cfg.Log.Access.SamplePercent = 100
+		app, hook := newRequestLogApp(cfg)
+
+		_, err := app.Test(httptest.NewRequest(http.MethodGet, "/api/users/johndoe", nil))
+		assert.NoError(t, err)
+
+		entry := hook.LastEntry()
+		assert.Equal(t, "request completed", entry.Message)
+		assert.Equal(t, http.MethodGet, entry.Data["method"])
+		assert.Equal(t, "/api/users/:username", entry.Data["route"])
+		assert.Equal(t, http.StatusOK, entry.Data["status"])
+		assert.NotContains(t, entry.Data, "path")
+		assert.Contains(t, entry.Data, "request_id")
+	})
+
+	t.Run("success server errors logged when not sampled", func(t *testing.T) {
+		cfg := new(config.Config)
+		cfg.Log.Access.Fields = []string{"status"}
+		app, hook := newRequestLogApp(cfg)
+
+		_, err := app.Test(httptest.NewRequest(http.MethodGet, "/api/users/johndoe", nil))
+		assert.NoError(t, err)
+		assert.Equal(t, "handled", hook.LastEntry().Message)
+
+		response, err := app.Test(httptest.NewRequest(http.MethodGet, "/api/failing", nil))
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusInternalServerError, response.StatusCode)
+
+		entry := hook.LastEntry()
+		assert.Equal(t, logrus.ErrorLevel, entry.Level)
+		assert.Equal(t, "request failed", entry.Message)
+		assert.Equal(t, http.StatusInternalServerError, entry.Data["status"])
+	})
+}
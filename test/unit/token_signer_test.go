@@ -0,0 +1,191 @@
+package unit
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/Ikhlashmulya/golang-clean-architecture-project-structure/config"
+	"github.com/Ikhlashmulya/golang-clean-architecture-project-structure/internal/infrastructure"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTokenSigner(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	assert.NoError(t, err)
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.NoError(t, err)
+	_, edKey, err := ed25519.GenerateKey(rand.Reader)
+	assert.NoError(t, err)
+
+	claims := jwt.MapClaims{
+		"username": "johndoe",
+		"exp":      time.Now().Add(time.Hour).Unix(),
+	}
+
+	for method, key := range map[string]crypto.Signer{"RS256": rsaKey, "ES256": ecKey, "EdDSA": edKey} {
+		t.Run("success "+method, func(t *testing.T) {
+			config := new(config.Config)
+			config.Auth.JWT.SigningMethod = method
+			config.Auth.JWT.PrivateKeyFile = writePrivateKey(t, key)
+
+			signer, err := infrastructure.NewTokenSigner(config)
+			assert.NoError(t, err)
+
+			token, err := signer.Sign(claims)
+			assert.NoError(t, err)
+
+			parsed, err := signer.Parse(token)
+			assert.NoError(t, err)
+			assert.Equal(t, "johndoe", parsed["username"])
+
+			jwks := signer.JWKS()
+			assert.Len(t, jwks.Keys, 1)
+			assert.Equal(t, method, jwks.Keys[0].Algorithm)
+			assert.NotEmpty(t, jwks.Keys[0].KeyID)
+		})
+	}
+
+	t.Run("success verify token signed by rotated key", func(t *testing.T) {
+		oldKeyFile := writePrivateKey(t, rsaKey)
+
+		oldConfig := new(config.Config)
+		oldConfig.Auth.JWT.SigningMethod = "RS256"
+		oldConfig.Auth.JWT.PrivateKeyFile = oldKeyFile
+
+		oldSigner, err := infrastructure.NewTokenSigner(oldConfig)
+		assert.NoError(t, err)
+		token, err := oldSigner.Sign(claims)
+		assert.NoError(t, err)
+
+		newConfig := new(config.Config)
+		newConfig.Auth.JWT.SigningMethod = "EdDSA"
+		newConfig.Auth.JWT.PrivateKeyFile = writePrivateKey(t, edKey)
+		newConfig.Auth.JWT.VerificationKeyFiles = []string{oldKeyFile}
+
+		signer, err := infrastructure.NewTokenSigner(newConfig)
+		assert.NoError(t, err)
+
+		_, err = signer.Parse(token)
+		assert.NoError(t, err)
+		assert.Len(t, signer.JWKS().Keys, 2)
+	})
+
+	t.Run("success verify rotated key with any algorithm of its type", func(t *testing.T) {
+		oldKeyFile := writePrivateKey(t, rsaKey)
+
+		newConfig := new(config.Config)
+		newConfig.Auth.JWT.SigningMethod = "ES256"
+		newConfig.Auth.JWT.PrivateKeyFile = writePrivateKey(t, ecKey)
+		newConfig.Auth.JWT.VerificationKeyFiles = []string{oldKeyFile}
+
+		signer, err := infrastructure.NewTokenSigner(newConfig)
+		assert.NoError(t, err)
+
+		for _, method := range []string{"RS256", "RS512", "PS384"} {
+			oldConfig := new(config.Config)
+			oldConfig.Auth.JWT.SigningMethod = method
+			oldConfig.Auth.JWT.PrivateKeyFile = oldKeyFile
+
+			oldSigner, err := infrastructure.NewTokenSigner(oldConfig)
+			assert.NoError(t, err)
+			token, err := oldSigner.Sign(claims)
+			assert.NoError(t, err)
+
+			_, err = signer.Parse(token)
+			assert.NoError(t, err, method)
+		}
+	})
+
+	t.Run("success verify token signed by previous secret", func(t *testing.T) {
+		oldConfig := new(config.Config)
+		oldConfig.Auth.JWT.SecretKey = "oldsecret"
+
+		oldSigner, err := infrastructure.NewTokenSigner(oldConfig)
+		assert.NoError(t, err)
+		token, err := oldSigner.Sign(claims)
+		assert.NoError(t, err)
+
+		newConfig := new(config.Config)
+		newConfig.Auth.JWT.SecretKey = "newsecret"
+
+		signer, err := infrastructure.NewTokenSigner(newConfig)
+		assert.NoError(t, err)
+		_, err = signer.Parse(token)
+		assert.Error(t, err)
+
+		newConfig.Auth.JWT.PreviousSecretKeys = []string{"oldsecret"}
+
+		signer, err = infrastructure.NewTokenSigner(newConfig)
+		assert.NoError(t, err)
+		_, err = signer.Parse(token)
+		assert.NoError(t, err)
+		assert.Empty(t, signer.JWKS().Keys)
+	})
+
+	t.Run("failed unknown key", func(t *testing.T) {
+		config := new(config.Config)
+		config.Auth.JWT.SigningMethod = "ES256"
+		config.Auth.JWT.PrivateKeyFile = writePrivateKey(t, ecKey)
+
+		signer, err := infrastructure.NewTokenSigner(config)
+		assert.NoError(t, err)
+		token, err := signer.Sign(claims)
+		assert.NoError(t, err)
+
+		config.Auth.JWT.PrivateKeyFile = writePrivateKey(t, edKey)
+		config.Auth.JWT.SigningMethod = "EdDSA"
+
+		signer, err = infrastructure.NewTokenSigner(config)
+		assert.NoError(t, err)
+		_, err = signer.Parse(token)
+		assert.Error(t, err)
+	})
+
+	t.Run("failed hmac token against public key", func(t *testing.T) {
+		config := new(config.Config)
+		config.Auth.JWT.SigningMethod = "RS256"
+		config.Auth.JWT.PrivateKeyFile = writePrivateKey(t, rsaKey)
+		signer, err := infrastructure.NewTokenSigner(config)
+		assert.NoError(t, err)
+
+		kid := signer.JWKS().Keys[0].KeyID
+		forged := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+		forged.Header["kid"] = kid
+		token, err := forged.SignedString(x509.MarshalPKCS1PublicKey(&rsaKey.PublicKey))
+		assert.NoError(t, err)
+
+		_, err = signer.Parse(token)
+		assert.Error(t, err)
+	})
+
+	t.Run("failed key does not match signing method", func(t *testing.T) {
+		config := new(config.Config)
+		config.Auth.JWT.SigningMethod = "RS256"
+		config.Auth.JWT.PrivateKeyFile = writePrivateKey(t, edKey)
+
+		_, err := infrastructure.NewTokenSigner(config)
+		assert.Error(t, err)
+	})
+}
+
+func writePrivateKey(t *testing.T, key crypto.Signer) string {
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	assert.NoError(t, err)
+
+	file := filepath.Join(t.TempDir(), "key.pem")
+	err = os.WriteFile(file, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}), 0o600)
+	assert.NoError(t, err)
+
+	return file
+}
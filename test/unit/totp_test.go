@@ -0,0 +1,49 @@
+package unit
+
+import (
+	"testing"
+	"time"
+
+	"github.com/Ikhlashmulya/golang-clean-architecture-project-structure/internal/infrastructure"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTOTP(t *testing.T) {
+	// base32 of the RFC 6238 appendix B SHA1 seed "12345678901234567890"
+	secret := "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"
+
+	t.Run("success rfc 6238 vectors", func(t *testing.T) {
+		for unix, expected := range map[int64]string{
+			59:         "287082",
+			1111111109: "081804",
+			1111111111: "050471",
+			1234567890: "005924",
+			2000000000: "279037",
+		} {
+			code, err := infrastructure.TOTPCode(secret, time.Unix(unix, 0))
+			assert.NoError(t, err)
+			assert.Equal(t, expected, code)
+		}
+	})
+
+	t.Run("success validate within skew", func(t *testing.T) {
+		now := time.Unix(1111111111, 0)
+
+		code, err := infrastructure.TOTPCode(secret, now.Add(-30*time.Second))
+		assert.NoError(t, err)
+
+		step, ok := infrastructure.ValidateTOTP(secret, code, now)
+		assert.True(t, ok)
+		assert.Equal(t, now.Unix()/30-1, step)
+	})
+
+	t.Run("failed validate outside skew", func(t *testing.T) {
+		now := time.Unix(1111111111, 0)
+
+		code, err := infrastructure.TOTPCode(secret, now.Add(-2*time.Minute))
+		assert.NoError(t, err)
+
+		_, ok := infrastructure.ValidateTOTP(secret, code, now)
+		assert.False(t, ok)
+	})
+}
@@ -0,0 +1,113 @@
+package unit
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/Ikhlashmulya/golang-clean-architecture-project-structure/config"
+	"github.com/Ikhlashmulya/golang-clean-architecture-project-structure/internal/delivery/http/middleware"
+	"github.com/Ikhlashmulya/golang-clean-architecture-project-structure/internal/infrastructure"
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestTracingMiddleware(t *testing.T) {
+	setup := func() (*fiber.App, *tracetest.SpanRecorder) {
+		recorder := tracetest.NewSpanRecorder()
+		provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+
+		app := fiber.New()
+		app.Use(middleware.NewTracing(provider))
+		app.Get("/api/users/:username", func(c *fiber.Ctx) error {
+			_, span := provider.Tracer("test").Start(c.UserContext(), "UserUsecase.Current")
+			span.End()
+			return c.SendStatus(fiber.StatusOK)
+		})
+		app.Get("/api/failing", func(c *fiber.Ctx) error {
+			return fiber.ErrInternalServerError
+		})
+
+		return app, recorder
+	}
+
+	t.Run("success continues the caller trace", func(t *testing.T) {
+		app, recorder := setup()
+		request := httptest.NewRequest(http.MethodGet, "/api/users/johndoe", nil)
+		request.Header.Set("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+
+		response, err := app.Test(request)
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusOK, response.StatusCode)
+
+		spans := recorder.Ended()
+		assert.Len(t, spans, 2)
+		child, server := spans[0], spans[1]
+		assert.Equal(t, "GET /api/users/:username", server.Name())
+		assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", server.SpanContext().TraceID().String())
+		assert.Equal(t, "00f067aa0ba902b7", server.Parent().SpanID().String())
+		assert.True(t, server.Parent().IsRemote())
+		// spans started from the user context are children of the request span
+		assert.Equal(t, server.SpanContext().SpanID(), child.Parent().SpanID())
+	})
+
+	t.Run("success new trace without traceparent", func(t *testing.T) {
+		app, recorder := setup()
+
+		_, err := app.Test(httptest.NewRequest(http.MethodGet, "/api/users/johndoe", nil))
+		assert.NoError(t, err)
+
+		server := recorder.Ended()[1]
+		assert.False(t, server.Parent().IsValid())
+		assert.True(t, server.SpanContext().IsValid())
+	})
+
+	t.Run("failed server error marks the span", func(t *testing.T) {
+		app, recorder := setup()
+
+		response, err := app.Test(httptest.NewRequest(http.MethodGet, "/api/failing", nil))
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusInternalServerError, response.StatusCode)
+
+		server := recorder.Ended()[0]
+		assert.Equal(t, "GET /api/failing", server.Name())
+		assert.Equal(t, codes.Error, server.Status().Code)
+	})
+
+	t.Run("success unmatched path not used as name", func(t *testing.T) {
+		app, recorder := setup()
+
+		response, err := app.Test(httptest.NewRequest(http.MethodGet, "/unknown/path", nil))
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusNotFound, response.StatusCode)
+		assert.Equal(t, "GET", recorder.Ended()[0].Name())
+	})
+}
+
+func TestTracerProviderFileExporter(t *testing.T) {
+	cfg := new(config.Config)
+	cfg.App.Name = "Golang Rest API"
+	cfg.Tracing.Exporter = "file"
+	cfg.Tracing.File = filepath.Join(t.TempDir(), "traces", "spans.json")
+	lifecycle := newLifecycle(time.Second)
+
+	provider, err := infrastructure.NewTracerProvider(cfg, lifecycle)
+	assert.NoError(t, err)
+
+	_, span := infrastructure.NewTracer(provider).Start(context.Background(), "UserUsecase.Update")
+	span.End()
+
+	// spans are batched, stopping the lifecycle flushes them
+	assert.NoError(t, lifecycle.Stop())
+	content, err := os.ReadFile(cfg.Tracing.File)
+	assert.NoError(t, err)
+	assert.Contains(t, string(content), `"Name":"UserUsecase.Update"`)
+	assert.Contains(t, string(content), `"Value":"Golang Rest API"`)
+}
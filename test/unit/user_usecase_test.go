@@ -2,17 +2,24 @@ package unit
 
 import (
 	"context"
+	"fmt"
+	"net/url"
+	"regexp"
 	"testing"
+	"time"
 
 	"github.com/Ikhlashmulya/golang-clean-architecture-project-structure/config"
 	"github.com/Ikhlashmulya/golang-clean-architecture-project-structure/internal/domain"
 	"github.com/Ikhlashmulya/golang-clean-architecture-project-structure/internal/exception"
+	"github.com/Ikhlashmulya/golang-clean-architecture-project-structure/internal/infrastructure"
 	"github.com/Ikhlashmulya/golang-clean-architecture-project-structure/internal/model"
+	"github.com/Ikhlashmulya/golang-clean-architecture-project-structure/internal/repository"
 	"github.com/Ikhlashmulya/golang-clean-architecture-project-structure/internal/usecase"
 	"github.com/Ikhlashmulya/golang-clean-architecture-project-structure/test/unit/mocks"
 	"github.com/go-playground/validator/v10"
 	"github.com/sirupsen/logrus"
 	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel/trace"
 	"go.uber.org/mock/gomock"
 	"golang.org/x/crypto/bcrypt"
 	"gorm.io/gorm"
@@ -25,22 +32,52 @@ var (
 func TestRegisterUser(t *testing.T) {
 	ctrl := gomock.NewController(t)
 	userRepository := mocks.NewMockUserRepository(ctrl)
-	userUsecase := usecase.NewUserUsecase(userRepository, logrus.New(), validator.New(), config.New())
+	mailer := infrastructure.NewInMemoryMailer()
+	userUsecase := newUserUsecase(t, userUsecaseMocks{UserRepository: userRepository, Mailer: mailer})
 
 	t.Run("success", func(t *testing.T) {
-		userRepository.EXPECT().CountByUsername(ctx, "johndoe").Return(int64(0), nil)
-		userRepository.EXPECT().Create(ctx, gomock.Any()).Return(nil)
+		userRepository.EXPECT().CountByUsername(gomock.Any(), "johndoe").Return(int64(0), nil)
+		userRepository.EXPECT().FindDeletedByUsername(gomock.Any(), "johndoe").Return(nil, gorm.ErrRecordNotFound)
+		userRepository.EXPECT().CountByEmail(gomock.Any(), "johndoe@example.com").Return(int64(0), nil)
+		userRepository.EXPECT().Create(gomock.Any(), gomock.Any()).Return(nil)
 
 		request := &model.RegisterUserRequest{
 			Name:     "John Doe",
 			Username: "johndoe",
-			Password: "password",
+			Email:    "johndoe@example.com",
+			Password: "Correct-Horse-42",
 		}
 
 		response, err := userUsecase.Register(ctx, request)
 		assert.NoError(t, err)
 		assert.Equal(t, request.Name, response.Name)
 		assert.Equal(t, request.Username, response.Username)
+		assert.Equal(t, request.Email, response.Email)
+		assert.Zero(t, response.EmailVerifiedAt)
+
+		assert.Eventually(t, func() bool { return len(mailer.Messages()) == 1 }, time.Second, 10*time.Millisecond)
+		assert.Equal(t, request.Email, mailer.Messages()[0].To)
+	})
+
+	t.Run("success email verified by operator", func(t *testing.T) {
+		mailer.Reset()
+		userRepository.EXPECT().CountByUsername(gomock.Any(), "janedoe").Return(int64(0), nil)
+		userRepository.EXPECT().FindDeletedByUsername(gomock.Any(), "janedoe").Return(nil, gorm.ErrRecordNotFound)
+		userRepository.EXPECT().CountByEmail(gomock.Any(), "janedoe@example.com").Return(int64(0), nil)
+		userRepository.EXPECT().Create(gomock.Any(), gomock.Any()).Return(nil)
+
+		response, err := userUsecase.Register(ctx, &model.RegisterUserRequest{
+			Name:          "Jane Doe",
+			Username:      "janedoe",
+			Email:         "janedoe@example.com",
+			Password:      "Correct-Horse-42",
+			EmailVerified: true,
+		})
+		assert.NoError(t, err)
+		assert.NotZero(t, response.EmailVerifiedAt)
+
+		time.Sleep(50 * time.Millisecond)
+		assert.Empty(t, mailer.Messages())
 	})
 
 	t.Run("failed validation", func(t *testing.T) {
@@ -55,28 +92,82 @@ func TestRegisterUser(t *testing.T) {
 	})
 
 	t.Run("failed username already exist", func(t *testing.T) {
-		userRepository.EXPECT().CountByUsername(ctx, "johndoe").Return(int64(1), nil)
+		userRepository.EXPECT().CountByUsername(gomock.Any(), "johndoe").Return(int64(1), nil)
 
 		request := &model.RegisterUserRequest{
 			Name:     "John Doe",
 			Username: "johndoe",
-			Password: "password",
+			Email:    "johndoe@example.com",
+			Password: "Correct-Horse-42",
 		}
 
 		_, err := userUsecase.Register(ctx, request)
 		assert.Error(t, err)
 	})
+
+	t.Run("failed email already exist", func(t *testing.T) {
+		userRepository.EXPECT().CountByUsername(gomock.Any(), "johndoe").Return(int64(0), nil)
+		userRepository.EXPECT().FindDeletedByUsername(gomock.Any(), "johndoe").Return(nil, gorm.ErrRecordNotFound)
+		userRepository.EXPECT().CountByEmail(gomock.Any(), "johndoe@example.com").Return(int64(1), nil)
+
+		request := &model.RegisterUserRequest{
+			Name:     "John Doe",
+			Username: "johndoe",
+			Email:    "johndoe@example.com",
+			Password: "Correct-Horse-42",
+		}
+
+		_, err := userUsecase.Register(ctx, request)
+		assert.Error(t, err)
+		assert.ErrorIs(t, exception.ErrEmailAlreadyExist, err)
+	})
+
+	t.Run("success username canonicalised", func(t *testing.T) {
+		userRepository.EXPECT().CountByUsername(gomock.Any(), "johndoe").Return(int64(1), nil)
+
+		_, err := userUsecase.Register(ctx, &model.RegisterUserRequest{
+			Name:     "John Doe",
+			Username: " ＪｏｈｎＤｏｅ ",
+			Email:    "johndoe@example.com",
+			Password: "Correct-Horse-42",
+		})
+		assert.ErrorIs(t, err, exception.ErrUserAlreadyExist)
+	})
+
+	t.Run("failed username or password rule", func(t *testing.T) {
+		for _, request := range []*model.RegisterUserRequest{
+			{Name: "John Doe", Username: "admin", Email: "johndoe@example.com", Password: "Correct-Horse-42"},
+			{Name: "John Doe", Username: "john doe", Email: "johndoe@example.com", Password: "Correct-Horse-42"},
+			{Name: "John Doe", Username: "johndoe", Email: "johndoe@example.com", Password: "password123"},
+			{Name: "John Doe", Username: "johndoe", Email: "johndoe@example.com", Password: "JohnDoe-Horse-42"},
+		} {
+			_, err := userUsecase.Register(ctx, request)
+			var validationErrors validator.ValidationErrors
+			assert.ErrorAs(t, err, &validationErrors)
+		}
+	})
 }
 
 func TestLoginUser(t *testing.T) {
 	ctrl := gomock.NewController(t)
 	userRepository := mocks.NewMockUserRepository(ctrl)
-	userUsecase := usecase.NewUserUsecase(userRepository, logrus.New(), validator.New(), config.New())
+	refreshTokenRepository := mocks.NewMockRefreshTokenRepository(ctrl)
+	twoFactorRepository := mocks.NewMockTwoFactorRepository(ctrl)
+	roleRepository := mocks.NewMockRoleRepository(ctrl)
+	userUsecase := newUserUsecase(t, userUsecaseMocks{
+		UserRepository:         userRepository,
+		RefreshTokenRepository: refreshTokenRepository,
+		TwoFactorRepository:    twoFactorRepository,
+		RoleRepository:         roleRepository,
+	})
 
 	user := createUser(t)
 
 	t.Run("success", func(t *testing.T) {
-		userRepository.EXPECT().FindByUsername(ctx, "johndoe").Return(user, nil)
+		userRepository.EXPECT().FindByUsername(gomock.Any(), "johndoe").Return(user, nil)
+		twoFactorRepository.EXPECT().FindTOTPByUserID(gomock.Any(), user.ID).Return(nil, gorm.ErrRecordNotFound)
+		roleRepository.EXPECT().FindNamesByUserID(gomock.Any(), user.ID).Return(nil, nil)
+		refreshTokenRepository.EXPECT().Create(gomock.Any(), gomock.Any()).Return(nil)
 
 		response, err := userUsecase.Login(ctx, &model.LoginUserRequest{
 			Username: "johndoe",
@@ -86,10 +177,12 @@ func TestLoginUser(t *testing.T) {
 		assert.NoError(t, err)
 		assert.Equal(t, "Bearer", response.TokenType)
 		assert.NotEmpty(t, response.TokenType)
+		assert.NotEmpty(t, response.RefreshToken)
+		assert.NotZero(t, response.ExpiresIn)
 	})
 
 	t.Run("failed user not found", func(t *testing.T) {
-		userRepository.EXPECT().FindByUsername(ctx, "johndoe").Return(nil, gorm.ErrRecordNotFound)
+		userRepository.EXPECT().FindByUsername(gomock.Any(), "johndoe").Return(nil, gorm.ErrRecordNotFound)
 
 		_, err := userUsecase.Login(ctx, &model.LoginUserRequest{
 			Username: "johndoe",
@@ -101,7 +194,7 @@ func TestLoginUser(t *testing.T) {
 	})
 
 	t.Run("failed password not match", func(t *testing.T) {
-		userRepository.EXPECT().FindByUsername(ctx, "johndoe").Return(user, nil)
+		userRepository.EXPECT().FindByUsername(gomock.Any(), "johndoe").Return(user, nil)
 
 		_, err := userUsecase.Login(ctx, &model.LoginUserRequest{
 			Username: "johndoe",
@@ -122,16 +215,443 @@ func TestLoginUser(t *testing.T) {
 	})
 }
 
+func TestLoginThrottle(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	userRepository := mocks.NewMockUserRepository(ctrl)
+	refreshTokenRepository := mocks.NewMockRefreshTokenRepository(ctrl)
+	twoFactorRepository := mocks.NewMockTwoFactorRepository(ctrl)
+	roleRepository := mocks.NewMockRoleRepository(ctrl)
+	cfg := config.New()
+	cfg.Auth.LoginThrottle.FreeAttempts = 3
+	cfg.Auth.LoginThrottle.LockoutThreshold = 3
+	cfg.Auth.LoginThrottle.IPFreeAttempts = 5
+	cfg.Auth.LoginThrottle.IPLockoutThreshold = 5
+	cfg.Auth.LoginThrottle.LockoutDuration = 900 * time.Second
+	userUsecase := newUserUsecase(t, userUsecaseMocks{
+		UserRepository:         userRepository,
+		RefreshTokenRepository: refreshTokenRepository,
+		TwoFactorRepository:    twoFactorRepository,
+		RoleRepository:         roleRepository,
+		Config:                 cfg,
+	})
+
+	user := createUser(t)
+
+	t.Run("failed locked after repeated failures", func(t *testing.T) {
+		userRepository.EXPECT().FindByUsername(gomock.Any(), "johndoe").Return(user, nil).Times(3)
+
+		for i := 0; i < 3; i++ {
+			_, err := userUsecase.Login(ctx, &model.LoginUserRequest{Username: "johndoe", Password: "wrongPassword"})
+			assert.ErrorIs(t, err, exception.ErrUserPasswordNotMatch)
+		}
+
+		// the correct password is not even checked while locked
+		_, err := userUsecase.Login(ctx, &model.LoginUserRequest{Username: "johndoe", Password: "password"})
+		assert.ErrorIs(t, err, exception.ErrUserLocked)
+
+		lockedError := new(exception.LockedError)
+		assert.ErrorAs(t, err, &lockedError)
+		assert.InDelta(t, 900*time.Second, lockedError.RetryAfter, float64(5*time.Second))
+	})
+
+	t.Run("success unlock", func(t *testing.T) {
+		userRepository.EXPECT().FindByUsername(gomock.Any(), "johndoe").Return(user, nil)
+
+		err := userUsecase.UnlockUser(ctx, &model.UnlockUserRequest{Username: "johndoe"})
+		assert.NoError(t, err)
+
+		userRepository.EXPECT().FindByUsername(gomock.Any(), "johndoe").Return(user, nil)
+		twoFactorRepository.EXPECT().FindTOTPByUserID(gomock.Any(), user.ID).Return(nil, gorm.ErrRecordNotFound)
+		roleRepository.EXPECT().FindNamesByUserID(gomock.Any(), user.ID).Return(nil, nil)
+		refreshTokenRepository.EXPECT().Create(gomock.Any(), gomock.Any()).Return(nil)
+
+		response, err := userUsecase.Login(ctx, &model.LoginUserRequest{Username: "johndoe", Password: "password"})
+		assert.NoError(t, err)
+		assert.NotEmpty(t, response.AccessToken)
+	})
+
+	t.Run("failed unlock user not found", func(t *testing.T) {
+		userRepository.EXPECT().FindByUsername(gomock.Any(), "janedoe").Return(nil, gorm.ErrRecordNotFound)
+
+		err := userUsecase.UnlockUser(ctx, &model.UnlockUserRequest{Username: "janedoe"})
+		assert.ErrorIs(t, err, exception.ErrUserNotFound)
+	})
+
+	t.Run("failed locked after repeated wrong two-factor codes", func(t *testing.T) {
+		userTOTP := &domain.UserTOTP{UserID: user.ID, Secret: "JBSWY3DPEHPK3PXP", ConfirmedAt: 12345}
+
+		for i := 0; i < 3; i++ {
+			userRepository.EXPECT().FindByUsername(gomock.Any(), "johndoe").Return(user, nil)
+			twoFactorRepository.EXPECT().FindTOTPByUserID(gomock.Any(), user.ID).Return(userTOTP, nil).Times(2)
+			userRepository.EXPECT().FindByID(gomock.Any(), user.ID).Return(user, nil)
+			twoFactorRepository.EXPECT().UseRecoveryCode(gomock.Any(), user.ID, gomock.Any(), gomock.Any()).Return(false, nil)
+
+			// the password is right every time, that must not clear the failures of the codes
+			response, err := userUsecase.Login(ctx, &model.LoginUserRequest{Username: "johndoe", Password: "password", IPAddress: "192.0.2.2"})
+			assert.NoError(t, err)
+
+			_, err = userUsecase.LoginMFA(ctx, &model.LoginMFARequest{MFAToken: response.MFAToken, Code: "ABCDE-FGHJK", IPAddress: "192.0.2.2"})
+			assert.ErrorIs(t, err, exception.ErrMFACodeInvalid)
+		}
+
+		_, err := userUsecase.Login(ctx, &model.LoginUserRequest{Username: "johndoe", Password: "password", IPAddress: "192.0.2.2"})
+		assert.ErrorIs(t, err, exception.ErrUserLocked)
+
+		userRepository.EXPECT().FindByUsername(gomock.Any(), "johndoe").Return(user, nil)
+		assert.NoError(t, userUsecase.UnlockUser(ctx, &model.UnlockUserRequest{Username: "johndoe"}))
+	})
+
+	t.Run("failed ip locked across usernames", func(t *testing.T) {
+		userRepository.EXPECT().FindByUsername(gomock.Any(), gomock.Any()).Return(nil, gorm.ErrRecordNotFound).Times(5)
+
+		for i := 0; i < 5; i++ {
+			_, err := userUsecase.Login(ctx, &model.LoginUserRequest{
+				Username:  fmt.Sprintf("guess%d", i),
+				Password:  "password",
+				IPAddress: "192.0.2.1",
+			})
+			assert.ErrorIs(t, err, exception.ErrUserNotFound)
+		}
+
+		_, err := userUsecase.Login(ctx, &model.LoginUserRequest{Username: "johndoe", Password: "password", IPAddress: "192.0.2.1"})
+		assert.ErrorIs(t, err, exception.ErrUserLocked)
+	})
+}
+
+func TestUserRoles(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	userRepository := mocks.NewMockUserRepository(ctrl)
+	refreshTokenRepository := mocks.NewMockRefreshTokenRepository(ctrl)
+	twoFactorRepository := mocks.NewMockTwoFactorRepository(ctrl)
+	roleRepository := mocks.NewMockRoleRepository(ctrl)
+	userUsecase := newUserUsecase(t, userUsecaseMocks{
+		UserRepository:         userRepository,
+		RefreshTokenRepository: refreshTokenRepository,
+		TwoFactorRepository:    twoFactorRepository,
+		RoleRepository:         roleRepository,
+	})
+
+	user := createUser(t)
+	role := &domain.Role{ID: 1, Name: "admin"}
+
+	login := func(t *testing.T, roles []string) string {
+		userRepository.EXPECT().FindByUsername(gomock.Any(), "johndoe").Return(user, nil)
+		twoFactorRepository.EXPECT().FindTOTPByUserID(gomock.Any(), user.ID).Return(nil, gorm.ErrRecordNotFound)
+		roleRepository.EXPECT().FindNamesByUserID(gomock.Any(), user.ID).Return(roles, nil)
+		refreshTokenRepository.EXPECT().Create(gomock.Any(), gomock.Any()).Return(nil)
+
+		response, err := userUsecase.Login(ctx, &model.LoginUserRequest{Username: "johndoe", Password: "password"})
+		assert.NoError(t, err)
+
+		return response.AccessToken
+	}
+
+	t.Run("success verify resolves permissions", func(t *testing.T) {
+		accessToken := login(t, []string{"admin"})
+
+		userRepository.EXPECT().CountByUsername(gomock.Any(), "johndoe").Return(int64(1), nil)
+		roleRepository.EXPECT().FindPermissionNamesByRoleNames(gomock.Any(), []string{"admin"}).Return([]string{"roles:write", "users:read"}, nil)
+
+		auth, err := userUsecase.Verify(ctx, &model.VerifyUserRequest{AccessToken: accessToken})
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"admin"}, auth.Roles)
+		assert.Equal(t, []string{"roles:write", "users:read"}, auth.Permissions)
+	})
+
+	t.Run("success verify without roles", func(t *testing.T) {
+		accessToken := login(t, nil)
+
+		userRepository.EXPECT().CountByUsername(gomock.Any(), "johndoe").Return(int64(1), nil)
+
+		auth, err := userUsecase.Verify(ctx, &model.VerifyUserRequest{AccessToken: accessToken})
+		assert.NoError(t, err)
+		assert.Empty(t, auth.Roles)
+		assert.Empty(t, auth.Permissions)
+	})
+
+	t.Run("success grant role", func(t *testing.T) {
+		userRepository.EXPECT().FindByUsername(gomock.Any(), "johndoe").Return(user, nil)
+		roleRepository.EXPECT().FindByName(gomock.Any(), "admin").Return(role, nil)
+		roleRepository.EXPECT().AddUserRole(gomock.Any(), &domain.UserRole{UserID: user.ID, RoleID: role.ID}).Return(nil)
+
+		err := userUsecase.GrantRole(ctx, &model.UserRoleRequest{Username: "JohnDoe", Role: "admin"})
+		assert.NoError(t, err)
+	})
+
+	t.Run("failed grant unknown role", func(t *testing.T) {
+		userRepository.EXPECT().FindByUsername(gomock.Any(), "johndoe").Return(user, nil)
+		roleRepository.EXPECT().FindByName(gomock.Any(), "superuser").Return(nil, gorm.ErrRecordNotFound)
+
+		err := userUsecase.GrantRole(ctx, &model.UserRoleRequest{Username: "johndoe", Role: "superuser"})
+		assert.ErrorIs(t, err, exception.ErrRoleNotFound)
+	})
+
+	t.Run("success revoke role invalidates access tokens", func(t *testing.T) {
+		accessToken := login(t, []string{"admin"})
+
+		userRepository.EXPECT().FindByUsername(gomock.Any(), "johndoe").Return(user, nil)
+		roleRepository.EXPECT().FindByName(gomock.Any(), "admin").Return(role, nil)
+		roleRepository.EXPECT().RemoveUserRole(gomock.Any(), user.ID, role.ID).Return(true, nil)
+
+		err := userUsecase.RevokeRole(ctx, &model.UserRoleRequest{Username: "johndoe", Role: "admin"})
+		assert.NoError(t, err)
+
+		_, err = userUsecase.Verify(ctx, &model.VerifyUserRequest{AccessToken: accessToken})
+		assert.ErrorIs(t, err, exception.ErrUserUnauthorized)
+	})
+}
+
+func TestLockUser(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	userRepository := mocks.NewMockUserRepository(ctrl)
+	refreshTokenRepository := mocks.NewMockRefreshTokenRepository(ctrl)
+	tokenRevocationStore := repository.NewInMemoryTokenRevocationStore()
+	userUsecase := newUserUsecase(t, userUsecaseMocks{
+		UserRepository:         userRepository,
+		RefreshTokenRepository: refreshTokenRepository,
+		TokenRevocationStore:   tokenRevocationStore,
+	})
+
+	user := createUser(t)
+
+	t.Run("success lock", func(t *testing.T) {
+		userRepository.EXPECT().FindByUsername(gomock.Any(), "johndoe").Return(user, nil)
+		userRepository.EXPECT().Update(gomock.Any(), user).Return(nil)
+		refreshTokenRepository.EXPECT().RevokeByUserID(gomock.Any(), user.ID, gomock.Any()).Return(nil)
+
+		err := userUsecase.LockUser(ctx, &model.LockUserRequest{Username: "JohnDoe"})
+		assert.NoError(t, err)
+		assert.NotZero(t, user.LockedAt)
+
+		revokedAt, err := tokenRevocationStore.FindRevokedAtByUserID(ctx, user.ID)
+		assert.NoError(t, err)
+		assert.NotZero(t, revokedAt)
+	})
+
+	t.Run("failed login locked account", func(t *testing.T) {
+		userRepository.EXPECT().FindByUsername(gomock.Any(), "johndoe").Return(user, nil)
+
+		_, err := userUsecase.Login(ctx, &model.LoginUserRequest{Username: "johndoe", Password: "password"})
+		assert.ErrorIs(t, err, exception.ErrAccountLocked)
+	})
+
+	t.Run("success unlock", func(t *testing.T) {
+		userRepository.EXPECT().FindByUsername(gomock.Any(), "johndoe").Return(user, nil)
+		userRepository.EXPECT().Update(gomock.Any(), user).Return(nil)
+
+		err := userUsecase.UnlockUser(ctx, &model.UnlockUserRequest{Username: "JohnDoe"})
+		assert.NoError(t, err)
+		assert.Zero(t, user.LockedAt)
+	})
+
+	t.Run("failed lock user not found", func(t *testing.T) {
+		userRepository.EXPECT().FindByUsername(gomock.Any(), "janedoe").Return(nil, gorm.ErrRecordNotFound)
+
+		err := userUsecase.LockUser(ctx, &model.LockUserRequest{Username: "janedoe"})
+		assert.ErrorIs(t, err, exception.ErrUserNotFound)
+	})
+}
+
+func TestSetPassword(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	userRepository := mocks.NewMockUserRepository(ctrl)
+	refreshTokenRepository := mocks.NewMockRefreshTokenRepository(ctrl)
+	userUsecase := newUserUsecase(t, userUsecaseMocks{
+		UserRepository:         userRepository,
+		RefreshTokenRepository: refreshTokenRepository,
+	})
+
+	user := createUser(t)
+
+	t.Run("success", func(t *testing.T) {
+		userRepository.EXPECT().FindByUsername(gomock.Any(), "johndoe").Return(user, nil)
+		userRepository.EXPECT().Update(gomock.Any(), user).Return(nil)
+		refreshTokenRepository.EXPECT().RevokeByUserID(gomock.Any(), user.ID, gomock.Any()).Return(nil)
+
+		err := userUsecase.SetPassword(ctx, &model.SetPasswordRequest{Username: "JohnDoe", Password: "Battery-Staple-77"})
+		assert.NoError(t, err)
+		assert.NoError(t, bcrypt.CompareHashAndPassword([]byte(user.Password), []byte("Battery-Staple-77")))
+	})
+
+	t.Run("failed validation", func(t *testing.T) {
+		err := userUsecase.SetPassword(ctx, &model.SetPasswordRequest{Username: "johndoe", Password: "short"})
+		assert.Error(t, err)
+	})
+}
+
+func TestDeleteUser(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	userRepository := mocks.NewMockUserRepository(ctrl)
+	refreshTokenRepository := mocks.NewMockRefreshTokenRepository(ctrl)
+	tokenRevocationStore := repository.NewInMemoryTokenRevocationStore()
+	userUsecase := newUserUsecase(t, userUsecaseMocks{
+		UserRepository:         userRepository,
+		RefreshTokenRepository: refreshTokenRepository,
+		TokenRevocationStore:   tokenRevocationStore,
+	})
+
+	user := createUser(t)
+
+	t.Run("success", func(t *testing.T) {
+		userRepository.EXPECT().FindByID(gomock.Any(), user.ID).Return(user, nil)
+		userRepository.EXPECT().Delete(gomock.Any(), user).Return(nil)
+		refreshTokenRepository.EXPECT().RevokeByUserID(gomock.Any(), user.ID, gomock.Any()).Return(nil)
+
+		err := userUsecase.Delete(ctx, &model.DeleteUserRequest{ID: user.ID, Password: "password"})
+		assert.NoError(t, err)
+
+		revokedAt, err := tokenRevocationStore.FindRevokedAtByUserID(ctx, user.ID)
+		assert.NoError(t, err)
+		assert.NotZero(t, revokedAt)
+	})
+
+	t.Run("failed password not match", func(t *testing.T) {
+		userRepository.EXPECT().FindByID(gomock.Any(), user.ID).Return(user, nil)
+
+		err := userUsecase.Delete(ctx, &model.DeleteUserRequest{ID: user.ID, Password: "wrongPassword"})
+		assert.ErrorIs(t, err, exception.ErrUserPasswordNotMatch)
+	})
+
+	t.Run("failed register username of deleted user", func(t *testing.T) {
+		userRepository.EXPECT().CountByUsername(gomock.Any(), "johndoe").Return(int64(0), nil)
+		userRepository.EXPECT().FindDeletedByUsername(gomock.Any(), "johndoe").Return(user, nil)
+
+		_, err := userUsecase.Register(ctx, &model.RegisterUserRequest{
+			Name:     "John Doe",
+			Username: "johndoe",
+			Email:    "johndoe@example.com",
+			Password: "Correct-Horse-42",
+		})
+		assert.ErrorIs(t, err, exception.ErrUserAlreadyExist)
+	})
+
+	t.Run("success restore", func(t *testing.T) {
+		deletedUser := *user
+		deletedUser.DeletedAt = gorm.DeletedAt{Time: time.Now().Add(-time.Hour), Valid: true}
+
+		userRepository.EXPECT().FindDeletedByUsername(gomock.Any(), "johndoe").Return(&deletedUser, nil)
+		userRepository.EXPECT().Restore(gomock.Any(), &deletedUser).Return(nil)
+
+		response, err := userUsecase.Restore(ctx, &model.RestoreUserRequest{Username: "johndoe", Password: "password"})
+		assert.NoError(t, err)
+		assert.Equal(t, user.Username, response.Username)
+	})
+
+	t.Run("failed restore after restore window", func(t *testing.T) {
+		deletedUser := *user
+		deletedUser.DeletedAt = gorm.DeletedAt{Time: time.Now().Add(-30 * 24 * time.Hour), Valid: true}
+
+		userRepository.EXPECT().FindDeletedByUsername(gomock.Any(), "johndoe").Return(&deletedUser, nil)
+
+		_, err := userUsecase.Restore(ctx, &model.RestoreUserRequest{Username: "johndoe", Password: "password"})
+		assert.ErrorIs(t, err, exception.ErrUserNotFound)
+	})
+
+	t.Run("success purge", func(t *testing.T) {
+		userRepository.EXPECT().PurgeDeleted(gomock.Any(), gomock.Any()).
+			DoAndReturn(func(ctx context.Context, deletedBefore time.Time) (int64, error) {
+				assert.WithinDuration(t, time.Now().Add(-30*24*time.Hour), deletedBefore, time.Minute)
+				return 2, nil
+			})
+
+		purged, err := userUsecase.PurgeDeleted(ctx)
+		assert.NoError(t, err)
+		assert.Equal(t, int64(2), purged)
+	})
+}
+
+func TestListUsers(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	userRepository := mocks.NewMockUserRepository(ctrl)
+	userUsecase := newUserUsecase(t, userUsecaseMocks{UserRepository: userRepository})
+
+	users := []domain.User{
+		{ID: 1, Name: "John Doe", Username: "johndoe", CreatedAt: 1000},
+		{ID: 2, Name: "Jane Doe", Username: "janedoe", CreatedAt: 2000},
+		{ID: 3, Name: "Jim Doe", Username: "jimdoe", CreatedAt: 3000},
+	}
+
+	t.Run("success offset pagination", func(t *testing.T) {
+		userRepository.EXPECT().Search(gomock.Any(), &repository.UserSearch{
+			NamePrefix:  "J",
+			CreatedFrom: 1000,
+			SortBy:      "name",
+			Descending:  true,
+			Offset:      2,
+			Limit:       2,
+			WithTotal:   true,
+		}).Return(users[2:], int64(3), nil)
+
+		responses, paging, err := userUsecase.List(ctx, &model.SearchUserRequest{
+			Name:         "J",
+			CreatedAfter: 1000,
+			Sort:         "-name",
+			Page:         2,
+			Size:         2,
+		})
+		assert.NoError(t, err)
+		assert.Len(t, responses, 1)
+		assert.Equal(t, "jimdoe", responses[0].Username)
+		assert.Equal(t, &model.PageMetadata{Page: 2, Size: 2, TotalItem: 3, TotalPage: 2}, paging)
+	})
+
+	t.Run("success cursor pagination", func(t *testing.T) {
+		userRepository.EXPECT().Search(gomock.Any(), &repository.UserSearch{SortBy: "created_at", Limit: 3}).Return(users, int64(0), nil)
+
+		responses, paging, err := userUsecase.List(ctx, &model.SearchUserRequest{Sort: "created_at", Size: 2})
+		assert.NoError(t, err)
+		assert.Len(t, responses, 2)
+		assert.NotEmpty(t, paging.NextCursor)
+
+		userRepository.EXPECT().Search(gomock.Any(), &repository.UserSearch{
+			SortBy: "created_at",
+			Limit:  3,
+			After:  &repository.UserSearchCursor{Value: int64(2000), ID: 2},
+		}).Return(users[2:], int64(0), nil)
+
+		responses, paging, err = userUsecase.List(ctx, &model.SearchUserRequest{Sort: "created_at", Size: 2, Cursor: paging.NextCursor})
+		assert.NoError(t, err)
+		assert.Len(t, responses, 1)
+		assert.Empty(t, paging.NextCursor)
+	})
+
+	t.Run("failed cursor from another sort", func(t *testing.T) {
+		userRepository.EXPECT().Search(gomock.Any(), gomock.Any()).Return(users, int64(0), nil)
+
+		_, paging, err := userUsecase.List(ctx, &model.SearchUserRequest{Sort: "username", Size: 1})
+		assert.NoError(t, err)
+
+		_, _, err = userUsecase.List(ctx, &model.SearchUserRequest{Sort: "-username", Cursor: paging.NextCursor})
+		assert.ErrorIs(t, err, exception.ErrCursorInvalid)
+	})
+
+	t.Run("failed invalid cursor", func(t *testing.T) {
+		_, _, err := userUsecase.List(ctx, &model.SearchUserRequest{Cursor: "not a cursor"})
+		assert.ErrorIs(t, err, exception.ErrCursorInvalid)
+	})
+
+	t.Run("failed validation", func(t *testing.T) {
+		_, _, err := userUsecase.List(ctx, &model.SearchUserRequest{Page: 1, Cursor: "abc"})
+		assert.Error(t, err)
+
+		_, _, err = userUsecase.List(ctx, &model.SearchUserRequest{Sort: "password"})
+		assert.Error(t, err)
+
+		_, _, err = userUsecase.List(ctx, &model.SearchUserRequest{Size: 1000})
+		assert.Error(t, err)
+	})
+}
+
 func TestUpdateUser(t *testing.T) {
 	ctrl := gomock.NewController(t)
 	userRepository := mocks.NewMockUserRepository(ctrl)
-	userUsecase := usecase.NewUserUsecase(userRepository, logrus.New(), validator.New(), config.New())
+	userUsecase := newUserUsecase(t, userUsecaseMocks{UserRepository: userRepository})
 
 	user := createUser(t)
 
 	t.Run("success", func(t *testing.T) {
-		userRepository.EXPECT().FindByUsername(ctx, "johndoe").Return(user, nil)
-		userRepository.EXPECT().Update(ctx, gomock.Any()).Return(nil)
+		userRepository.EXPECT().FindByUsername(gomock.Any(), "johndoe").Return(user, nil)
+		userRepository.EXPECT().Update(gomock.Any(), gomock.Any()).Return(nil)
 
 		request := &model.UpdateUserRequest{
 			Username: "johndoe",
@@ -144,7 +664,7 @@ func TestUpdateUser(t *testing.T) {
 	})
 
 	t.Run("failed user not found", func(t *testing.T) {
-		userRepository.EXPECT().FindByUsername(ctx, "johndoe").Return(nil, gorm.ErrRecordNotFound)
+		userRepository.EXPECT().FindByUsername(gomock.Any(), "johndoe").Return(nil, gorm.ErrRecordNotFound)
 
 		request := &model.UpdateUserRequest{
 			Username: "johndoe",
@@ -161,12 +681,12 @@ func TestUpdateUser(t *testing.T) {
 func TestCurrentUser(t *testing.T) {
 	ctrl := gomock.NewController(t)
 	userRepository := mocks.NewMockUserRepository(ctrl)
-	userUsecase := usecase.NewUserUsecase(userRepository, logrus.New(), validator.New(), config.New())
+	userUsecase := newUserUsecase(t, userUsecaseMocks{UserRepository: userRepository})
 
 	user := createUser(t)
 
 	t.Run("success", func(t *testing.T) {
-		userRepository.EXPECT().FindByUsername(ctx, "johndoe").Return(user, nil)
+		userRepository.EXPECT().FindByUsername(gomock.Any(), "johndoe").Return(user, nil)
 
 		response, err := userUsecase.Current(ctx, &model.GetUserRequest{Username: "johndoe"})
 		assert.NoError(t, err)
@@ -178,7 +698,7 @@ func TestCurrentUser(t *testing.T) {
 	})
 
 	t.Run("failed not found", func(t *testing.T) {
-		userRepository.EXPECT().FindByUsername(ctx, "johndoe").Return(nil, gorm.ErrRecordNotFound)
+		userRepository.EXPECT().FindByUsername(gomock.Any(), "johndoe").Return(nil, gorm.ErrRecordNotFound)
 
 		_, err := userUsecase.Current(ctx, &model.GetUserRequest{Username: "johndoe"})
 		assert.Error(t, err)
@@ -189,12 +709,23 @@ func TestCurrentUser(t *testing.T) {
 func TestVerifyUser(t *testing.T) {
 	ctrl := gomock.NewController(t)
 	userRepository := mocks.NewMockUserRepository(ctrl)
-	userUsecase := usecase.NewUserUsecase(userRepository, logrus.New(), validator.New(), config.New())
+	refreshTokenRepository := mocks.NewMockRefreshTokenRepository(ctrl)
+	twoFactorRepository := mocks.NewMockTwoFactorRepository(ctrl)
+	roleRepository := mocks.NewMockRoleRepository(ctrl)
+	userUsecase := newUserUsecase(t, userUsecaseMocks{
+		UserRepository:         userRepository,
+		RefreshTokenRepository: refreshTokenRepository,
+		TwoFactorRepository:    twoFactorRepository,
+		RoleRepository:         roleRepository,
+	})
 
 	user := createUser(t)
 
 	t.Run("success", func(t *testing.T) {
-		userRepository.EXPECT().FindByUsername(ctx, "johndoe").Return(user, nil)
+		userRepository.EXPECT().FindByUsername(gomock.Any(), "johndoe").Return(user, nil)
+		twoFactorRepository.EXPECT().FindTOTPByUserID(gomock.Any(), user.ID).Return(nil, gorm.ErrRecordNotFound)
+		roleRepository.EXPECT().FindNamesByUserID(gomock.Any(), user.ID).Return(nil, nil)
+		refreshTokenRepository.EXPECT().Create(gomock.Any(), gomock.Any()).Return(nil)
 
 		response, err := userUsecase.Login(ctx, &model.LoginUserRequest{
 			Username: "johndoe",
@@ -202,7 +733,7 @@ func TestVerifyUser(t *testing.T) {
 		})
 		assert.NoError(t, err)
 
-		userRepository.EXPECT().CountByUsername(ctx, "johndoe").Return(int64(1), nil)
+		userRepository.EXPECT().CountByUsername(gomock.Any(), "johndoe").Return(int64(1), nil)
 
 		auth, err := userUsecase.Verify(ctx, &model.VerifyUserRequest{AccessToken: response.AccessToken})
 		assert.NoError(t, err)
@@ -216,18 +747,634 @@ func TestVerifyUser(t *testing.T) {
 	})
 }
 
+func TestLogoutUser(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	userRepository := mocks.NewMockUserRepository(ctrl)
+	refreshTokenRepository := mocks.NewMockRefreshTokenRepository(ctrl)
+	twoFactorRepository := mocks.NewMockTwoFactorRepository(ctrl)
+	roleRepository := mocks.NewMockRoleRepository(ctrl)
+	userUsecase := newUserUsecase(t, userUsecaseMocks{
+		UserRepository:         userRepository,
+		RefreshTokenRepository: refreshTokenRepository,
+		TwoFactorRepository:    twoFactorRepository,
+		RoleRepository:         roleRepository,
+	})
+
+	user := createUser(t)
+
+	login := func(t *testing.T) (string, *model.Auth) {
+		userRepository.EXPECT().FindByUsername(gomock.Any(), "johndoe").Return(user, nil)
+		twoFactorRepository.EXPECT().FindTOTPByUserID(gomock.Any(), user.ID).Return(nil, gorm.ErrRecordNotFound)
+		roleRepository.EXPECT().FindNamesByUserID(gomock.Any(), user.ID).Return(nil, nil)
+		refreshTokenRepository.EXPECT().Create(gomock.Any(), gomock.Any()).Return(nil)
+
+		response, err := userUsecase.Login(ctx, &model.LoginUserRequest{
+			Username: "johndoe",
+			Password: "password",
+		})
+		assert.NoError(t, err)
+
+		userRepository.EXPECT().CountByUsername(gomock.Any(), "johndoe").Return(int64(1), nil)
+
+		auth, err := userUsecase.Verify(ctx, &model.VerifyUserRequest{AccessToken: response.AccessToken})
+		assert.NoError(t, err)
+		assert.NotEmpty(t, auth.TokenID)
+		assert.NotEmpty(t, auth.SessionID)
+
+		return response.AccessToken, auth
+	}
+
+	t.Run("success logout current session", func(t *testing.T) {
+		accessToken, auth := login(t)
+		refreshTokenRepository.EXPECT().RevokeFamily(gomock.Any(), auth.SessionID, gomock.Any()).Return(nil)
+
+		err := userUsecase.Logout(ctx, &model.LogoutUserRequest{
+			ID:        auth.ID,
+			TokenID:   auth.TokenID,
+			SessionID: auth.SessionID,
+			ExpiresAt: auth.ExpiresAt,
+		})
+		assert.NoError(t, err)
+
+		_, err = userUsecase.Verify(ctx, &model.VerifyUserRequest{AccessToken: accessToken})
+		assert.Error(t, err)
+		assert.ErrorIs(t, exception.ErrUserUnauthorized, err)
+	})
+
+	t.Run("success logout all sessions", func(t *testing.T) {
+		accessToken, auth := login(t)
+		refreshTokenRepository.EXPECT().RevokeByUserID(gomock.Any(), auth.ID, gomock.Any()).Return(nil)
+
+		err := userUsecase.LogoutAll(ctx, &model.LogoutUserRequest{ID: auth.ID, TokenID: auth.TokenID})
+		assert.NoError(t, err)
+
+		_, err = userUsecase.Verify(ctx, &model.VerifyUserRequest{AccessToken: accessToken})
+		assert.Error(t, err)
+		assert.ErrorIs(t, exception.ErrUserUnauthorized, err)
+	})
+
+	t.Run("success login in the same second as logout all", func(t *testing.T) {
+		// start at the beginning of a second so both happen within it
+		time.Sleep(time.Until(time.Now().Truncate(time.Second).Add(time.Second)))
+
+		_, auth := login(t)
+		refreshTokenRepository.EXPECT().RevokeByUserID(gomock.Any(), auth.ID, gomock.Any()).Return(nil)
+		loggedOutAt := time.Now().Unix()
+
+		err := userUsecase.LogoutAll(ctx, &model.LogoutUserRequest{ID: auth.ID, TokenID: auth.TokenID})
+		assert.NoError(t, err)
+
+		// login verifies the new access token
+		login(t)
+		assert.Equal(t, loggedOutAt, time.Now().Unix())
+	})
+
+	t.Run("failed validation", func(t *testing.T) {
+		err := userUsecase.Logout(ctx, &model.LogoutUserRequest{})
+		assert.Error(t, err)
+	})
+}
+
+func TestVerifyEmail(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	userRepository := mocks.NewMockUserRepository(ctrl)
+	mailer := infrastructure.NewInMemoryMailer()
+	cfg := config.New()
+	cfg.Auth.AllowUnverifiedLogin = false
+	userUsecase := newUserUsecase(t, userUsecaseMocks{UserRepository: userRepository, Mailer: mailer, Config: cfg})
+
+	user := createUser(t)
+	user.EmailVerifiedAt = 0
+
+	register := func(t *testing.T) string {
+		userRepository.EXPECT().CountByUsername(gomock.Any(), "johndoe").Return(int64(0), nil)
+		userRepository.EXPECT().FindDeletedByUsername(gomock.Any(), "johndoe").Return(nil, gorm.ErrRecordNotFound)
+		userRepository.EXPECT().CountByEmail(gomock.Any(), "johndoe@example.com").Return(int64(0), nil)
+		userRepository.EXPECT().Create(gomock.Any(), gomock.Any()).DoAndReturn(func(_ context.Context, created *domain.User) error {
+			created.ID = user.ID
+			return nil
+		})
+
+		sent := len(mailer.Messages())
+		_, err := userUsecase.Register(ctx, &model.RegisterUserRequest{
+			Name:     "John Doe",
+			Username: "johndoe",
+			Email:    "johndoe@example.com",
+			Password: "Correct-Horse-42",
+		})
+		assert.NoError(t, err)
+
+		assert.Eventually(t, func() bool { return len(mailer.Messages()) > sent }, time.Second, 10*time.Millisecond)
+		link, err := url.Parse(regexp.MustCompile(`https?://\S+`).FindString(mailer.Messages()[sent].Body))
+		assert.NoError(t, err)
+
+		return link.Query().Get("token")
+	}
+
+	t.Run("failed login unverified", func(t *testing.T) {
+		userRepository.EXPECT().FindByUsername(gomock.Any(), "johndoe").Return(user, nil)
+
+		_, err := userUsecase.Login(ctx, &model.LoginUserRequest{Username: "johndoe", Password: "password"})
+		assert.Error(t, err)
+		assert.ErrorIs(t, exception.ErrEmailNotVerified, err)
+	})
+
+	t.Run("success", func(t *testing.T) {
+		token := register(t)
+
+		userRepository.EXPECT().FindByID(gomock.Any(), user.ID).Return(user, nil)
+		userRepository.EXPECT().Update(gomock.Any(), gomock.Any()).Return(nil)
+
+		response, err := userUsecase.VerifyEmail(ctx, &model.VerifyEmailRequest{Token: token})
+		assert.NoError(t, err)
+		assert.NotZero(t, response.EmailVerifiedAt)
+	})
+
+	t.Run("failed email changed", func(t *testing.T) {
+		token := register(t)
+
+		changed := *user
+		email := "janedoe@example.com"
+		changed.Email = &email
+		userRepository.EXPECT().FindByID(gomock.Any(), user.ID).Return(&changed, nil)
+
+		_, err := userUsecase.VerifyEmail(ctx, &model.VerifyEmailRequest{Token: token})
+		assert.Error(t, err)
+		assert.ErrorIs(t, exception.ErrEmailTokenInvalid, err)
+	})
+
+	t.Run("failed verification token used as access token", func(t *testing.T) {
+		token := register(t)
+
+		_, err := userUsecase.Verify(ctx, &model.VerifyUserRequest{AccessToken: token})
+		assert.Error(t, err)
+		assert.ErrorIs(t, exception.ErrUserUnauthorized, err)
+	})
+
+	t.Run("failed invalid token", func(t *testing.T) {
+		_, err := userUsecase.VerifyEmail(ctx, &model.VerifyEmailRequest{Token: "wrongToken"})
+		assert.Error(t, err)
+		assert.ErrorIs(t, exception.ErrEmailTokenInvalid, err)
+	})
+}
+
+func TestForgotPassword(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	userRepository := mocks.NewMockUserRepository(ctrl)
+	passwordResetTokenRepository := mocks.NewMockPasswordResetTokenRepository(ctrl)
+	mailer := infrastructure.NewInMemoryMailer()
+	userUsecase := newUserUsecase(t, userUsecaseMocks{
+		UserRepository:               userRepository,
+		PasswordResetTokenRepository: passwordResetTokenRepository,
+		Mailer:                       mailer,
+	})
+
+	user := createUser(t)
+
+	t.Run("success", func(t *testing.T) {
+		userRepository.EXPECT().FindByUsername(gomock.Any(), "johndoe").Return(user, nil)
+		passwordResetTokenRepository.EXPECT().MarkUsedByUserID(gomock.Any(), user.ID, gomock.Any()).Return(nil)
+		passwordResetTokenRepository.EXPECT().Create(gomock.Any(), gomock.Any()).Return(nil)
+
+		err := userUsecase.ForgotPassword(ctx, &model.ForgotPasswordRequest{Username: "JohnDoe"})
+		assert.NoError(t, err)
+
+		assert.Eventually(t, func() bool { return len(mailer.Messages()) == 1 }, time.Second, 10*time.Millisecond)
+		assert.Contains(t, mailer.Messages()[0].Body, "token=")
+	})
+
+	t.Run("success unknown user", func(t *testing.T) {
+		done := make(chan struct{})
+		userRepository.EXPECT().FindByUsername(gomock.Any(), "janedoe").DoAndReturn(func(context.Context, string) (*domain.User, error) {
+			close(done)
+			return nil, gorm.ErrRecordNotFound
+		})
+
+		err := userUsecase.ForgotPassword(ctx, &model.ForgotPasswordRequest{Username: "janedoe"})
+		assert.NoError(t, err)
+
+		<-done
+		assert.Len(t, mailer.Messages(), 1)
+	})
+
+	t.Run("failed validation", func(t *testing.T) {
+		err := userUsecase.ForgotPassword(ctx, &model.ForgotPasswordRequest{Username: ""})
+		assert.Error(t, err)
+	})
+}
+
+func TestResetPassword(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	userRepository := mocks.NewMockUserRepository(ctrl)
+	refreshTokenRepository := mocks.NewMockRefreshTokenRepository(ctrl)
+	passwordResetTokenRepository := mocks.NewMockPasswordResetTokenRepository(ctrl)
+	userUsecase := newUserUsecase(t, userUsecaseMocks{
+		UserRepository:               userRepository,
+		RefreshTokenRepository:       refreshTokenRepository,
+		PasswordResetTokenRepository: passwordResetTokenRepository,
+	})
+
+	user := createUser(t)
+
+	t.Run("success", func(t *testing.T) {
+		passwordResetToken := &domain.PasswordResetToken{ID: 1, UserID: user.ID, ExpiresAt: time.Now().Add(time.Hour).UnixMilli()}
+
+		passwordResetTokenRepository.EXPECT().FindByTokenHash(gomock.Any(), gomock.Any()).Return(passwordResetToken, nil)
+		passwordResetTokenRepository.EXPECT().MarkUsed(gomock.Any(), passwordResetToken.ID, gomock.Any()).Return(true, nil)
+		userRepository.EXPECT().FindByID(gomock.Any(), user.ID).Return(user, nil)
+		userRepository.EXPECT().Update(gomock.Any(), gomock.Any()).Return(nil)
+		refreshTokenRepository.EXPECT().RevokeByUserID(gomock.Any(), user.ID, gomock.Any()).Return(nil)
+
+		err := userUsecase.ResetPassword(ctx, &model.ResetPasswordRequest{Token: "token", Password: "Battery-Staple-77"})
+		assert.NoError(t, err)
+		assert.NoError(t, bcrypt.CompareHashAndPassword([]byte(user.Password), []byte("Battery-Staple-77")))
+	})
+
+	t.Run("failed token already used", func(t *testing.T) {
+		passwordResetToken := &domain.PasswordResetToken{
+			ID:        1,
+			UserID:    user.ID,
+			ExpiresAt: time.Now().Add(time.Hour).UnixMilli(),
+			UsedAt:    time.Now().UnixMilli(),
+		}
+
+		passwordResetTokenRepository.EXPECT().FindByTokenHash(gomock.Any(), gomock.Any()).Return(passwordResetToken, nil)
+
+		err := userUsecase.ResetPassword(ctx, &model.ResetPasswordRequest{Token: "token", Password: "Battery-Staple-77"})
+		assert.Error(t, err)
+		assert.ErrorIs(t, exception.ErrResetTokenInvalid, err)
+	})
+
+	t.Run("failed token expired", func(t *testing.T) {
+		passwordResetToken := &domain.PasswordResetToken{ID: 1, UserID: user.ID, ExpiresAt: time.Now().Add(-time.Hour).UnixMilli()}
+
+		passwordResetTokenRepository.EXPECT().FindByTokenHash(gomock.Any(), gomock.Any()).Return(passwordResetToken, nil)
+
+		err := userUsecase.ResetPassword(ctx, &model.ResetPasswordRequest{Token: "token", Password: "Battery-Staple-77"})
+		assert.Error(t, err)
+		assert.ErrorIs(t, exception.ErrResetTokenInvalid, err)
+	})
+
+	t.Run("failed token not found", func(t *testing.T) {
+		passwordResetTokenRepository.EXPECT().FindByTokenHash(gomock.Any(), gomock.Any()).Return(nil, gorm.ErrRecordNotFound)
+
+		err := userUsecase.ResetPassword(ctx, &model.ResetPasswordRequest{Token: "token", Password: "Battery-Staple-77"})
+		assert.Error(t, err)
+		assert.ErrorIs(t, exception.ErrResetTokenInvalid, err)
+	})
+
+	t.Run("failed validation", func(t *testing.T) {
+		err := userUsecase.ResetPassword(ctx, &model.ResetPasswordRequest{Token: "token", Password: "short"})
+		assert.Error(t, err)
+	})
+}
+
+func TestRefreshToken(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	userRepository := mocks.NewMockUserRepository(ctrl)
+	refreshTokenRepository := mocks.NewMockRefreshTokenRepository(ctrl)
+	roleRepository := mocks.NewMockRoleRepository(ctrl)
+	userUsecase := newUserUsecase(t, userUsecaseMocks{
+		UserRepository:         userRepository,
+		RefreshTokenRepository: refreshTokenRepository,
+		RoleRepository:         roleRepository,
+	})
+
+	user := createUser(t)
+
+	t.Run("success", func(t *testing.T) {
+		refreshToken := createRefreshToken(time.Now().Add(time.Hour).UnixMilli())
+
+		refreshTokenRepository.EXPECT().FindByTokenHash(gomock.Any(), gomock.Any()).Return(refreshToken, nil)
+		refreshTokenRepository.EXPECT().MarkUsed(gomock.Any(), refreshToken.ID, gomock.Any()).Return(true, nil)
+		userRepository.EXPECT().FindByID(gomock.Any(), user.ID).Return(user, nil)
+		roleRepository.EXPECT().FindNamesByUserID(gomock.Any(), user.ID).Return(nil, nil)
+		refreshTokenRepository.EXPECT().Create(gomock.Any(), gomock.Any()).DoAndReturn(func(_ context.Context, newToken *domain.RefreshToken) error {
+			assert.Equal(t, refreshToken.FamilyID, newToken.FamilyID)
+			assert.NotEqual(t, refreshToken.TokenHash, newToken.TokenHash)
+			return nil
+		})
+
+		response, err := userUsecase.Refresh(ctx, &model.RefreshTokenRequest{RefreshToken: "refreshtoken"})
+		assert.NoError(t, err)
+		assert.NotEmpty(t, response.AccessToken)
+		assert.NotEmpty(t, response.RefreshToken)
+	})
+
+	t.Run("failed reuse detected", func(t *testing.T) {
+		refreshToken := createRefreshToken(time.Now().Add(time.Hour).UnixMilli())
+		refreshToken.UsedAt = time.Now().UnixMilli()
+
+		refreshTokenRepository.EXPECT().FindByTokenHash(gomock.Any(), gomock.Any()).Return(refreshToken, nil)
+		refreshTokenRepository.EXPECT().RevokeFamily(gomock.Any(), refreshToken.FamilyID, gomock.Any()).Return(nil)
+
+		_, err := userUsecase.Refresh(ctx, &model.RefreshTokenRequest{RefreshToken: "refreshtoken"})
+		assert.Error(t, err)
+		assert.ErrorIs(t, exception.ErrRefreshTokenInvalid, err)
+	})
+
+	t.Run("failed concurrent rotation", func(t *testing.T) {
+		refreshToken := createRefreshToken(time.Now().Add(time.Hour).UnixMilli())
+
+		refreshTokenRepository.EXPECT().FindByTokenHash(gomock.Any(), gomock.Any()).Return(refreshToken, nil)
+		refreshTokenRepository.EXPECT().MarkUsed(gomock.Any(), refreshToken.ID, gomock.Any()).Return(false, nil)
+		refreshTokenRepository.EXPECT().RevokeFamily(gomock.Any(), refreshToken.FamilyID, gomock.Any()).Return(nil)
+
+		_, err := userUsecase.Refresh(ctx, &model.RefreshTokenRequest{RefreshToken: "refreshtoken"})
+		assert.Error(t, err)
+		assert.ErrorIs(t, exception.ErrRefreshTokenInvalid, err)
+	})
+
+	t.Run("failed expired", func(t *testing.T) {
+		refreshToken := createRefreshToken(time.Now().Add(-time.Hour).UnixMilli())
+
+		refreshTokenRepository.EXPECT().FindByTokenHash(gomock.Any(), gomock.Any()).Return(refreshToken, nil)
+
+		_, err := userUsecase.Refresh(ctx, &model.RefreshTokenRequest{RefreshToken: "refreshtoken"})
+		assert.Error(t, err)
+		assert.ErrorIs(t, exception.ErrRefreshTokenInvalid, err)
+	})
+
+	t.Run("failed not found", func(t *testing.T) {
+		refreshTokenRepository.EXPECT().FindByTokenHash(gomock.Any(), gomock.Any()).Return(nil, gorm.ErrRecordNotFound)
+
+		_, err := userUsecase.Refresh(ctx, &model.RefreshTokenRequest{RefreshToken: "refreshtoken"})
+		assert.Error(t, err)
+		assert.ErrorIs(t, exception.ErrRefreshTokenInvalid, err)
+	})
+
+	t.Run("failed validation", func(t *testing.T) {
+		_, err := userUsecase.Refresh(ctx, &model.RefreshTokenRequest{RefreshToken: ""})
+		assert.Error(t, err)
+	})
+}
+
+func TestTwoFactor(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	userRepository := mocks.NewMockUserRepository(ctrl)
+	refreshTokenRepository := mocks.NewMockRefreshTokenRepository(ctrl)
+	twoFactorRepository := mocks.NewMockTwoFactorRepository(ctrl)
+	roleRepository := mocks.NewMockRoleRepository(ctrl)
+	userUsecase := newUserUsecase(t, userUsecaseMocks{
+		UserRepository:         userRepository,
+		RefreshTokenRepository: refreshTokenRepository,
+		TwoFactorRepository:    twoFactorRepository,
+		RoleRepository:         roleRepository,
+	})
+
+	user := createUser(t)
+
+	secret, err := infrastructure.GenerateTOTPSecret()
+	assert.NoError(t, err)
+
+	login := func(t *testing.T, userTOTP *domain.UserTOTP) string {
+		userRepository.EXPECT().FindByUsername(gomock.Any(), "johndoe").Return(user, nil)
+		twoFactorRepository.EXPECT().FindTOTPByUserID(gomock.Any(), user.ID).Return(userTOTP, nil)
+
+		response, err := userUsecase.Login(ctx, &model.LoginUserRequest{Username: "johndoe", Password: "password"})
+		assert.NoError(t, err)
+		assert.True(t, response.MFARequired)
+		assert.Empty(t, response.AccessToken)
+		assert.Empty(t, response.RefreshToken)
+		assert.NotEmpty(t, response.MFAToken)
+
+		return response.MFAToken
+	}
+
+	t.Run("success enroll", func(t *testing.T) {
+		userRepository.EXPECT().FindByID(gomock.Any(), user.ID).Return(user, nil)
+		twoFactorRepository.EXPECT().FindTOTPByUserID(gomock.Any(), user.ID).Return(nil, gorm.ErrRecordNotFound)
+		twoFactorRepository.EXPECT().SaveTOTP(gomock.Any(), gomock.Any()).Return(nil)
+
+		response, err := userUsecase.EnrollTOTP(ctx, &model.EnrollTOTPRequest{ID: user.ID})
+		assert.NoError(t, err)
+		assert.NotEmpty(t, response.Secret)
+		assert.Contains(t, response.URI, "otpauth://totp/")
+		assert.Contains(t, response.URI, "secret="+response.Secret)
+	})
+
+	t.Run("failed enroll already enabled", func(t *testing.T) {
+		userRepository.EXPECT().FindByID(gomock.Any(), user.ID).Return(user, nil)
+		twoFactorRepository.EXPECT().FindTOTPByUserID(gomock.Any(), user.ID).Return(&domain.UserTOTP{UserID: user.ID, Secret: secret, ConfirmedAt: 12345}, nil)
+
+		_, err := userUsecase.EnrollTOTP(ctx, &model.EnrollTOTPRequest{ID: user.ID})
+		assert.ErrorIs(t, exception.ErrMFAAlreadyEnabled, err)
+	})
+
+	t.Run("success confirm", func(t *testing.T) {
+		code, err := infrastructure.TOTPCode(secret, time.Now())
+		assert.NoError(t, err)
+
+		twoFactorRepository.EXPECT().FindTOTPByUserID(gomock.Any(), user.ID).Return(&domain.UserTOTP{UserID: user.ID, Secret: secret}, nil)
+		twoFactorRepository.EXPECT().ConfirmTOTP(gomock.Any(), gomock.Any(), gomock.Len(10)).
+			DoAndReturn(func(ctx context.Context, userTOTP *domain.UserTOTP, recoveryCodes []domain.RecoveryCode) error {
+				assert.NotZero(t, userTOTP.ConfirmedAt)
+				assert.NotZero(t, userTOTP.LastUsedStep)
+				return nil
+			})
+
+		response, err := userUsecase.ConfirmTOTP(ctx, &model.ConfirmTOTPRequest{ID: user.ID, Code: code})
+		assert.NoError(t, err)
+		assert.Len(t, response.RecoveryCodes, 10)
+	})
+
+	t.Run("failed confirm wrong code", func(t *testing.T) {
+		code, err := infrastructure.TOTPCode(secret, time.Now().Add(-time.Hour))
+		assert.NoError(t, err)
+
+		twoFactorRepository.EXPECT().FindTOTPByUserID(gomock.Any(), user.ID).Return(&domain.UserTOTP{UserID: user.ID, Secret: secret}, nil)
+
+		_, err = userUsecase.ConfirmTOTP(ctx, &model.ConfirmTOTPRequest{ID: user.ID, Code: code})
+		assert.ErrorIs(t, exception.ErrMFACodeInvalid, err)
+	})
+
+	userTOTP := &domain.UserTOTP{UserID: user.ID, Secret: secret, ConfirmedAt: 12345}
+
+	t.Run("success login with totp code", func(t *testing.T) {
+		mfaToken := login(t, userTOTP)
+
+		code, err := infrastructure.TOTPCode(secret, time.Now())
+		assert.NoError(t, err)
+
+		twoFactorRepository.EXPECT().FindTOTPByUserID(gomock.Any(), user.ID).Return(userTOTP, nil)
+		twoFactorRepository.EXPECT().UseTOTPStep(gomock.Any(), user.ID, gomock.Any()).Return(true, nil)
+		userRepository.EXPECT().FindByID(gomock.Any(), user.ID).Return(user, nil)
+		roleRepository.EXPECT().FindNamesByUserID(gomock.Any(), user.ID).Return(nil, nil)
+		refreshTokenRepository.EXPECT().Create(gomock.Any(), gomock.Any()).Return(nil)
+
+		response, err := userUsecase.LoginMFA(ctx, &model.LoginMFARequest{MFAToken: mfaToken, Code: code})
+		assert.NoError(t, err)
+		assert.NotEmpty(t, response.AccessToken)
+		assert.NotEmpty(t, response.RefreshToken)
+
+		t.Run("failed challenge reused", func(t *testing.T) {
+			userRepository.EXPECT().FindByID(gomock.Any(), user.ID).Return(user, nil)
+
+			_, err := userUsecase.LoginMFA(ctx, &model.LoginMFARequest{MFAToken: mfaToken, Code: code})
+			assert.ErrorIs(t, exception.ErrMFATokenInvalid, err)
+		})
+
+		t.Run("failed challenge used as access token", func(t *testing.T) {
+			_, err := userUsecase.Verify(ctx, &model.VerifyUserRequest{AccessToken: mfaToken})
+			assert.ErrorIs(t, exception.ErrUserUnauthorized, err)
+		})
+	})
+
+	t.Run("failed login with replayed totp code", func(t *testing.T) {
+		mfaToken := login(t, userTOTP)
+
+		code, err := infrastructure.TOTPCode(secret, time.Now())
+		assert.NoError(t, err)
+
+		userRepository.EXPECT().FindByID(gomock.Any(), user.ID).Return(user, nil)
+		twoFactorRepository.EXPECT().FindTOTPByUserID(gomock.Any(), user.ID).Return(userTOTP, nil)
+		twoFactorRepository.EXPECT().UseTOTPStep(gomock.Any(), user.ID, gomock.Any()).Return(false, nil)
+
+		_, err = userUsecase.LoginMFA(ctx, &model.LoginMFARequest{MFAToken: mfaToken, Code: code})
+		assert.ErrorIs(t, exception.ErrMFACodeInvalid, err)
+	})
+
+	t.Run("success login with recovery code", func(t *testing.T) {
+		mfaToken := login(t, userTOTP)
+
+		twoFactorRepository.EXPECT().FindTOTPByUserID(gomock.Any(), user.ID).Return(userTOTP, nil)
+		twoFactorRepository.EXPECT().UseRecoveryCode(gomock.Any(), user.ID, gomock.Any(), gomock.Any()).Return(true, nil)
+		userRepository.EXPECT().FindByID(gomock.Any(), user.ID).Return(user, nil)
+		roleRepository.EXPECT().FindNamesByUserID(gomock.Any(), user.ID).Return(nil, nil)
+		refreshTokenRepository.EXPECT().Create(gomock.Any(), gomock.Any()).Return(nil)
+
+		response, err := userUsecase.LoginMFA(ctx, &model.LoginMFARequest{MFAToken: mfaToken, Code: "ABCDE-FGHJK"})
+		assert.NoError(t, err)
+		assert.NotEmpty(t, response.AccessToken)
+	})
+
+	t.Run("failed login with unknown recovery code", func(t *testing.T) {
+		mfaToken := login(t, userTOTP)
+
+		userRepository.EXPECT().FindByID(gomock.Any(), user.ID).Return(user, nil)
+		twoFactorRepository.EXPECT().FindTOTPByUserID(gomock.Any(), user.ID).Return(userTOTP, nil)
+		twoFactorRepository.EXPECT().UseRecoveryCode(gomock.Any(), user.ID, gomock.Any(), gomock.Any()).Return(false, nil)
+
+		_, err := userUsecase.LoginMFA(ctx, &model.LoginMFARequest{MFAToken: mfaToken, Code: "ABCDE-FGHJK"})
+		assert.ErrorIs(t, exception.ErrMFACodeInvalid, err)
+	})
+
+	t.Run("failed invalid mfa token", func(t *testing.T) {
+		_, err := userUsecase.LoginMFA(ctx, &model.LoginMFARequest{MFAToken: "wrongToken", Code: "123456"})
+		assert.ErrorIs(t, exception.ErrMFATokenInvalid, err)
+	})
+
+	t.Run("success disable", func(t *testing.T) {
+		userRepository.EXPECT().FindByID(gomock.Any(), user.ID).Return(user, nil)
+		twoFactorRepository.EXPECT().FindTOTPByUserID(gomock.Any(), user.ID).Return(userTOTP, nil)
+		twoFactorRepository.EXPECT().DeleteByUserID(gomock.Any(), user.ID).Return(nil)
+
+		err := userUsecase.DisableTOTP(ctx, &model.DisableTOTPRequest{ID: user.ID, Password: "password"})
+		assert.NoError(t, err)
+	})
+
+	t.Run("failed disable wrong password", func(t *testing.T) {
+		userRepository.EXPECT().FindByID(gomock.Any(), user.ID).Return(user, nil)
+
+		err := userUsecase.DisableTOTP(ctx, &model.DisableTOTPRequest{ID: user.ID, Password: "wrongPassword"})
+		assert.ErrorIs(t, exception.ErrUserPasswordNotMatch, err)
+	})
+}
+
+func createRefreshToken(expiresAt int64) *domain.RefreshToken {
+	return &domain.RefreshToken{
+		ID:        1,
+		UserID:    1,
+		FamilyID:  "family",
+		TokenHash: "hash",
+		ExpiresAt: expiresAt,
+	}
+}
+
+// userUsecaseMocks holds the dependencies a test sets up itself, newUserUsecase fills in the others.
+type userUsecaseMocks struct {
+	UserRepository               repository.UserRepository
+	RefreshTokenRepository       repository.RefreshTokenRepository
+	PasswordResetTokenRepository repository.PasswordResetTokenRepository
+	TwoFactorRepository          repository.TwoFactorRepository
+	RoleRepository               repository.RoleRepository
+	TokenRevocationStore         repository.TokenRevocationStore
+	LoginAttemptStore            repository.LoginAttemptStore
+	Mailer                       infrastructure.Mailer
+	PasswordBreachChecker        infrastructure.PasswordBreachChecker
+	Config                       *config.Config
+}
+
+func newUserUsecase(t *testing.T, m userUsecaseMocks) usecase.UserUsecase {
+	ctrl := gomock.NewController(t)
+	if m.UserRepository == nil {
+		m.UserRepository = mocks.NewMockUserRepository(ctrl)
+	}
+	if m.RefreshTokenRepository == nil {
+		m.RefreshTokenRepository = mocks.NewMockRefreshTokenRepository(ctrl)
+	}
+	if m.PasswordResetTokenRepository == nil {
+		m.PasswordResetTokenRepository = mocks.NewMockPasswordResetTokenRepository(ctrl)
+	}
+	if m.TwoFactorRepository == nil {
+		m.TwoFactorRepository = mocks.NewMockTwoFactorRepository(ctrl)
+	}
+	if m.RoleRepository == nil {
+		m.RoleRepository = mocks.NewMockRoleRepository(ctrl)
+	}
+	if m.TokenRevocationStore == nil {
+		m.TokenRevocationStore = repository.NewInMemoryTokenRevocationStore()
+	}
+	if m.LoginAttemptStore == nil {
+		m.LoginAttemptStore = repository.NewInMemoryLoginAttemptStore()
+	}
+	if m.Mailer == nil {
+		m.Mailer = infrastructure.NewInMemoryMailer()
+	}
+	if m.PasswordBreachChecker == nil {
+		m.PasswordBreachChecker = infrastructure.NoopPasswordBreachChecker{}
+	}
+	if m.Config == nil {
+		m.Config = config.New()
+	}
+	lifecycle := infrastructure.NewLifecycle(m.Config, logrus.New())
+	t.Cleanup(func() { assert.NoError(t, lifecycle.Stop()) })
+	mailQueue := infrastructure.NewMailQueue(m.Config, lifecycle, logrus.New())
+	tokenSigner, err := infrastructure.NewTokenSigner(m.Config)
+	assert.NoError(t, err)
+
+	return usecase.NewUserUsecase(m.UserRepository, m.RefreshTokenRepository, m.PasswordResetTokenRepository,
+		m.TwoFactorRepository, m.RoleRepository, m.TokenRevocationStore, m.LoginAttemptStore, tokenSigner, m.Mailer,
+		mailQueue, m.PasswordBreachChecker, infrastructure.NewMetrics(), trace.NewNoopTracerProvider().Tracer(""),
+		logrus.New(), newValidator(t), m.Config)
+}
+
 func createUser(t *testing.T) *domain.User {
 	hashedPassword, err := bcrypt.GenerateFromPassword([]byte("password"), bcrypt.DefaultCost)
 	assert.NoError(t, err)
 
+	email := "johndoe@example.com"
 	user := &domain.User{
-		ID:        1,
-		Name:      "John Doe",
-		Username:  "johndoe",
-		Password:  string(hashedPassword),
-		CreatedAt: 12345,
-		UpdatedAt: 12345,
+		ID:              1,
+		Name:            "John Doe",
+		Username:        "johndoe",
+		Email:           &email,
+		EmailVerifiedAt: 12345,
+		Password:        string(hashedPassword),
+		CreatedAt:       12345,
+		UpdatedAt:       12345,
 	}
 
 	return user
 }
+
+// newValidator returns the validator of the server, which knows the username and password rules.
+func newValidator(t *testing.T) *validator.Validate {
+	translator, err := infrastructure.NewTranslator()
+	assert.NoError(t, err)
+
+	validate, err := infrastructure.NewValidator(config.New(), translator)
+	assert.NoError(t, err)
+
+	return validate
+}
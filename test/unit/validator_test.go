@@ -0,0 +1,63 @@
+package unit
+
+import (
+	"testing"
+
+	"github.com/Ikhlashmulya/golang-clean-architecture-project-structure/internal/infrastructure"
+	"github.com/go-playground/validator/v10"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCanonicalUsername(t *testing.T) {
+	assert.Equal(t, "johndoe", infrastructure.CanonicalUsername("JohnDoe"))
+	assert.Equal(t, "johndoe", infrastructure.CanonicalUsername(" ｊｏｈｎｄｏｅ "))
+	assert.Equal(t, "john.doe", infrastructure.CanonicalUsername("John.Doe"))
+}
+
+func TestPasswordEntropy(t *testing.T) {
+	weak := []string{"aaaaaaaaaaaa", "abcdefghijkl", "123456789", "Password123", "qwertyuiop"}
+	for _, password := range weak {
+		assert.Less(t, infrastructure.PasswordEntropy(password), 40.0, password)
+	}
+
+	strong := []string{"Correct-Horse-42", "Tr0ub4dor&3", "correcthorsebatterystaple"}
+	for _, password := range strong {
+		assert.GreaterOrEqual(t, infrastructure.PasswordEntropy(password), 40.0, password)
+	}
+
+	assert.Equal(t, 1, infrastructure.PasswordClasses("correcthorse"))
+	assert.Equal(t, 4, infrastructure.PasswordClasses("Correct-Horse-42"))
+}
+
+func TestValidatorRules(t *testing.T) {
+	validate := newValidator(t)
+
+	type request struct {
+		Username string `json:"username" validate:"username"`
+		Password string `json:"password" validate:"password=Username"`
+	}
+
+	t.Run("success", func(t *testing.T) {
+		for _, username := range []string{"johndoe", "john.doe", "j_d-42"} {
+			assert.NoError(t, validate.Struct(&request{Username: username, Password: "Correct-Horse-42"}), username)
+		}
+	})
+
+	t.Run("failed username", func(t *testing.T) {
+		for _, username := range []string{"jd", "JohnDoe", "john doe", "jöhn", "john😀", ".johndoe", "admin", "_current"} {
+			err := validate.Struct(&request{Username: username, Password: "Correct-Horse-42"})
+			var validationErrors validator.ValidationErrors
+			assert.ErrorAs(t, err, &validationErrors, username)
+			assert.Equal(t, "username", validationErrors[0].Tag())
+		}
+	})
+
+	t.Run("failed password", func(t *testing.T) {
+		for _, password := range []string{"correcthorsebatterystaple", "password123!", "x-JOHNDOE-42-x"} {
+			err := validate.Struct(&request{Username: "johndoe", Password: password})
+			var validationErrors validator.ValidationErrors
+			assert.ErrorAs(t, err, &validationErrors, password)
+			assert.Equal(t, "password", validationErrors[0].Tag())
+		}
+	})
+}